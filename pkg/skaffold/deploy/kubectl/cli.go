@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os/exec"
 	"strings"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/portforward"
 	kstatus "github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/status"
 	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
 )
 
 // CLI holds parameters to run kubectl.
@@ -43,6 +45,14 @@ type CLI struct {
 	*kubectl.CLI
 	Flags latestV1.KubectlFlags
 
+	// ApplyCommand is the kubectl subcommand used to apply manifests, `apply` by default.
+	// It can be set to a kubectl plugin that reads manifests from stdin, like `kubectl apply` does.
+	ApplyCommand string
+
+	// CommandPrefix is prepended to the commands that apply, delete or preview manifests, e.g. a wrapper
+	// that injects credentials. It must run the kubectl command it's given and pass its stdin through.
+	CommandPrefix []string
+
 	forceDeploy      bool
 	waitForDeletions config.WaitForDeletions
 	previousApply    manifest.ManifestList
@@ -75,7 +85,7 @@ func NewCLI(cfg Config, flags latestV1.KubectlFlags, defaultNamespace string) CL
 // Delete runs `kubectl delete` on a list of manifests.
 func (c *CLI) Delete(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
 	args := c.args(c.Flags.Delete, "--ignore-not-found=true", "--wait=false", "-f", "-")
-	if err := c.Run(ctx, manifests.Reader(), out, "delete", args...); err != nil {
+	if err := c.RunWithPrefix(ctx, manifests.Reader(), out, "delete", args...); err != nil {
 		return deployerr.CleanupErr(fmt.Errorf("kubectl delete: %w", err))
 	}
 
@@ -92,8 +102,8 @@ func (c *CLI) Apply(ctx context.Context, out io.Writer, manifests manifest.Manif
 	// TODO(dgageot): should we delete a manifest that was deployed and is not anymore?
 	updated := c.previousApply.Diff(manifests)
 	logrus.Debugln(len(manifests), "manifests to deploy.", len(updated), "are updated or new")
-	c.previousApply = manifests
 	if len(updated) == 0 {
+		c.previousApply = manifests
 		return nil
 	}
 
@@ -106,14 +116,51 @@ func (c *CLI) Apply(ctx context.Context, out io.Writer, manifests manifest.Manif
 		args = append(args, "--validate=false")
 	}
 
-	if err := c.Run(ctx, updated.Reader(), out, "apply", c.args(c.Flags.Apply, args...)...); err != nil {
+	command := c.ApplyCommand
+	if command == "" {
+		command = "apply"
+	}
+
+	if err := c.RunWithPrefix(ctx, updated.Reader(), out, command, c.args(c.Flags.Apply, args...)...); err != nil {
 		endTrace(instrumentation.TraceEndError(err))
 		return userErr(fmt.Errorf("kubectl apply: %w", err))
 	}
 
+	// Only remember successfully applied manifests so that a failed apply can be retried.
+	c.previousApply = manifests
 	return nil
 }
 
+// RunWithPrefix is like Run but prepends CommandPrefix to the kubectl command.
+func (c *CLI) RunWithPrefix(ctx context.Context, in io.Reader, out io.Writer, command string, arg ...string) error {
+	cmd := c.prefixedCommand(ctx, command, arg...)
+	cmd.Stdin = in
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return util.RunCmd(cmd)
+}
+
+// RunOutInputWithPrefix is like RunOutInput but prepends CommandPrefix to the kubectl command.
+func (c *CLI) RunOutInputWithPrefix(ctx context.Context, in io.Reader, command string, arg ...string) ([]byte, error) {
+	cmd := c.prefixedCommand(ctx, command, arg...)
+	cmd.Stdin = in
+	return util.RunCmdOut(cmd)
+}
+
+func (c *CLI) prefixedCommand(ctx context.Context, command string, arg ...string) *exec.Cmd {
+	cmd := c.Command(ctx, command, arg...)
+	if len(c.CommandPrefix) == 0 {
+		return cmd
+	}
+	return exec.CommandContext(ctx, c.CommandPrefix[0], append(append([]string{}, c.CommandPrefix[1:]...), cmd.Args...)...)
+}
+
+// RememberApplied records the manifests as successfully applied, so that the next Apply only
+// sends the ones that changed. It's needed when subsets of the manifests are applied with copies of the CLI.
+func (c *CLI) RememberApplied(manifests manifest.ManifestList) {
+	c.previousApply = manifests
+}
+
 // Kustomize runs `kubectl kustomize` with the provided args
 func (c *CLI) Kustomize(ctx context.Context, args []string) ([]byte, error) {
 	return c.RunOut(ctx, "kustomize", c.args(nil, args...)...)
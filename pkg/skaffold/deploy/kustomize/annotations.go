@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// validateCommonAnnotations checks the keys of the annotations added to all the rendered resources.
+func validateCommonAnnotations(annotations map[string]string) error {
+	var keys []string
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid commonAnnotations key %q: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// addCommonAnnotations adds the given annotations to the metadata of all the resources.
+// Annotations that a resource already has keep their value unless `overwrite` is set.
+func addCommonAnnotations(manifests manifest.ManifestList, common map[string]string, overwrite bool) (manifest.ManifestList, error) {
+	var annotated manifest.ManifestList
+	for _, m := range manifests {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+
+		metadata, ok := obj["metadata"].(map[string]interface{})
+		if !ok {
+			metadata = map[string]interface{}{}
+			obj["metadata"] = metadata
+		}
+		annotations, ok := metadata["annotations"].(map[string]interface{})
+		if !ok {
+			annotations = map[string]interface{}{}
+			metadata["annotations"] = annotations
+		}
+		for key, value := range common {
+			if _, found := annotations[key]; found && !overwrite {
+				continue
+			}
+			annotations[key] = value
+		}
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		annotated = append(annotated, buf)
+	}
+	return annotated, nil
+}
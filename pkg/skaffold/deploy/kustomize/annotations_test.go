@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestAddCommonAnnotations(t *testing.T) {
+	const annotatedServiceYAML = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  annotations:
+    team: payments`
+
+	tests := []struct {
+		description string
+		overwrite   bool
+		manifests   manifest.ManifestList
+		expected    manifest.ManifestList
+	}{
+		{
+			description: "annotations added",
+			manifests:   manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)},
+			expected: manifest.ManifestList{
+				[]byte("apiVersion: v1\nkind: Service\nmetadata:\n  annotations:\n    cost-center: \"1234\"\n    team: web\n  name: web\n"),
+				[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  annotations:\n    cost-center: \"1234\"\n    team: web\n  name: web\n"),
+			},
+		},
+		{
+			description: "existing values kept",
+			manifests:   manifest.ManifestList{[]byte(annotatedServiceYAML)},
+			expected: manifest.ManifestList{
+				[]byte("apiVersion: v1\nkind: Service\nmetadata:\n  annotations:\n    cost-center: \"1234\"\n    team: payments\n  name: web\n"),
+			},
+		},
+		{
+			description: "existing values overwritten",
+			overwrite:   true,
+			manifests:   manifest.ManifestList{[]byte(annotatedServiceYAML)},
+			expected: manifest.ManifestList{
+				[]byte("apiVersion: v1\nkind: Service\nmetadata:\n  annotations:\n    cost-center: \"1234\"\n    team: web\n  name: web\n"),
+			},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			annotated, err := addCommonAnnotations(test.manifests, map[string]string{"team": "web", "cost-center": "1234"}, test.overwrite)
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected.String(), annotated.String())
+		})
+	}
+}
+
+func TestValidateCommonAnnotations(t *testing.T) {
+	testutil.CheckError(t, false, validateCommonAnnotations(nil))
+	testutil.CheckError(t, false, validateCommonAnnotations(map[string]string{"team": "web", "example.com/cost-center": "1234"}))
+	testutil.CheckError(t, true, validateCommonAnnotations(map[string]string{"": "web"}))
+	testutil.CheckError(t, true, validateCommonAnnotations(map[string]string{"cost center": "1234"}))
+}
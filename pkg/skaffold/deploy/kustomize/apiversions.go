@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// deprecatedAPIVersions maps deprecated apiVersions to the ones that replace them.
+var deprecatedAPIVersions = map[string]string{
+	"extensions/v1beta1":                   "apps/v1 or networking.k8s.io/v1",
+	"apps/v1beta1":                         "apps/v1",
+	"apps/v1beta2":                         "apps/v1",
+	"batch/v1beta1":                        "batch/v1",
+	"networking.k8s.io/v1beta1":            "networking.k8s.io/v1",
+	"policy/v1beta1":                       "policy/v1",
+	"rbac.authorization.k8s.io/v1beta1":    "rbac.authorization.k8s.io/v1",
+	"apiextensions.k8s.io/v1beta1":         "apiextensions.k8s.io/v1",
+	"admissionregistration.k8s.io/v1beta1": "admissionregistration.k8s.io/v1",
+	"scheduling.k8s.io/v1beta1":            "scheduling.k8s.io/v1",
+	"storage.k8s.io/v1beta1":               "storage.k8s.io/v1",
+}
+
+// apiVersionPattern matches a core apiVersion, like `v1`, or a `group/version` pair, like `apps/v1beta2`.
+var apiVersionPattern = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// validateAPIVersionOverrides checks the apiVersions rewritten in the rendered resources.
+// A rewritten apiVersion can't itself be rewritten, so that the result doesn't depend on the order of the rewrites.
+func validateAPIVersionOverrides(overrides map[string]string) error {
+	var from []string
+	for f := range overrides {
+		from = append(from, f)
+	}
+	sort.Strings(from)
+
+	for _, f := range from {
+		to := overrides[f]
+		switch {
+		case !apiVersionPattern.MatchString(f):
+			return fmt.Errorf("invalid apiVersion override: %q is not an apiVersion", f)
+		case !apiVersionPattern.MatchString(to):
+			return fmt.Errorf("invalid apiVersion override for %q: %q is not an apiVersion", f, to)
+		case f == to:
+			return fmt.Errorf("invalid apiVersion override: %q is rewritten to itself", f)
+		case overrides[to] != "":
+			return fmt.Errorf("invalid apiVersion override for %q: %q is itself rewritten to %q", f, to, overrides[to])
+		}
+	}
+	return nil
+}
+
+// overrideAPIVersions rewrites the apiVersion of the rendered resources according to the given mapping.
+// Resources whose apiVersion isn't mapped are left untouched.
+func overrideAPIVersions(manifests manifest.ManifestList, overrides map[string]string) (manifest.ManifestList, error) {
+	var updated manifest.ManifestList
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+
+		to, found := overrides[r.APIVersion]
+		if !found {
+			updated = append(updated, m)
+			continue
+		}
+
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+		obj["apiVersion"] = to
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, buf)
+	}
+	return updated, nil
+}
+
+// checkAPIVersions warns about rendered resources whose apiVersion is deprecated,
+// or not served by the target cluster.
+func (k *Deployer) checkAPIVersions(ctx context.Context, manifests manifest.ManifestList) error {
+	out, err := k.kubectl.RunOut(ctx, "api-versions")
+	if err != nil {
+		return userErr(fmt.Errorf("listing the API versions served by the cluster: %w", err))
+	}
+
+	served := util.NewStringSet()
+	served.Insert(strings.Fields(string(out))...)
+
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !served.Contains(r.APIVersion):
+			warnings.Printf("%s uses apiVersion %s, which is not served by the cluster", r, r.APIVersion)
+		case deprecatedAPIVersions[r.APIVersion] != "":
+			warnings.Printf("%s uses deprecated apiVersion %s, use %s instead", r, r.APIVersion, deprecatedAPIVersions[r.APIVersion])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const (
+	ingressV1beta1YAML = `apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: web`
+	podDisruptionBudgetYAML = `apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: web`
+)
+
+func TestKustomizeRenderValidateAPIVersions(t *testing.T) {
+	tests := []struct {
+		description      string
+		offline          bool
+		commands         util.Command
+		expectedWarnings []string
+		shouldErr        bool
+	}{
+		{
+			description: "deprecated and unavailable api versions",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", serviceYAML+"\n---\n"+ingressV1beta1YAML+"\n---\n"+podDisruptionBudgetYAML).
+				AndRunOut("kubectl --context kubecontext --namespace testNamespace api-versions", "apps/v1\nnetworking.k8s.io/v1\nnetworking.k8s.io/v1beta1\npolicy/v1\nv1\n"),
+			expectedWarnings: []string{
+				"Ingress/web uses deprecated apiVersion networking.k8s.io/v1beta1, use networking.k8s.io/v1 instead",
+				"PodDisruptionBudget/web uses apiVersion policy/v1beta1, which is not served by the cluster",
+			},
+		},
+		{
+			description: "skipped in offline mode",
+			offline:     true,
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", ingressV1beta1YAML),
+		},
+		{
+			description: "cluster unreachable",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", serviceYAML).
+				AndRunOutErr("kubectl --context kubecontext --namespace testNamespace api-versions", "", errors.New("connection refused")),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"."},
+				ValidateAPIVersions: true,
+			})
+			t.RequireNoError(err)
+
+			err = k.Render(context.Background(), &bytes.Buffer{}, nil, test.offline, "")
+
+			t.CheckError(test.shouldErr, err)
+			t.CheckDeepEqual(test.expectedWarnings, fakeWarner.Warnings)
+		})
+	}
+}
+
+func TestKustomizeRenderAPIVersionOverrides(t *testing.T) {
+	tests := []struct {
+		description string
+		overrides   map[string]string
+		rendered    string
+		expected    string
+		shouldErr   bool
+	}{
+		{
+			description: "rewritten apiVersion",
+			overrides:   map[string]string{"networking.k8s.io/v1beta1": "networking.k8s.io/v1", "extensions/v1beta1": "apps/v1"},
+			rendered:    serviceYAML + "\n---\n" + ingressV1beta1YAML,
+			expected:    serviceYAML + "\n---\napiVersion: networking.k8s.io/v1\nkind: Ingress\nmetadata:\n  name: web\n",
+		},
+		{
+			description: "no matching apiVersion",
+			overrides:   map[string]string{"extensions/v1beta1": "apps/v1"},
+			rendered:    ingressV1beta1YAML,
+			expected:    ingressV1beta1YAML + "\n",
+		},
+		{
+			description: "invalid source apiVersion",
+			overrides:   map[string]string{"Apps/V1": "apps/v1"},
+			shouldErr:   true,
+		},
+		{
+			description: "invalid target apiVersion",
+			overrides:   map[string]string{"extensions/v1beta1": "apps/"},
+			shouldErr:   true,
+		},
+		{
+			description: "rewritten to itself",
+			overrides:   map[string]string{"apps/v1": "apps/v1"},
+			shouldErr:   true,
+		},
+		{
+			description: "chained rewrites",
+			overrides:   map[string]string{"extensions/v1beta1": "apps/v1beta2", "apps/v1beta2": "apps/v1"},
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", test.rendered))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"."},
+				APIVersionOverrides: test.overrides,
+			})
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+
+			var out bytes.Buffer
+			err = k.Render(context.Background(), &out, nil, true, "")
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected, out.String())
+		})
+	}
+}
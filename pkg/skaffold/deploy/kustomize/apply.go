@@ -0,0 +1,263 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+var (
+	// lookPath finds executables in the PATH. For testing.
+	lookPath = exec.LookPath
+
+	// applyRetryBackoff is the delay before the first apply retry. It doubles on each attempt.
+	applyRetryBackoff = 1 * time.Second
+
+	// transientApplyErrors are error messages returned by the API server that are worth retrying.
+	transientApplyErrors = []string{
+		"etcdserver: leader changed",
+		"etcdserver: request timed out",
+		"etcdserver: too many requests",
+		"the server is currently unable to handle the request",
+		"the server was unable to return a response in the time allotted",
+		"TLS handshake timeout",
+		"http2: server sent GOAWAY",
+		"connection reset by peer",
+		"i/o timeout",
+	}
+
+	// webhookTimeoutBackoff is the delay before the first retry of an apply whose admission webhook timed out.
+	// It doubles on each attempt.
+	webhookTimeoutBackoff = 2 * time.Second
+
+	// permanentApplyErrors are error messages that must never be retried, even if they look transient.
+	permanentApplyErrors = []string{
+		"admission webhook",
+		"is invalid",
+		"is forbidden",
+		"error validating",
+	}
+)
+
+// kubectlPluginBinary returns the name of the executable that implements a kubectl plugin subcommand.
+func kubectlPluginBinary(plugin string) string {
+	return "kubectl-" + strings.ReplaceAll(plugin, "-", "_")
+}
+
+// checkApplyPlugin verifies that the kubectl plugin used to apply manifests is installed.
+func (k *Deployer) checkApplyPlugin() error {
+	if k.ApplyPlugin == "" {
+		return nil
+	}
+
+	binary := kubectlPluginBinary(k.ApplyPlugin)
+	if _, err := lookPath(binary); err != nil {
+		return userErr(fmt.Errorf("kubectl plugin %q is not installed: %q not found in PATH", k.ApplyPlugin, binary))
+	}
+	return nil
+}
+
+// validateCommandPrefix checks the command prepended to kubectl. The manifests are passed to kubectl on stdin
+// and no shell is involved, so the prefix can neither read stdin itself nor use shell redirections.
+func validateCommandPrefix(prefix []string) error {
+	for i, arg := range prefix {
+		switch {
+		case strings.TrimSpace(arg) == "":
+			return fmt.Errorf("invalid applyCommandPrefix: argument %d is empty", i)
+		case arg == "-":
+			return fmt.Errorf("invalid applyCommandPrefix: %q would read the manifests that kubectl expects on stdin", arg)
+		case strings.ContainsAny(arg, "<>|") && strings.Trim(arg, "<>|&") == "":
+			return fmt.Errorf("invalid applyCommandPrefix: shell operator %q is not supported", arg)
+		}
+	}
+	return nil
+}
+
+// apply sends the rendered manifests to the cluster.
+func (k *Deployer) apply(ctx context.Context, out io.Writer, manifests manifest.ManifestList) (err error) {
+	if err := k.checkApplyPlugin(); err != nil {
+		return err
+	}
+
+	if k.AdoptHelmResources {
+		if manifests, err = k.adoptHelmResources(ctx, manifests); err != nil {
+			return err
+		}
+	}
+
+	if k.ServerSidePreview {
+		if err := k.previewServerSideApply(ctx, out, manifests); err != nil {
+			return err
+		}
+	}
+
+	if k.ServerSideApplyLargeCRDs == nil || *k.ServerSideApplyLargeCRDs {
+		if manifests, err = k.applyLargeCRDs(ctx, out, manifests); err != nil {
+			return err
+		}
+		if len(manifests) == 0 {
+			return nil
+		}
+	}
+
+	if k.PauseRollouts {
+		var paused []pausedRollout
+		if paused, err = k.pauseRollouts(ctx, manifests); err != nil {
+			return err
+		}
+
+		// Resume the rollouts even if the apply fails, so that workloads are never left paused.
+		defer func() {
+			if resumeErr := k.resumeRollouts(ctx, out, paused); err == nil {
+				err = resumeErr
+			}
+		}()
+	}
+
+	if len(k.PropagationPolicies) > 0 {
+		return k.applyWithPropagationPolicies(ctx, out, manifests)
+	}
+	return k.applyManifests(ctx, out, manifests)
+}
+
+// applyManifests sends the manifests to the cluster with the configured apply strategy.
+func (k *Deployer) applyManifests(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	if k.ApplyByDependencies {
+		return k.applyByDependencies(ctx, out, manifests)
+	}
+
+	if k.ApplyConcurrency > 1 {
+		return k.applyByNamespace(ctx, out, manifests)
+	}
+
+	if k.ApplyByKind {
+		return k.applyByKind(ctx, out, manifests)
+	}
+
+	if k.WaitForCRDs {
+		return k.applyCRDsFirst(ctx, out, manifests)
+	}
+
+	return k.kubectlApply(ctx, out, manifests)
+}
+
+// kubectlApply runs `kubectl apply`, retrying on transient API server errors.
+func (k *Deployer) kubectlApply(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	if k.Prune {
+		// Anything left out of the apply would be pruned, so the unchanged manifests can't be skipped.
+		k.kubectl.RememberApplied(nil)
+	}
+	return k.kubectlApplyWith(ctx, &k.kubectl, out, manifests)
+}
+
+// kubectlApplyWith is like kubectlApply but uses the given kubectl CLI.
+func (k *Deployer) kubectlApplyWith(ctx context.Context, cli *kubectl.CLI, out io.Writer, manifests manifest.ManifestList) error {
+	backoff := applyRetryBackoff
+	webhookBackoff := webhookTimeoutBackoff
+	var retries, webhookRetries int
+	recreated := false
+
+	for {
+		var output bytes.Buffer
+		err := cli.Apply(ctx, io.MultiWriter(out, &output), manifests)
+
+		if err != nil && k.RecreateImmutable && !recreated {
+			conflicts, parseErr := immutableConflicts(manifests, err, output.String())
+			if parseErr != nil {
+				return parseErr
+			}
+			if len(conflicts) > 0 {
+				if err := deleteForRecreate(ctx, cli, out, conflicts); err != nil {
+					return err
+				}
+				recreated = true
+				continue
+			}
+		}
+
+		var retry bool
+		var delay time.Duration
+		switch {
+		case err == nil:
+		case isWebhookTimeoutErr(err, output.String()):
+			if webhookRetries < k.WebhookTimeoutRetries {
+				retry = true
+				webhookRetries++
+				delay = webhookBackoff
+				webhookBackoff *= 2
+				logrus.Debugf("kubectl apply failed with an admission webhook timeout, retrying in %v: %v", delay, err)
+			}
+		case retries < k.ApplyRetries && isTransientApplyErr(err, output.String()):
+			retry = true
+			retries++
+			delay = backoff
+			backoff *= 2
+			logrus.Debugf("kubectl apply failed with a transient error, retrying in %v: %v", delay, err)
+		}
+
+		if !retry {
+			if k.ResourceEvents {
+				reportAppliedResources(manifests, output.String(), err)
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// isWebhookTimeoutErr checks whether a failed apply, given its error and output, was caused by
+// an admission webhook that didn't respond in time, as opposed to one that denied the request.
+func isWebhookTimeoutErr(err error, output string) bool {
+	msg := err.Error() + "\n" + output
+	if !strings.Contains(msg, "failed calling webhook") || strings.Contains(msg, "denied the request") {
+		return false
+	}
+	return strings.Contains(msg, "context deadline exceeded") || strings.Contains(strings.ToLower(msg), "timeout")
+}
+
+// isTransientApplyErr checks whether a failed apply, given its error and output, is worth retrying.
+func isTransientApplyErr(err error, output string) bool {
+	msg := err.Error() + "\n" + output
+
+	for _, permanent := range permanentApplyErrors {
+		if strings.Contains(msg, permanent) {
+			return false
+		}
+	}
+	for _, transient := range transientApplyErrors {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const applyCommand = "kubectl --context kubecontext --namespace testNamespace apply -f -"
+
+func TestKustomizeApplyRetries(t *testing.T) {
+	tests := []struct {
+		description string
+		kustomize   latestV1.KustomizeDeploy
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "transient error then success",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				ApplyRetries:   2,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", kubectl.DeploymentWebYAML).
+				AndRunErr(applyCommand, errors.New("etcdserver: leader changed")).
+				AndRun(applyCommand),
+		},
+		{
+			description: "transient errors exhaust retries",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				ApplyRetries:   1,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", kubectl.DeploymentWebYAML).
+				AndRunErr(applyCommand, errors.New("etcdserver: leader changed")).
+				AndRunErr(applyCommand, errors.New("etcdserver: request timed out")),
+			shouldErr: true,
+		},
+		{
+			description: "admission errors are not retried",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				ApplyRetries:   2,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", kubectl.DeploymentWebYAML).
+				AndRunErr(applyCommand, errors.New(`admission webhook "validate.example.com" denied the request: i/o timeout`)),
+			shouldErr: true,
+		},
+		{
+			description: "webhook timeout then success",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths:        []string{"."},
+				WebhookTimeoutRetries: 1,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", kubectl.DeploymentWebYAML).
+				AndRunErr(applyCommand, errors.New(`Internal error occurred: failed calling webhook "validate.example.com": Post "https://webhook.svc:443/validate": context deadline exceeded`)).
+				AndRun(applyCommand),
+		},
+		{
+			description: "webhook timeouts don't use the transient retries",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				ApplyRetries:   2,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", kubectl.DeploymentWebYAML).
+				AndRunErr(applyCommand, errors.New(`Internal error occurred: failed calling webhook "validate.example.com": Post "https://webhook.svc:443/validate": i/o timeout`)),
+			shouldErr: true,
+		},
+		{
+			description: "webhook timeouts exhaust retries",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths:        []string{"."},
+				WebhookTimeoutRetries: 1,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", kubectl.DeploymentWebYAML).
+				AndRunErr(applyCommand, errors.New(`failed calling webhook "validate.example.com": context deadline exceeded`)).
+				AndRunErr(applyCommand, errors.New(`failed calling webhook "validate.example.com": context deadline exceeded`)),
+			shouldErr: true,
+		},
+		{
+			description: "webhook denials are not retried",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths:        []string{"."},
+				WebhookTimeoutRetries: 2,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", kubectl.DeploymentWebYAML).
+				AndRunErr(applyCommand, errors.New(`admission webhook "validate.example.com" denied the request: timeout must be set`)),
+			shouldErr: true,
+		},
+		{
+			description: "no retry by default",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", kubectl.DeploymentWebYAML).
+				AndRunErr(applyCommand, errors.New("etcdserver: leader changed")),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.Override(&applyRetryBackoff, time.Duration(0))
+			t.Override(&webhookTimeoutBackoff, time.Duration(0))
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &test.kustomize)
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestKustomizeApplyPlugin(t *testing.T) {
+	tests := []struct {
+		description string
+		installed   bool
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "apply through plugin",
+			installed:   true,
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply-set -f -", deploymentYAML),
+		},
+		{
+			description: "plugin not installed",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			var lookedUp string
+			t.Override(&lookPath, func(file string) (string, error) {
+				lookedUp = file
+				if !test.installed {
+					return "", errors.New("not found")
+				}
+				return "/usr/local/bin/" + file, nil
+			})
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				ApplyPlugin:    "apply-set",
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+			t.CheckDeepEqual("kubectl-apply_set", lookedUp)
+		})
+	}
+}
+
+func TestKustomizeApplyCommandPrefix(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		// kubectl version and get commands are not prefixed.
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", deploymentYAML).
+			AndRunInput("with-credentials --profile dev -- kubectl --context kubecontext --namespace testNamespace apply -f -", deploymentYAML).
+			AndRunOut("kustomize build .", deploymentYAML).
+			AndRunInput("with-credentials --profile dev -- kubectl --context kubecontext --namespace testNamespace delete --ignore-not-found=true --wait=false -f -", deploymentYAML))
+		t.Override(&client.Client, deployutil.MockK8sClient)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:     []string{"."},
+			ApplyCommandPrefix: []string{"with-credentials", "--profile", "dev", "--"},
+		})
+		t.RequireNoError(err)
+
+		t.CheckNoError(k.Deploy(context.Background(), ioutil.Discard, nil))
+		t.CheckNoError(k.Cleanup(context.Background(), ioutil.Discard))
+	})
+}
+
+func TestInvalidApplyCommandPrefix(t *testing.T) {
+	tests := []struct {
+		description string
+		prefix      []string
+	}{
+		{description: "empty argument", prefix: []string{"sudo", ""}},
+		{description: "reads stdin", prefix: []string{"tee", "-"}},
+		{description: "shell redirection", prefix: []string{"wrapper", "<", "manifests.yaml"}},
+		{description: "shell pipe", prefix: []string{"cat", "|"}},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			_, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				ApplyCommandPrefix: test.prefix,
+			})
+
+			t.CheckErrorContains("invalid applyCommandPrefix", err)
+		})
+	}
+}
+
+func TestIsTransientApplyErr(t *testing.T) {
+	tests := []struct {
+		description string
+		err         error
+		output      string
+		expected    bool
+	}{
+		{
+			description: "leader election in error",
+			err:         errors.New("etcdserver: leader changed"),
+			expected:    true,
+		},
+		{
+			description: "unavailable server in output",
+			err:         errors.New("exit status 1"),
+			output:      "Error from server (ServiceUnavailable): the server is currently unable to handle the request",
+			expected:    true,
+		},
+		{
+			description: "validation error",
+			err:         errors.New("exit status 1"),
+			output:      `The Deployment "web" is invalid: spec.template.metadata.labels: Invalid value`,
+		},
+		{
+			description: "unknown error",
+			err:         errors.New("exit status 1"),
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckDeepEqual(test.expected, isTransientApplyErr(test.err, test.output))
+		})
+	}
+}
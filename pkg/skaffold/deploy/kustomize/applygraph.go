@@ -0,0 +1,238 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// crdNames is the part of a CustomResourceDefinition that identifies its custom resources.
+type crdNames struct {
+	Spec struct {
+		Group string `yaml:"group"`
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+	} `yaml:"spec"`
+}
+
+// applyDependencies returns, for each resource, the indexes of the resources that must be applied before it:
+// the CustomResourceDefinition of a custom resource, the Namespace of a namespaced resource and
+// the resources listed in its `skaffold.dev/depends-on` annotation.
+func applyDependencies(manifests manifest.ManifestList) ([]resource, [][]int, error) {
+	resources := make([]resource, len(manifests))
+	byID := map[string][]int{}
+	crds := map[string][]int{}
+	namespaces := map[string][]int{}
+
+	for i, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, nil, err
+		}
+		resources[i] = r
+
+		id := r.Kind + "/" + r.Metadata.Name
+		byID[id] = append(byID[id], i)
+
+		switch r.Kind {
+		case crdKind:
+			var crd crdNames
+			if err := yaml.Unmarshal(m, &crd); err != nil {
+				return nil, nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+			}
+			gk := crd.Spec.Names.Kind + "." + crd.Spec.Group
+			crds[gk] = append(crds[gk], i)
+		case namespaceKind:
+			namespaces[r.Metadata.Name] = append(namespaces[r.Metadata.Name], i)
+		}
+	}
+
+	dependencies := make([][]int, len(manifests))
+	for i, r := range resources {
+		seen := map[int]bool{i: true}
+		add := func(targets []int) {
+			for _, target := range targets {
+				if !seen[target] {
+					seen[target] = true
+					dependencies[i] = append(dependencies[i], target)
+				}
+			}
+		}
+
+		add(crds[r.Kind+"."+r.apiGroup()])
+		add(namespaces[r.Metadata.Namespace])
+		for _, dep := range strings.Split(r.Metadata.Annotations[dependsOnAnnotation], ",") {
+			if dep = strings.TrimSpace(dep); dep != "" {
+				add(byID[dep])
+			}
+		}
+	}
+
+	return resources, dependencies, nil
+}
+
+// applyResult is the outcome of applying a single resource.
+type applyResult struct {
+	index  int
+	output []byte
+	err    error
+}
+
+// applyByDependencies applies each resource on its own, as soon as all the resources it depends on are applied,
+// with at most `applyConcurrency` applies running at the same time. No new apply is started once one fails.
+func (k *Deployer) applyByDependencies(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	resources, dependencies, err := applyDependencies(manifests)
+	if err != nil {
+		return err
+	}
+
+	// dependents[i] lists the resources that depend on resource i.
+	dependents := make([][]int, len(manifests))
+	pending := make([]int, len(manifests))
+	for i, deps := range dependencies {
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], i)
+		}
+		pending[i] = len(deps)
+	}
+	if err := checkAcyclic(resources, dependents, pending); err != nil {
+		return err
+	}
+
+	// CustomResourceDefinitions are established before their custom resources are applied.
+	toWait := util.NewStringSet()
+	if k.WaitForCRDs {
+		var names []string
+		for _, r := range resources {
+			if r.Kind == crdKind {
+				names = append(names, r.Metadata.Name)
+			}
+		}
+		if k.SkipExistingCRDWait {
+			names = k.newCRDs(ctx, names)
+		}
+		toWait.Insert(names...)
+	}
+
+	concurrency := k.ApplyConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Each resource is compared to the manifests applied by the previous deploy.
+	previous := k.kubectl
+
+	var ready []int
+	for i := range manifests {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	var (
+		results = make(chan applyResult)
+		running int
+		applied int
+		errs    []string
+	)
+	for {
+		for len(errs) == 0 && len(ready) > 0 && running < concurrency {
+			i := ready[0]
+			ready = ready[1:]
+			running++
+
+			go func(i int) {
+				// Buffer the output so that concurrent applies don't interleave.
+				var buf bytes.Buffer
+				cli := previous
+				err := k.kubectlApplyWith(ctx, &cli, &buf, manifest.ManifestList{manifests[i]})
+				if err == nil && resources[i].Kind == crdKind && toWait.Contains(resources[i].Metadata.Name) {
+					err = k.waitForCRDs(ctx, &buf, []string{resources[i].Metadata.Name})
+				}
+				results <- applyResult{index: i, output: buf.Bytes(), err: err}
+			}(i)
+		}
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+		out.Write(result.output)
+		if result.err != nil {
+			errs = append(errs, result.err.Error())
+			continue
+		}
+
+		applied++
+		for _, dependent := range dependents[result.index] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return userErr(fmt.Errorf("applied %d of %d resources: %s", applied, len(manifests), strings.Join(errs, "; ")))
+	}
+
+	k.kubectl.RememberApplied(manifests)
+	return nil
+}
+
+// checkAcyclic fails if the resources can't all be applied because some of them depend on each other.
+func checkAcyclic(resources []resource, dependents [][]int, pending []int) error {
+	remaining := append([]int{}, pending...)
+	var ready []int
+	for i, p := range remaining {
+		if p == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	done := 0
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		done++
+		for _, dependent := range dependents[i] {
+			if remaining[dependent]--; remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if done == len(resources) {
+		return nil
+	}
+
+	var cycle []string
+	for i, r := range resources {
+		if remaining[i] > 0 {
+			cycle = append(cycle, r.String())
+		}
+	}
+	return userErr(fmt.Errorf("cycle in the dependencies between %s", strings.Join(cycle, ", ")))
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const dependencyGraphYAML = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: a
+  annotations:
+    skaffold.dev/depends-on: ConfigMap/config
+---
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: foo
+  namespace: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: a
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+spec:
+  group: example.com
+  names:
+    kind: Foo
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: a`
+
+// dependencyApplies fakes `kubectl apply` and records when each resource starts and finishes being applied.
+type dependencyApplies struct {
+	rendered    string
+	mu          sync.Mutex
+	events      []string
+	inFlight    int
+	maxInFlight int
+	failures    map[string]bool
+}
+
+func (d *dependencyApplies) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Args[0] == "kustomize" {
+		return []byte(d.rendered), nil
+	}
+	return []byte(kubectl.KubectlVersion118), nil
+}
+
+func (d *dependencyApplies) RunCmd(cmd *exec.Cmd) error {
+	input, err := ioutil.ReadAll(cmd.Stdin)
+	if err != nil {
+		return err
+	}
+	r, err := parseResource(input)
+	if err != nil {
+		return err
+	}
+	id := r.Kind + "/" + r.Metadata.Name
+
+	d.mu.Lock()
+	d.events = append(d.events, "start "+id)
+	d.inFlight++
+	if d.inFlight > d.maxInFlight {
+		d.maxInFlight = d.inFlight
+	}
+	d.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	d.events = append(d.events, "end "+id)
+	if d.failures[id] {
+		return errors.New("apply failed")
+	}
+	return nil
+}
+
+// index returns the position of the given event, or -1 if it didn't happen.
+func (d *dependencyApplies) index(event string) int {
+	for i, e := range d.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestKustomizeApplyByDependencies(t *testing.T) {
+	tests := []struct {
+		description    string
+		rendered       string
+		failures       map[string]bool
+		expectedErr    string
+		expectedNotRun []string
+	}{
+		{
+			description: "dependencies applied first",
+			rendered:    dependencyGraphYAML,
+		},
+		{
+			description:    "dependents of a failed apply are skipped",
+			rendered:       dependencyGraphYAML,
+			failures:       map[string]bool{"ConfigMap/config": true},
+			expectedErr:    "applied 4 of 6 resources: kubectl apply: apply failed",
+			expectedNotRun: []string{"start Deployment/web"},
+		},
+		{
+			description: "cycle",
+			rendered: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: a
+  annotations:
+    skaffold.dev/depends-on: ConfigMap/config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: a`,
+			expectedErr:    "cycle in the dependencies between ConfigMap/config (namespace a), Namespace/a",
+			expectedNotRun: []string{"start Namespace/a", "start ConfigMap/config"},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fake := &dependencyApplies{rendered: test.rendered, failures: test.failures}
+			t.Override(&util.DefaultExecCommand, fake)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"."},
+				ApplyByDependencies: true,
+				ApplyConcurrency:    2,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			if test.expectedErr != "" {
+				t.CheckErrorContains(test.expectedErr, err)
+			} else {
+				t.CheckNoError(err)
+			}
+			for _, event := range test.expectedNotRun {
+				t.CheckDeepEqual(-1, fake.index(event))
+			}
+
+			// Every resource that was applied started after its dependencies were applied.
+			for dependent, dependencies := range map[string][]string{
+				"Foo/foo":          {"CustomResourceDefinition/foos.example.com", "Namespace/a"},
+				"ConfigMap/config": {"Namespace/a"},
+				"Deployment/web":   {"ConfigMap/config", "Namespace/a"},
+			} {
+				start := fake.index("start " + dependent)
+				if start == -1 {
+					continue
+				}
+				for _, dependency := range dependencies {
+					if end := fake.index("end " + dependency); end == -1 || end > start {
+						t.Errorf("%s was applied before %s", dependent, dependency)
+					}
+				}
+			}
+			if test.expectedErr == "" {
+				t.CheckDeepEqual(2, fake.maxInFlight)
+				t.CheckDeepEqual(12, len(fake.events))
+			}
+		})
+	}
+}
+
+func TestApplyDependencies(t *testing.T) {
+	manifests := manifest.ManifestList{}
+	for _, m := range strings.Split(dependencyGraphYAML, "\n---\n") {
+		manifests.Append([]byte(m))
+	}
+
+	_, dependencies, err := applyDependencies(manifests)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, [][]int{nil, {5, 3}, {4, 5}, {5}, nil, nil}, dependencies)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+// groupByKind groups the resources by kind. The groups are ordered by the first
+// appearance of their kind, so that the order kustomize uses across kinds is preserved.
+func groupByKind(manifests manifest.ManifestList) ([]string, []manifest.ManifestList, error) {
+	var kinds []string
+	var groups []manifest.ManifestList
+	index := map[string]int{}
+
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		i, found := index[r.Kind]
+		if !found {
+			i = len(groups)
+			index[r.Kind] = i
+			kinds = append(kinds, r.Kind)
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+
+	return kinds, groups, nil
+}
+
+// applyByKind runs one `kubectl apply` per kind, so that all the resources of a kind are created together.
+// With `waitForCRDs`, the CustomResourceDefinitions are applied and established before any other kind.
+func (k *Deployer) applyByKind(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	kinds, groups, err := groupByKind(manifests)
+	if err != nil {
+		return err
+	}
+
+	// Each group is compared to the manifests applied by the previous deploy.
+	previous := k.kubectl
+
+	if k.WaitForCRDs {
+		for i, kind := range kinds {
+			if kind != crdKind {
+				continue
+			}
+
+			crds := groups[i]
+			kinds = append(kinds[:i:i], kinds[i+1:]...)
+			groups = append(groups[:i:i], groups[i+1:]...)
+
+			_, names, _, err := splitCRDs(crds)
+			if err != nil {
+				return err
+			}
+			cli := previous
+			if err := k.kubectlApplyWith(ctx, &cli, out, crds); err != nil {
+				return err
+			}
+			if err := k.waitForCRDs(ctx, out, names); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	for _, group := range groups {
+		cli := previous
+		if err := k.kubectlApplyWith(ctx, &cli, out, group); err != nil {
+			return err
+		}
+	}
+
+	k.kubectl.RememberApplied(manifests)
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const apiServiceYAML = `apiVersion: v1
+kind: Service
+metadata:
+  name: api`
+
+func TestKustomizeApplyByKind(t *testing.T) {
+	tests := []struct {
+		description string
+		kustomize   latestV1.KustomizeDeploy
+		commands    util.Command
+	}{
+		{
+			description: "one apply per kind",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				ApplyByKind:    true,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", serviceYAML+"\n---\n"+deploymentYAML+"\n---\n"+apiServiceYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", serviceYAML+"\n---\n"+apiServiceYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", deploymentYAML),
+		},
+		{
+			description: "crds first",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				ApplyByKind:    true,
+				WaitForCRDs:    true,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crYAML+"\n---\n"+crdYAML+"\n---\n"+serviceYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crdYAML).
+				AndRun("kubectl --context kubecontext --namespace testNamespace wait --for=condition=established --timeout=60s crd/foos.example.com").
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", serviceYAML),
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &test.kustomize)
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckNoError(err)
+		})
+	}
+}
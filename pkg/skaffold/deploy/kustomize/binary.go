@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultBinaryLookupRetries = 2
+
+// binaryLookupBackoff is the delay before the first retry of a build whose binary wasn't found. For testing.
+var binaryLookupBackoff = 200 * time.Millisecond
+
+// buildWithRetry runs the kustomize build of the given path. On some network filesystems, looking up
+// the binary in the PATH fails intermittently, so the build is retried when the binary isn't found.
+func (k *Deployer) buildWithRetry(ctx context.Context, kustomizePath string, out io.Writer) ([]byte, error) {
+	retries := defaultBinaryLookupRetries
+	if k.BinaryLookupRetries != nil {
+		retries = *k.BinaryLookupRetries
+	}
+	backoff := binaryLookupBackoff
+
+	for attempt := 0; ; attempt++ {
+		cmd := k.kustomizeBuildCmd(ctx, kustomizePath)
+		buf, err := k.cachedKustomizeBuild(cmd, kustomizePath, out)
+		if err == nil || !errors.Is(err, exec.ErrNotFound) {
+			return buf, err
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("%s binary not found in PATH, retried %d times: %w", cmd.Args[0], attempt, err)
+		}
+
+		logrus.Debugf("%s binary not found, retrying in %v", cmd.Args[0], backoff)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
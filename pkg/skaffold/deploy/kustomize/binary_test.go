@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeBinaryLookupRetries(t *testing.T) {
+	notFound := &exec.Error{Name: "kustomize", Err: exec.ErrNotFound}
+	noRetry := 0
+
+	tests := []struct {
+		description string
+		retries     *int
+		commands    util.Command
+		expectedErr string
+	}{
+		{
+			description: "transient lookup failure",
+			commands: testutil.
+				CmdRunOutErr("kustomize build .", "", notFound).
+				AndRunOutErr("kustomize build .", "", notFound).
+				AndRunOut("kustomize build .", serviceYAML),
+		},
+		{
+			description: "binary not found",
+			commands: testutil.
+				CmdRunOutErr("kustomize build .", "", notFound).
+				AndRunOutErr("kustomize build .", "", notFound).
+				AndRunOutErr("kustomize build .", "", notFound),
+			expectedErr: "kustomize binary not found in PATH, retried 2 times",
+		},
+		{
+			description: "retries disabled",
+			retries:     &noRetry,
+			commands:    testutil.CmdRunOutErr("kustomize build .", "", notFound),
+			expectedErr: "kustomize binary not found in PATH, retried 0 times",
+		},
+		{
+			description: "other errors are not retried",
+			commands:    testutil.CmdRunOutErr("kustomize build .", "", errors.New("invalid kustomization")),
+			expectedErr: "invalid kustomization",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.Override(&binaryLookupBackoff, time.Millisecond)
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"."},
+				BinaryLookupRetries: test.retries,
+			})
+			t.RequireNoError(err)
+
+			manifests, err := k.readManifests(context.Background(), ioutil.Discard)
+
+			if test.expectedErr != "" {
+				t.CheckErrorContains(test.expectedErr, err)
+				return
+			}
+			t.CheckNoError(err)
+			t.CheckDeepEqual(serviceYAML, manifests.String())
+		})
+	}
+}
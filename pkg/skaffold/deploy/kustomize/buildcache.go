@@ -0,0 +1,248 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+const (
+	defaultBuildCacheMaxSize = "100Mi"
+	buildCacheExtension      = ".kustomize-build"
+	buildCacheChecksumPrefix = "sha256:"
+)
+
+// binaryVersion returns the version printed by the binary of a build command. For testing.
+var binaryVersion = func(path string) (string, error) {
+	args := []string{"version"}
+	if strings.HasPrefix(filepath.Base(path), "kubectl") {
+		args = append(args, "--client")
+	}
+	out, err := exec.Command(path, args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// parseBuildCacheMaxSize parses the size limit of the build cache, e.g. `100Mi`.
+func parseBuildCacheMaxSize(dir, size string) (int64, error) {
+	if size == "" {
+		size = defaultBuildCacheMaxSize
+	} else if dir == "" {
+		return 0, fmt.Errorf("buildCacheMaxSize requires buildCacheDir")
+	}
+
+	q, err := k8sresource.ParseQuantity(size)
+	if err != nil || q.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid buildCacheMaxSize %q: must be a positive size, such as 100Mi", size)
+	}
+	return q.Value(), nil
+}
+
+// cachedKustomizeBuild runs the kustomize build of the given path, unless the output of the same build,
+// with the same local files, is found in the build cache. Kustomizations that use remote bases are never cached.
+func (k *Deployer) cachedKustomizeBuild(cmd *exec.Cmd, kustomizePath string, out io.Writer) ([]byte, error) {
+	if k.BuildCacheDir == "" {
+		return k.runKustomizeBuild(cmd, out)
+	}
+
+	key, err := buildCacheKey(cmd, k.kustomizeBuildEnv(), kustomizePath, k.MaxDependencyDepth)
+	if err != nil {
+		logrus.Debugf("not caching the build of %q: %v", kustomizePath, err)
+		return k.runKustomizeBuild(cmd, out)
+	}
+
+	if buf, found := readBuildCache(k.BuildCacheDir, key); found {
+		logrus.Debugf("found the build of %q in the build cache", kustomizePath)
+		return buf, nil
+	}
+
+	buf, err := k.runKustomizeBuild(cmd, out)
+	if err != nil {
+		// Failed builds are never cached, but their output is kept for AllowPartialBuildOutput.
+		return buf, err
+	}
+
+	if err := writeBuildCache(k.BuildCacheDir, key, buf); err != nil {
+		logrus.Warnf("unable to cache the build of %q: %v", kustomizePath, err)
+	} else if err := evictBuildCache(k.BuildCacheDir, k.buildCacheMaxSize); err != nil {
+		logrus.Warnf("unable to evict old builds from the build cache: %v", err)
+	}
+	return buf, nil
+}
+
+// buildCacheKey fingerprints a build with its command, the version of its binary, the environment variables
+// that change its output and the content of all the local files it depends on. The rest of the environment
+// is left out, so that the cache is shared across shells and CI steps.
+func buildCacheKey(cmd *exec.Cmd, addedEnv []string, kustomizePath string, maxDepth int) (string, error) {
+	var remotes []string
+	deps, err := dependenciesForKustomization(kustomizePath, dependencyOptions{
+		maxDepth:   maxDepth,
+		remoteBase: func(target string) { remotes = append(remotes, target) },
+	}, 0)
+	if err != nil {
+		return "", err
+	}
+	if len(remotes) > 0 {
+		return "", fmt.Errorf("remote bases can't be fingerprinted: %s", strings.Join(remotes, ", "))
+	}
+
+	absPath, err := filepath.Abs(kustomizePath)
+	if err != nil {
+		return "", err
+	}
+
+	version, err := binaryVersion(cmd.Path)
+	if err != nil {
+		return "", fmt.Errorf("getting the version of %s: %w", cmd.Path, err)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "path %s\nbinary %s\nversion %q\nargs %q\nenv %q\n", absPath, cmd.Path, version, cmd.Args, buildCacheEnv(addedEnv))
+
+	sort.Strings(deps)
+	for _, dep := range deps {
+		content, err := ioutil.ReadFile(dep)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file %s %d\n", dep, len(content))
+		h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// buildCacheEnv returns the environment variables that change the output of a build: those read by kustomize
+// from the OS environment, followed by those added by the deployer.
+func buildCacheEnv(addedEnv []string) []string {
+	var env []string
+	for _, v := range util.OSEnviron() {
+		if strings.HasPrefix(v, "KUSTOMIZE_") || strings.HasPrefix(v, "XDG_CONFIG_HOME=") {
+			env = append(env, v)
+		}
+	}
+	sort.Strings(env)
+	return append(env, addedEnv...)
+}
+
+// readBuildCache returns the cached output of a build. Entries that don't match their checksum are removed.
+func readBuildCache(dir, key string) ([]byte, bool) {
+	path := filepath.Join(dir, key+buildCacheExtension)
+	entry, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	header, buf, found := bytesCut(entry, '\n')
+	if !found || string(header) != buildCacheChecksumPrefix+checksum(buf) {
+		logrus.Warnf("removing corrupted build cache entry %q", path)
+		os.Remove(path)
+		return nil, false
+	}
+
+	// Recently used entries are evicted last.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return buf, true
+}
+
+// writeBuildCache stores the output of a build along with its checksum.
+func writeBuildCache(dir, key string, buf []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that concurrent runs never read a partial entry.
+	tmp, err := ioutil.TempFile(dir, key+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := fmt.Fprintf(tmp, "%s%s\n", buildCacheChecksumPrefix, checksum(buf)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, key+buildCacheExtension))
+}
+
+// evictBuildCache removes the least recently used entries until the cache fits in maxSize bytes.
+func evictBuildCache(dir string, maxSize int64) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var entries []os.FileInfo
+	var size int64
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != buildCacheExtension {
+			continue
+		}
+		entries = append(entries, f)
+		size += f.Size()
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	for _, entry := range entries {
+		if size <= maxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+		size -= entry.Size()
+	}
+	return nil
+}
+
+func checksum(buf []byte) string {
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+// bytesCut slices buf around the first instance of sep.
+func bytesCut(buf []byte, sep byte) ([]byte, []byte, bool) {
+	if i := bytes.IndexByte(buf, sep); i >= 0 {
+		return buf[:i], buf[i+1:], true
+	}
+	return buf, nil, false
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeBuildCache(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir().
+			Write("app/kustomization.yaml", "resources:\n- service.yaml").
+			Write("app/service.yaml", serviceYAML).
+			Chdir()
+		cacheDir := tmpDir.Path("cache")
+
+		// The second build is a cache hit, the third follows a change to a dependency.
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kustomize build app", serviceYAML).
+			AndRunOut("kustomize build app", deploymentYAML))
+		t.Override(&binaryVersion, func(string) (string, error) { return "v4.1.2", nil })
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		newDeployer := func() *Deployer {
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"app"},
+				BuildCacheDir:  cacheDir,
+			})
+			t.RequireNoError(err)
+			return k
+		}
+
+		// Separate deployers share the cache, like separate Skaffold runs.
+		manifests, err := newDeployer().readManifests(context.Background(), ioutil.Discard)
+		t.CheckNoError(err)
+		t.CheckDeepEqual(serviceYAML, manifests.String())
+
+		manifests, err = newDeployer().readManifests(context.Background(), ioutil.Discard)
+		t.CheckNoError(err)
+		t.CheckDeepEqual(serviceYAML, manifests.String())
+
+		tmpDir.Write("app/service.yaml", deploymentYAML)
+		manifests, err = newDeployer().readManifests(context.Background(), ioutil.Discard)
+		t.CheckNoError(err)
+		t.CheckDeepEqual(deploymentYAML, manifests.String())
+	})
+}
+
+func TestBuildCacheKeyBinaryVersion(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.NewTempDir().
+			Write("app/kustomization.yaml", "resources:\n- service.yaml").
+			Write("app/service.yaml", serviceYAML).
+			Chdir()
+
+		version := "v4.1.2"
+		var versionedBinary string
+		t.Override(&binaryVersion, func(path string) (string, error) {
+			versionedBinary = path
+			return version, nil
+		})
+		cmd := exec.Command("/usr/local/bin/kustomize", "build", "app")
+
+		key, err := buildCacheKey(cmd, nil, "app", 0)
+		t.CheckNoError(err)
+		t.CheckDeepEqual("/usr/local/bin/kustomize", versionedBinary)
+
+		sameKey, err := buildCacheKey(cmd, nil, "app", 0)
+		t.CheckNoError(err)
+		t.CheckDeepEqual(key, sameKey)
+
+		version = "v4.2.0"
+		upgradedKey, err := buildCacheKey(cmd, nil, "app", 0)
+		t.CheckNoError(err)
+		t.CheckTrue(key != upgradedKey)
+
+		otherBinaryKey, err := buildCacheKey(exec.Command("/opt/bin/kustomize", "build", "app"), nil, "app", 0)
+		t.CheckNoError(err)
+		t.CheckTrue(upgradedKey != otherBinaryKey)
+	})
+}
+
+func TestBuildCacheKeyEnv(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.NewTempDir().
+			Write("app/kustomization.yaml", "resources:\n- service.yaml").
+			Write("app/service.yaml", serviceYAML).
+			Chdir()
+		t.Override(&binaryVersion, func(string) (string, error) { return "v4.1.2", nil })
+
+		env := []string{"PATH=/usr/bin", "PWD=/home/dev", "SHLVL=1", "KUSTOMIZE_PLUGIN_HOME=/plugins"}
+		t.Override(&util.OSEnviron, func() []string { return env })
+		key := func(addedEnv ...string) string {
+			cmd := exec.Command("/usr/local/bin/kustomize", "build", "app")
+			cmd.Env = append(util.OSEnviron(), addedEnv...)
+			key, err := buildCacheKey(cmd, addedEnv, "app", 0)
+			t.RequireNoError(err)
+			return key
+		}
+
+		initial := key("GIT_TERMINAL_PROMPT=0")
+
+		// Unrelated variables are ignored.
+		env = []string{"PATH=/usr/bin", "PWD=/builds/app", "SHLVL=2", "TERM=xterm", "KUSTOMIZE_PLUGIN_HOME=/plugins"}
+		t.CheckDeepEqual(initial, key("GIT_TERMINAL_PROMPT=0"))
+
+		// Variables read by kustomize and those added by the deployer are not.
+		env = []string{"PATH=/usr/bin", "KUSTOMIZE_PLUGIN_HOME=/other"}
+		t.CheckTrue(initial != key("GIT_TERMINAL_PROMPT=0"))
+		env = []string{"PATH=/usr/bin", "KUSTOMIZE_PLUGIN_HOME=/plugins", "XDG_CONFIG_HOME=/config"}
+		t.CheckTrue(initial != key("GIT_TERMINAL_PROMPT=0"))
+		env = []string{"KUSTOMIZE_PLUGIN_HOME=/plugins"}
+		t.CheckTrue(initial != key())
+	})
+}
+
+func TestReadBuildCacheCorrupted(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		dir := t.NewTempDir().Root()
+		t.CheckNoError(writeBuildCache(dir, "key", []byte(serviceYAML)))
+
+		buf, found := readBuildCache(dir, "key")
+		t.CheckTrue(found)
+		t.CheckDeepEqual(serviceYAML, string(buf))
+
+		// Tamper with the cached output.
+		path := filepath.Join(dir, "key"+buildCacheExtension)
+		entry, err := ioutil.ReadFile(path)
+		t.RequireNoError(err)
+		t.RequireNoError(ioutil.WriteFile(path, append(entry, []byte("\nextra: field")...), 0644))
+
+		_, found = readBuildCache(dir, "key")
+		t.CheckFalse(found)
+		_, err = os.Stat(path)
+		t.CheckTrue(os.IsNotExist(err))
+	})
+}
+
+func TestEvictBuildCache(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		dir := t.NewTempDir().Write("unrelated.txt", "kept").Root()
+
+		// Each entry holds a 72 bytes checksum line and the output.
+		entrySize := int64(72 + len(serviceYAML))
+		now := time.Now()
+		for i, key := range []string{"oldest", "old", "recent"} {
+			t.RequireNoError(writeBuildCache(dir, key, []byte(serviceYAML)))
+			modTime := now.Add(time.Duration(i-3) * time.Hour)
+			t.RequireNoError(os.Chtimes(filepath.Join(dir, key+buildCacheExtension), modTime, modTime))
+		}
+
+		err := evictBuildCache(dir, 2*entrySize)
+
+		t.CheckNoError(err)
+		_, found := readBuildCache(dir, "oldest")
+		t.CheckFalse(found)
+		_, found = readBuildCache(dir, "old")
+		t.CheckTrue(found)
+		_, found = readBuildCache(dir, "recent")
+		t.CheckTrue(found)
+		t.CheckFileExistAndContent(filepath.Join(dir, "unrelated.txt"), []byte("kept"))
+	})
+}
+
+func TestInvalidBuildCacheMaxSize(t *testing.T) {
+	tests := []struct {
+		description string
+		dir         string
+		size        string
+		expected    int64
+		shouldErr   bool
+	}{
+		{description: "default", dir: "cache", expected: 100 * 1024 * 1024},
+		{description: "custom", dir: "cache", size: "1Gi", expected: 1024 * 1024 * 1024},
+		{description: "invalid", dir: "cache", size: "big", shouldErr: true},
+		{description: "zero", dir: "cache", size: "0", shouldErr: true},
+		{description: "without cache dir", size: "1Gi", shouldErr: true},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			size, err := parseBuildCacheMaxSize(test.dir, test.size)
+
+			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected, size)
+		})
+	}
+}
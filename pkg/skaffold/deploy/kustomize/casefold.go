@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+var (
+	// sameFile checks whether two paths point to the same file. On a case-insensitive filesystem,
+	// paths that only differ by case do. For testing.
+	sameFile = func(path, other string) bool {
+		a, err := os.Stat(path)
+		if err != nil {
+			return false
+		}
+		b, err := os.Stat(other)
+		if err != nil {
+			return false
+		}
+		return os.SameFile(a, b)
+	}
+
+	// warnedCaseMismatches records the kustomization files already warned about.
+	warnedCaseMismatches sync.Map
+)
+
+// findKustomizationFile looks for a kustomization config in the listed files of a directory.
+// On a case-insensitive filesystem, such as macOS's default one, a file like `Kustomization.yaml` is the same
+// file as `kustomization.yaml`. It is used as such, but the user is warned that it won't be found elsewhere.
+func findKustomizationFile(dir string) (string, bool) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for _, candidate := range KustomizeFilePaths {
+		for _, f := range files {
+			if f.Name() == candidate {
+				return filepath.Join(dir, candidate), true
+			}
+		}
+
+		for _, f := range files {
+			if !strings.EqualFold(f.Name(), candidate) || !sameFile(filepath.Join(dir, f.Name()), filepath.Join(dir, candidate)) {
+				continue
+			}
+
+			path := filepath.Join(dir, f.Name())
+			if _, warned := warnedCaseMismatches.LoadOrStore(path, true); !warned {
+				warnings.Printf("%q is used as %q because the filesystem is case-insensitive, rename it to %q so that it's also found on case-sensitive filesystems", path, candidate, candidate)
+			}
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestFindKustomizationConfigCaseInsensitive(t *testing.T) {
+	tests := []struct {
+		description     string
+		files           []string
+		caseInsensitive bool
+		expected        string
+		shouldErr       bool
+		expectWarning   bool
+	}{
+		{
+			description: "exact name",
+			files:       []string{"kustomization.yaml"},
+			expected:    "kustomization.yaml",
+		},
+		{
+			description:     "exact name preferred on case-insensitive filesystem",
+			files:           []string{"kustomization.yaml"},
+			caseInsensitive: true,
+			expected:        "kustomization.yaml",
+		},
+		{
+			description:     "different case on case-insensitive filesystem",
+			files:           []string{"Kustomization.yaml"},
+			caseInsensitive: true,
+			expected:        "Kustomization.yaml",
+			expectWarning:   true,
+		},
+		{
+			description: "different case on case-sensitive filesystem",
+			files:       []string{"Kustomization.yaml"},
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir()
+			for _, file := range test.files {
+				tmpDir.Write(file, "resources: []")
+			}
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+			if test.caseInsensitive {
+				t.Override(&sameFile, func(string, string) bool { return true })
+			}
+
+			path, err := FindKustomizationConfig(tmpDir.Root())
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+
+			expected := filepath.Join(tmpDir.Root(), test.expected)
+			t.CheckDeepEqual(expected, path)
+			if test.expectWarning {
+				t.CheckDeepEqual([]string{fmt.Sprintf("%q is used as %q because the filesystem is case-insensitive, rename it to %q so that it's also found on case-sensitive filesystems", expected, "kustomization.yaml", "kustomization.yaml")}, fakeWarner.Warnings)
+
+				// warned only once per file
+				_, err := FindKustomizationConfig(tmpDir.Root())
+				t.CheckNoError(err)
+				t.CheckDeepEqual(1, len(fakeWarner.Warnings))
+			} else {
+				t.CheckDeepEqual(0, len(fakeWarner.Warnings))
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+)
+
+const defaultClusterCheckTimeout = 10 * time.Second
+
+// for testing
+var clusterReachable = serverVersion
+
+// serverVersion queries the version of the API server. The request is cancelled with the context.
+func serverVersion(ctx context.Context) error {
+	c, err := client.Client()
+	if err != nil {
+		return err
+	}
+
+	restClient := c.Discovery().RESTClient()
+	if restClient == nil {
+		// Fake clients don't have a REST client.
+		_, err = c.Discovery().ServerVersion()
+		return err
+	}
+	return restClient.Get().AbsPath("/version").Do(ctx).Error()
+}
+
+// checkClusterReachable fails fast with a clear error when the cluster can't be reached,
+// instead of letting `kubectl apply` fail after the manifests are rendered.
+// The check is cancelled when it doesn't complete within `clusterCheckTimeout`.
+func (k *Deployer) checkClusterReachable(ctx context.Context) error {
+	timeout := defaultClusterCheckTimeout
+	if k.ClusterCheckTimeout != "" {
+		// Already validated in NewDeployer.
+		timeout, _ = time.ParseDuration(k.ClusterCheckTimeout)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := clusterReachable(timeoutCtx)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(timeoutCtx.Err(), context.DeadlineExceeded):
+		return clusterUnreachableErr(fmt.Errorf("no response from the API server within %v", timeout))
+	default:
+		return clusterUnreachableErr(err)
+	}
+}
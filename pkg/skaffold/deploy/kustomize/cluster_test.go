@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	sErrors "github.com/GoogleContainerTools/skaffold/pkg/skaffold/errors"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/proto/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeDeployClusterUnreachable(t *testing.T) {
+	tests := []struct {
+		description      string
+		reachable        func(context.Context) error
+		timeout          string
+		expectedErrorMsg string
+	}{
+		{
+			description:      "connection refused",
+			reachable:        func(context.Context) error { return errors.New("connection refused") },
+			expectedErrorMsg: "cluster unreachable: unable to connect to Kubernetes: connection refused",
+		},
+		{
+			description: "no response",
+			reachable: func(ctx context.Context) error {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Minute):
+					return nil
+				}
+			},
+			timeout:          "10ms",
+			expectedErrorMsg: "cluster unreachable: unable to connect to Kubernetes: no response from the API server within 10ms",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			// Nothing is rendered nor applied.
+			t.Override(&util.DefaultExecCommand, testutil.CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118))
+			t.Override(&clusterReachable, test.reachable)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"."},
+				ClusterCheckTimeout: test.timeout,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckErrorContains(test.expectedErrorMsg, err)
+			var actionable sErrors.Error
+			t.CheckTrue(errors.As(err, &actionable))
+			t.CheckDeepEqual(proto.StatusCode_DEPLOY_CLUSTER_CONNECTION_ERR, actionable.StatusCode())
+		})
+	}
+}
+
+func TestInvalidClusterCheckTimeout(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		_, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			ClusterCheckTimeout: "0s",
+		})
+
+		t.CheckErrorContains(`invalid clusterCheckTimeout "0s"`, err)
+	})
+}
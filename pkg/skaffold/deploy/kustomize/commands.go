@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// validateCommandOverrides checks the command overrides of the workloads.
+func validateCommandOverrides(overrides []latestV1.KustomizeCommandOverride) error {
+	for _, o := range overrides {
+		if o.Name == "" {
+			return fmt.Errorf("invalid commandOverrides: name is required")
+		}
+		if len(o.Command) == 0 && len(o.Args) == 0 {
+			return fmt.Errorf("invalid commandOverrides %q: either command or args is required", o.Name)
+		}
+	}
+	return nil
+}
+
+// overrideCommands replaces the command and arguments of the containers targeted by the overrides.
+// An override that matches no workload, or no container of a workload, is an error.
+func overrideCommands(manifests manifest.ManifestList, overrides []latestV1.KustomizeCommandOverride) (manifest.ManifestList, error) {
+	matched := make([]bool, len(overrides))
+
+	var updated manifest.ManifestList
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+
+		var targeting []int
+		for i, o := range overrides {
+			if o.Name == r.Metadata.Name && (o.Kind == "" || o.Kind == r.Kind) {
+				targeting = append(targeting, i)
+			}
+		}
+		if len(targeting) == 0 {
+			updated = append(updated, m)
+			continue
+		}
+
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+		spec := podSpec(obj)
+		if spec == nil {
+			updated = append(updated, m)
+			continue
+		}
+
+		for _, i := range targeting {
+			if err := overrideContainerCommand(spec, overrides[i]); err != nil {
+				return nil, fmt.Errorf("overriding the command of %s %q: %w", r.Kind, r.Metadata.Name, err)
+			}
+			matched[i] = true
+		}
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, buf)
+	}
+
+	for i, o := range overrides {
+		if !matched[i] {
+			return nil, fmt.Errorf("commandOverrides %q matches no workload of the rendered manifests", o.Name)
+		}
+	}
+	return updated, nil
+}
+
+func overrideContainerCommand(spec map[string]interface{}, o latestV1.KustomizeCommandOverride) error {
+	containers, _ := spec["containers"].([]interface{})
+
+	var target map[string]interface{}
+	switch {
+	case o.Container != "":
+		for _, c := range containers {
+			if c, ok := c.(map[string]interface{}); ok && c["name"] == o.Container {
+				target = c
+				break
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("no container named %q", o.Container)
+		}
+	case len(containers) == 1:
+		target, _ = containers[0].(map[string]interface{})
+		if target == nil {
+			return fmt.Errorf("invalid container")
+		}
+	default:
+		return fmt.Errorf("the workload has %d containers, the container to override must be set", len(containers))
+	}
+
+	if len(o.Command) > 0 {
+		target["command"] = o.Command
+	}
+	if len(o.Args) > 0 {
+		target["args"] = o.Args
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestOverrideCommands(t *testing.T) {
+	const (
+		webYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - args:
+        - --port=8080
+        image: web
+        name: web
+      - image: envoy
+        name: proxy`
+		workerYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  template:
+    spec:
+      containers:
+      - image: worker
+        name: worker`
+	)
+
+	tests := []struct {
+		description string
+		overrides   []latestV1.KustomizeCommandOverride
+		expected    manifest.ManifestList
+		shouldErr   bool
+	}{
+		{
+			description: "named deployment overridden",
+			overrides: []latestV1.KustomizeCommandOverride{
+				{Name: "web", Kind: "Deployment", Container: "web", Command: []string{"sleep"}, Args: []string{"infinity"}},
+			},
+			expected: manifest.ManifestList{
+				[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  template:\n    spec:\n      containers:\n" +
+					"      - args:\n        - infinity\n        command:\n        - sleep\n        image: web\n        name: web\n" +
+					"      - image: envoy\n        name: proxy\n"),
+				[]byte(workerYAML),
+			},
+		},
+		{
+			description: "single container",
+			overrides:   []latestV1.KustomizeCommandOverride{{Name: "worker", Command: []string{"sh"}}},
+			expected: manifest.ManifestList{
+				[]byte(webYAML),
+				[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: worker\nspec:\n  template:\n    spec:\n      containers:\n" +
+					"      - command:\n        - sh\n        image: worker\n        name: worker\n"),
+			},
+		},
+		{
+			description: "container required with several containers",
+			overrides:   []latestV1.KustomizeCommandOverride{{Name: "web", Command: []string{"sh"}}},
+			shouldErr:   true,
+		},
+		{
+			description: "unknown container",
+			overrides:   []latestV1.KustomizeCommandOverride{{Name: "web", Container: "sidecar", Command: []string{"sh"}}},
+			shouldErr:   true,
+		},
+		{
+			description: "no matching workload",
+			overrides:   []latestV1.KustomizeCommandOverride{{Name: "web", Kind: "StatefulSet", Command: []string{"sh"}}},
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			updated, err := overrideCommands(manifest.ManifestList{[]byte(webYAML), []byte(workerYAML)}, test.overrides)
+
+			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected.String(), updated.String())
+		})
+	}
+}
+
+func TestValidateCommandOverrides(t *testing.T) {
+	testutil.CheckError(t, false, validateCommandOverrides([]latestV1.KustomizeCommandOverride{{Name: "web", Args: []string{"-v"}}}))
+	testutil.CheckError(t, true, validateCommandOverrides([]latestV1.KustomizeCommandOverride{{Command: []string{"sh"}}}))
+	testutil.CheckError(t, true, validateCommandOverrides([]latestV1.KustomizeCommandOverride{{Name: "web"}}))
+}
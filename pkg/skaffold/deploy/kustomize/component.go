@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+const componentAPIVersion = "kustomize.config.k8s.io/v1alpha1"
+
+// componentKustomization is the kustomization of a kustomize component.
+type componentKustomization struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Resources  []string `yaml:"resources"`
+}
+
+// writeComponent writes the rendered resources as a kustomize component, that other kustomizations can
+// list in their `components`. Each resource goes to its own file, listed by the component's kustomization.
+// The resource files of a previous render are removed first.
+func writeComponent(dir string, manifests manifest.ManifestList) error {
+	if err := validateComponentResources(manifests); err != nil {
+		return err
+	}
+	if err := removeResourceFiles(dir); err != nil {
+		return err
+	}
+
+	files, _, err := writeResourceFiles(dir, manifests)
+	if err != nil {
+		return err
+	}
+
+	buf, err := yaml.Marshal(componentKustomization{
+		APIVersion: componentAPIVersion,
+		Kind:       "Component",
+		Resources:  files,
+	})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), buf, 0644)
+}
+
+// validateComponentResources checks that kustomize can load the resources of a component:
+// they must be identified by a kind and a name, and no two resources can share the same identity.
+func validateComponentResources(manifests manifest.ManifestList) error {
+	seen := map[string]bool{}
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+		if r.Kind == "" || r.Metadata.Name == "" {
+			return fmt.Errorf("invalid component: every resource needs a kind and a name, found %q", r)
+		}
+
+		id := r.Metadata.Namespace + "/" + r.kubectlID()
+		if seen[id] {
+			return fmt.Errorf("invalid component: %s is rendered more than once", r)
+		}
+		seen[id] = true
+	}
+	return nil
+}
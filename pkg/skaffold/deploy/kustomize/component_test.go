@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestWriteComponent(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir().Write("app/kustomization.yaml", "components:\n- ../rendered")
+
+		err := writeComponent(tmpDir.Path("rendered"), manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)})
+		t.RequireNoError(err)
+
+		kustomization, err := ioutil.ReadFile(tmpDir.Path("rendered/kustomization.yaml"))
+		t.RequireNoError(err)
+		t.CheckDeepEqual(`apiVersion: kustomize.config.k8s.io/v1alpha1
+kind: Component
+resources:
+- 000-service-web.yaml
+- 001-deployment-web.yaml
+`, string(kustomization))
+		t.CheckFileExistAndContent(tmpDir.Path("rendered/000-service-web.yaml"), []byte(serviceYAML+"\n"))
+		t.CheckFileExistAndContent(tmpDir.Path("rendered/001-deployment-web.yaml"), []byte(deploymentYAML+"\n"))
+
+		// The component can be used by another kustomization.
+		deps, err := dependenciesForKustomization(tmpDir.Path("app"), dependencyOptions{}, 0)
+		t.CheckNoError(err)
+		t.CheckDeepEqual([]string{
+			tmpDir.Path("app/kustomization.yaml"),
+			tmpDir.Path("rendered/kustomization.yaml"),
+			tmpDir.Path("rendered/000-service-web.yaml"),
+			tmpDir.Path("rendered/001-deployment-web.yaml"),
+		}, deps)
+	})
+}
+
+func TestWriteComponentRemovesStaleFiles(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir()
+
+		err := writeComponent(tmpDir.Path("rendered"), manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)})
+		t.RequireNoError(err)
+
+		err = writeComponent(tmpDir.Path("rendered"), manifest.ManifestList{[]byte(deploymentYAML)})
+		t.RequireNoError(err)
+
+		files, err := ioutil.ReadDir(tmpDir.Path("rendered"))
+		t.RequireNoError(err)
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+		t.CheckDeepEqual([]string{"000-deployment-web.yaml", "kustomization.yaml"}, names)
+	})
+}
+
+func TestKustomizeRenderComponentWithOutputFile(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir()
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:     []string{"."},
+			RenderComponentDir: tmpDir.Path("rendered"),
+		})
+		t.RequireNoError(err)
+
+		err = k.Render(context.Background(), ioutil.Discard, nil, true, tmpDir.Path("output.yaml"))
+
+		t.CheckErrorContains("renderComponentDir can't be combined with an output file", err)
+	})
+}
+
+func TestWriteComponentInvalidResources(t *testing.T) {
+	tests := []struct {
+		description string
+		manifests   manifest.ManifestList
+		expected    string
+	}{
+		{
+			description: "duplicate resource",
+			manifests:   manifest.ManifestList{[]byte(serviceYAML), []byte(serviceYAML)},
+			expected:    "invalid component: Service/web is rendered more than once",
+		},
+		{
+			description: "missing name",
+			manifests:   manifest.ManifestList{[]byte("apiVersion: v1\nkind: ConfigMap")},
+			expected:    "invalid component: every resource needs a kind and a name",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			err := writeComponent(t.NewTempDir().Path("rendered"), test.manifests)
+
+			t.CheckErrorContains(test.expected, err)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+// namespaceKind is the kind of Namespaces, which are applied before the resources they contain.
+const namespaceKind = "Namespace"
+
+// groupByNamespace separates the resources other resources depend on, CustomResourceDefinitions
+// and Namespaces, from the rest, which are grouped by namespace in order of appearance.
+func groupByNamespace(manifests manifest.ManifestList) (manifest.ManifestList, []manifest.ManifestList, error) {
+	var first manifest.ManifestList
+	var groups []manifest.ManifestList
+	index := map[string]int{}
+
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if r.Kind == crdKind || r.Kind == namespaceKind {
+			first = append(first, m)
+			continue
+		}
+
+		i, found := index[r.Metadata.Namespace]
+		if !found {
+			i = len(groups)
+			index[r.Metadata.Namespace] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+
+	return first, groups, nil
+}
+
+// applyByNamespace applies the CustomResourceDefinitions and Namespaces first, then the resources
+// of each namespace concurrently, with at most `applyConcurrency` applies running at the same time.
+// The errors of all the namespaces are reported.
+func (k *Deployer) applyByNamespace(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	first, groups, err := groupByNamespace(manifests)
+	if err != nil {
+		return err
+	}
+
+	// Each group is compared to the manifests applied by the previous deploy.
+	previous := k.kubectl
+
+	if len(first) > 0 {
+		if k.WaitForCRDs {
+			err = k.applyCRDsFirst(ctx, out, first)
+		} else {
+			cli := previous
+			err = k.kubectlApplyWith(ctx, &cli, out, first)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		wg     sync.WaitGroup
+		outMu  sync.Mutex
+		errsMu sync.Mutex
+		errs   []string
+		sem    = make(chan struct{}, k.ApplyConcurrency)
+	)
+	for _, group := range groups {
+		wg.Add(1)
+		go func(group manifest.ManifestList) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// Buffer the output so that concurrent applies don't interleave.
+			var buf bytes.Buffer
+			cli := previous
+			err := k.kubectlApplyWith(ctx, &cli, &buf, group)
+
+			outMu.Lock()
+			out.Write(buf.Bytes())
+			outMu.Unlock()
+
+			if err != nil {
+				errsMu.Lock()
+				errs = append(errs, err.Error())
+				errsMu.Unlock()
+			}
+		}(group)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return userErr(fmt.Errorf("applying %d of %d namespaces failed: %s", len(errs), len(groups), strings.Join(errs, "; ")))
+	}
+
+	k.kubectl.RememberApplied(manifests)
+	return nil
+}
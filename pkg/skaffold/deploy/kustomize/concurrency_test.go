@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const (
+	namespacesYAML = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: b`
+	namespaceAYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: a`
+	namespaceBYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: b`
+)
+
+// concurrentApplies fakes `kubectl apply` and records how many namespaces were applied at the same time.
+type concurrentApplies struct {
+	mu          sync.Mutex
+	applied     []string
+	inFlight    int
+	maxInFlight int
+	failures    map[string]bool
+}
+
+func (c *concurrentApplies) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Args[0] == "kustomize" {
+		return []byte(namespacesYAML + "\n---\n" + namespaceAYAML + "\n---\n" + namespaceBYAML), nil
+	}
+	return []byte(kubectl.KubectlVersion118), nil
+}
+
+func (c *concurrentApplies) RunCmd(cmd *exec.Cmd) error {
+	input, err := ioutil.ReadAll(cmd.Stdin)
+	if err != nil {
+		return err
+	}
+	manifests := string(input)
+
+	c.mu.Lock()
+	c.applied = append(c.applied, manifests)
+	if strings.Contains(manifests, "kind: Namespace") {
+		c.mu.Unlock()
+		return nil
+	}
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.mu.Unlock()
+
+	// Wait for the other namespace to be applied concurrently.
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		done := c.maxInFlight > 1
+		c.mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	if c.failures[manifests] {
+		return errors.New("apply failed")
+	}
+	return nil
+}
+
+func TestKustomizeApplyConcurrency(t *testing.T) {
+	tests := []struct {
+		description string
+		failures    map[string]bool
+		shouldErr   bool
+	}{
+		{
+			description: "namespaces applied concurrently",
+		},
+		{
+			description: "errors are aggregated",
+			failures:    map[string]bool{namespaceAYAML: true, namespaceBYAML: true},
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fake := &concurrentApplies{failures: test.failures}
+			t.Override(&util.DefaultExecCommand, fake)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:   []string{"."},
+				ApplyConcurrency: 2,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				t.CheckErrorContains("applying 2 of 2 namespaces failed", err)
+			}
+			t.CheckDeepEqual(2, fake.maxInFlight)
+			t.CheckDeepEqual(namespacesYAML, fake.applied[0])
+			t.CheckDeepEqual(3, len(fake.applied))
+		})
+	}
+}
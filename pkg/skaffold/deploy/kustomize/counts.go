@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+// validateResourceCounts checks the minimum numbers of resources expected to be applied
+// and the maximum number of resources a render can produce.
+func validateResourceCounts(total int, perKind map[string]int, max int) error {
+	if total < 0 {
+		return fmt.Errorf("invalid minResources %d: must not be negative", total)
+	}
+	if max < 0 {
+		return fmt.Errorf("invalid maxResources %d: must not be negative", max)
+	}
+	if max > 0 && max < total {
+		return fmt.Errorf("invalid maxResources %d: must not be lower than minResources %d", max, total)
+	}
+	for kind, count := range perKind {
+		if kind == "" {
+			return fmt.Errorf("invalid minResourcesPerKind: kinds must not be empty")
+		}
+		if count < 0 {
+			return fmt.Errorf("invalid minResourcesPerKind %d for %s: must not be negative", count, kind)
+		}
+	}
+	return nil
+}
+
+// checkResourceCounts fails if fewer resources were applied than expected, overall or for a given kind,
+// which usually means that the render was truncated.
+func checkResourceCounts(applied manifest.ManifestList, total int, perKind map[string]int) error {
+	counts := map[string]int{}
+	for _, m := range applied {
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+		counts[r.Kind]++
+	}
+
+	var missing []string
+	if len(applied) < total {
+		missing = append(missing, fmt.Sprintf("%d resources instead of at least %d", len(applied), total))
+	}
+
+	var kinds []string
+	for kind := range perKind {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		if counts[kind] < perKind[kind] {
+			missing = append(missing, fmt.Sprintf("%d %s instead of at least %d", counts[kind], kind, perKind[kind]))
+		}
+	}
+
+	if len(missing) > 0 {
+		return userErr(fmt.Errorf("fewer resources were applied than expected: %s", strings.Join(missing, ", ")))
+	}
+	return nil
+}
+
+// checkMaxResources fails if a render produced more resources than allowed, reporting
+// the kinds with the most resources since they are the likely culprits.
+func checkMaxResources(rendered manifest.ManifestList, max int) error {
+	if len(rendered) <= max {
+		return nil
+	}
+
+	counts := map[string]int{}
+	var kinds []string
+	for _, m := range rendered {
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+		if counts[r.Kind] == 0 {
+			kinds = append(kinds, r.Kind)
+		}
+		counts[r.Kind]++
+	}
+	sort.SliceStable(kinds, func(i, j int) bool { return counts[kinds[i]] > counts[kinds[j]] })
+	if len(kinds) > 3 {
+		kinds = kinds[:3]
+	}
+
+	var largest []string
+	for _, kind := range kinds {
+		largest = append(largest, fmt.Sprintf("%d %s", counts[kind], kind))
+	}
+	return userErr(fmt.Errorf("rendered %d resources, more than the maxResources limit of %d: the largest kinds are %s",
+		len(rendered), max, strings.Join(largest, ", ")))
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeMinResources(t *testing.T) {
+	tests := []struct {
+		description string
+		total       int
+		perKind     map[string]int
+		rendered    string
+		expectedErr string
+	}{
+		{
+			description: "expectations met",
+			total:       2,
+			perKind:     map[string]int{"Deployment": 1, "Service": 1},
+			rendered:    serviceYAML + "\n---\n" + deploymentYAML,
+		},
+		{
+			description: "too few resources",
+			total:       3,
+			rendered:    serviceYAML + "\n---\n" + deploymentYAML,
+			expectedErr: "fewer resources were applied than expected: 2 resources instead of at least 3",
+		},
+		{
+			description: "too few of a kind",
+			perKind:     map[string]int{"Deployment": 2, "Service": 1, "ConfigMap": 1},
+			rendered:    serviceYAML + "\n---\n" + deploymentYAML,
+			expectedErr: "fewer resources were applied than expected: 0 ConfigMap instead of at least 1, 1 Deployment instead of at least 2",
+		},
+		{
+			description: "empty render",
+			total:       1,
+			expectedErr: "fewer resources were applied than expected: 0 resources instead of at least 1",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			commands := testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", test.rendered)
+			if test.rendered != "" {
+				commands = commands.AndRun(applyCommand)
+			}
+			t.Override(&util.DefaultExecCommand, commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"."},
+				MinResources:        test.total,
+				MinResourcesPerKind: test.perKind,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			if test.expectedErr != "" {
+				t.CheckErrorContains(test.expectedErr, err)
+			} else {
+				t.CheckNoError(err)
+			}
+		})
+	}
+}
+
+func TestKustomizeMinResourcesOnlyNewResources(t *testing.T) {
+	tests := []struct {
+		description string
+		commands    util.Command
+	}{
+		{
+			description: "redeploy with all the resources existing",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML+"\n---\n"+serviceYAML).
+				AndRunInputOut(getLiveCommand, deploymentYAML+"\n---\n"+serviceYAML, liveDeploymentYAML+`
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: web
+    namespace: testNamespace`),
+		},
+		{
+			description: "redeploy with some resources existing",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML+"\n---\n"+serviceYAML).
+				AndRunInputOut(getLiveCommand, deploymentYAML+"\n---\n"+serviceYAML, liveDeploymentYAML).
+				AndRunInput(applyCommand, serviceYAML),
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"."},
+				OnlyNewResources:    true,
+				MinResources:        2,
+				MinResourcesPerKind: map[string]int{"Deployment": 1},
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckNoError(err)
+		})
+	}
+}
+
+func TestKustomizeRenderMaxResources(t *testing.T) {
+	const configMapsYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: c`
+
+	tests := []struct {
+		description string
+		max         int
+		expectedErr string
+	}{
+		{
+			description: "limit exceeded",
+			max:         4,
+			expectedErr: "rendered 5 resources, more than the maxResources limit of 4: the largest kinds are 3 ConfigMap, 1 Service, 1 Deployment",
+		},
+		{
+			description: "at the limit",
+			max:         5,
+		},
+		{
+			description: "unlimited by default",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", serviceYAML+"\n---\n"+configMapsYAML+"\n---\n"+deploymentYAML))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				MaxResources:   test.max,
+			})
+			t.RequireNoError(err)
+
+			err = k.Render(context.Background(), ioutil.Discard, nil, true, "")
+
+			if test.expectedErr != "" {
+				t.CheckErrorContains(test.expectedErr, err)
+			} else {
+				t.CheckNoError(err)
+			}
+		})
+	}
+}
+
+func TestValidateResourceCounts(t *testing.T) {
+	testutil.CheckError(t, false, validateResourceCounts(0, nil, 0))
+	testutil.CheckError(t, false, validateResourceCounts(3, map[string]int{"Deployment": 1}, 0))
+	testutil.CheckError(t, true, validateResourceCounts(-1, nil, 0))
+	testutil.CheckError(t, true, validateResourceCounts(0, map[string]int{"Deployment": -1}, 0))
+	testutil.CheckError(t, true, validateResourceCounts(0, map[string]int{"": 1}, 0))
+	testutil.CheckError(t, false, validateResourceCounts(2, nil, 10))
+	testutil.CheckError(t, true, validateResourceCounts(0, nil, -1))
+	testutil.CheckError(t, true, validateResourceCounts(5, nil, 4))
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+const (
+	crdKind               = "CustomResourceDefinition"
+	defaultCRDWaitTimeout = "60s"
+)
+
+// splitCRDs separates the CustomResourceDefinitions from the other resources.
+// It also returns the names of the CustomResourceDefinitions, in order.
+func splitCRDs(manifests manifest.ManifestList) (manifest.ManifestList, []string, manifest.ManifestList, error) {
+	var crds, others manifest.ManifestList
+	var names []string
+
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if r.Kind == crdKind {
+			crds = append(crds, m)
+			names = append(names, r.Metadata.Name)
+		} else {
+			others = append(others, m)
+		}
+	}
+
+	return crds, names, others, nil
+}
+
+// applyCRDsFirst applies the CustomResourceDefinitions, waits for them to
+// be established and only then applies the remaining resources.
+func (k *Deployer) applyCRDsFirst(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	crds, names, others, err := splitCRDs(manifests)
+	if err != nil {
+		return err
+	}
+
+	if len(crds) == 0 {
+		return k.kubectlApply(ctx, out, manifests)
+	}
+
+	// CustomResourceDefinitions that already exist are established, even when they are updated.
+	toWait := names
+	if k.SkipExistingCRDWait {
+		toWait = k.newCRDs(ctx, names)
+	}
+
+	if err := k.kubectlApply(ctx, out, crds); err != nil {
+		return err
+	}
+
+	if err := k.waitForCRDs(ctx, out, toWait); err != nil {
+		return err
+	}
+
+	if len(others) == 0 {
+		return nil
+	}
+	return k.kubectlApply(ctx, out, others)
+}
+
+// waitForCRDs runs `kubectl wait --for=condition=established` on each of the given CustomResourceDefinitions.
+func (k *Deployer) waitForCRDs(ctx context.Context, out io.Writer, names []string) error {
+	timeout := k.CRDWaitTimeout
+	if timeout == "" {
+		timeout = defaultCRDWaitTimeout
+	}
+
+	for _, name := range names {
+		if err := k.kubectl.Run(ctx, nil, out, "wait", "--for=condition=established", "--timeout="+timeout, "crd/"+name); err != nil {
+			return userErr(fmt.Errorf("waiting for CustomResourceDefinition %q to be established: %w", name, err))
+		}
+	}
+
+	return nil
+}
+
+// newCRDs returns the CustomResourceDefinitions that don't exist on the cluster yet.
+// If the cluster can't be queried, all of them are considered new.
+func (k *Deployer) newCRDs(ctx context.Context, names []string) []string {
+	var args []string
+	for _, name := range names {
+		args = append(args, "crd/"+name)
+	}
+
+	out, err := k.kubectl.RunOut(ctx, "get", append([]string{"--ignore-not-found", "-o", "name"}, args...)...)
+	if err != nil {
+		logrus.Warnf("unable to check which CustomResourceDefinitions exist: %v", err)
+		return names
+	}
+
+	existing := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		// Names are printed as `customresourcedefinition.apiextensions.k8s.io/foos.example.com`.
+		if i := strings.Index(line, "/"); i >= 0 {
+			existing[strings.TrimSpace(line[i+1:])] = true
+		}
+	}
+
+	var created []string
+	for _, name := range names {
+		if existing[name] {
+			logrus.Debugf("CustomResourceDefinition %q already exists, not waiting for it", name)
+			continue
+		}
+		created = append(created, name)
+	}
+	return created
+}
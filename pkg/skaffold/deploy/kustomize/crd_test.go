@@ -0,0 +1,169 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const (
+	crdYAML = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com`
+	crYAML = `apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: foo`
+	barCRDYAML = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: bars.example.com`
+)
+
+func TestKustomizeDeployCRDsFirst(t *testing.T) {
+	tests := []struct {
+		description string
+		kustomize   latestV1.KustomizeDeploy
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "wait for crd with default timeout",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				WaitForCRDs:    true,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crdYAML+"\n---\n"+crYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crdYAML).
+				AndRun("kubectl --context kubecontext --namespace testNamespace wait --for=condition=established --timeout=60s crd/foos.example.com").
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crYAML),
+		},
+		{
+			description: "wait for crd with custom timeout",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				WaitForCRDs:    true,
+				CRDWaitTimeout: "5s",
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crYAML+"\n---\n"+crdYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crdYAML).
+				AndRun("kubectl --context kubecontext --namespace testNamespace wait --for=condition=established --timeout=5s crd/foos.example.com").
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crYAML),
+		},
+		{
+			description: "crd never established",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				WaitForCRDs:    true,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crdYAML+"\n---\n"+crYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crdYAML).
+				AndRunErr("kubectl --context kubecontext --namespace testNamespace wait --for=condition=established --timeout=60s crd/foos.example.com", errors.New("timed out")),
+			shouldErr: true,
+		},
+		{
+			description: "skip the wait for existing crds",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"."},
+				WaitForCRDs:         true,
+				SkipExistingCRDWait: true,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crdYAML+"\n---\n"+barCRDYAML+"\n---\n"+crYAML).
+				AndRunOut("kubectl --context kubecontext --namespace testNamespace get --ignore-not-found -o name crd/foos.example.com crd/bars.example.com", "customresourcedefinition.apiextensions.k8s.io/foos.example.com\n").
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crdYAML+"\n---\n"+barCRDYAML).
+				AndRun("kubectl --context kubecontext --namespace testNamespace wait --for=condition=established --timeout=60s crd/bars.example.com").
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crYAML),
+		},
+		{
+			description: "wait for all crds when they can't be looked up",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"."},
+				WaitForCRDs:         true,
+				SkipExistingCRDWait: true,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crdYAML+"\n---\n"+crYAML).
+				AndRunOutErr("kubectl --context kubecontext --namespace testNamespace get --ignore-not-found -o name crd/foos.example.com", "", errors.New("forbidden")).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crdYAML).
+				AndRun("kubectl --context kubecontext --namespace testNamespace wait --for=condition=established --timeout=60s crd/foos.example.com").
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crYAML),
+		},
+		{
+			description: "no crd",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				WaitForCRDs:    true,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", crYAML),
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &test.kustomize)
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestInvalidCRDWaitTimeout(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		_, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			WaitForCRDs:    true,
+			CRDWaitTimeout: "soon",
+		})
+
+		t.CheckErrorContains(`invalid crdWaitTimeout "soon"`, err)
+	})
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	depfileList = "list"
+	depfileMake = "make"
+)
+
+// validateDepfileFormat checks the format of the dependency file.
+func validateDepfileFormat(format string) error {
+	switch format {
+	case "", depfileList, depfileMake:
+		return nil
+	default:
+		return fmt.Errorf("invalid depfileFormat %q: must be either %q or %q", format, depfileList, depfileMake)
+	}
+}
+
+// writeDepfile writes the dependencies to a file, either one path per line or as a Makefile rule
+// whose target is the depfile itself. The file is only rewritten when its content changes,
+// so that tools watching it are not triggered needlessly.
+func writeDepfile(path, format string, absolute bool, deps []string) error {
+	var paths []string
+	for _, dep := range deps {
+		p, err := depfilePath(dep, absolute)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, p)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case depfileMake:
+		buf.WriteString(escapeMakePath(path) + ":")
+		for _, p := range paths {
+			buf.WriteString(" \\\n  " + escapeMakePath(p))
+		}
+		buf.WriteString("\n")
+	default:
+		for _, p := range paths {
+			buf.WriteString(p + "\n")
+		}
+	}
+
+	if previous, err := ioutil.ReadFile(path); err == nil && bytes.Equal(previous, buf.Bytes()) {
+		return nil
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing depfile %q: %w", path, err)
+	}
+	return nil
+}
+
+// depfilePath returns either the absolute path of a dependency, or its path relative to the working directory.
+func depfilePath(dep string, absolute bool) (string, error) {
+	abs, err := filepath.Abs(dep)
+	if err != nil {
+		return "", err
+	}
+	if absolute {
+		return abs, nil
+	}
+
+	wd, err := filepath.Abs(".")
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil {
+		return abs, nil
+	}
+	return rel, nil
+}
+
+// escapeMakePath escapes the characters that have a special meaning in a Makefile rule.
+func escapeMakePath(path string) string {
+	return strings.NewReplacer(" ", `\ `, "#", `\#`, "$", "$$").Replace(path)
+}
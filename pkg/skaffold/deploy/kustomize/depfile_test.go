@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestDepfile(t *testing.T) {
+	tests := []struct {
+		description string
+		format      string
+		absolute    bool
+		expected    func(root string) string
+	}{
+		{
+			description: "list",
+			expected: func(string) string {
+				return "app/kustomization.yaml\napp/my pod.yaml\n"
+			},
+		},
+		{
+			description: "make",
+			format:      "make",
+			expected: func(string) string {
+				return "deps.d: \\\n  app/kustomization.yaml \\\n  app/my\\ pod.yaml\n"
+			},
+		},
+		{
+			description: "absolute paths",
+			absolute:    true,
+			expected: func(root string) string {
+				return root + "/app/kustomization.yaml\n" + root + "/app/my pod.yaml\n"
+			},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir().
+				Write("app/kustomization.yaml", `resources:
+- my pod.yaml`).
+				Write("app/my pod.yaml", "").
+				Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:       []string{"app"},
+				Depfile:              "deps.d",
+				DepfileFormat:        test.format,
+				DepfileAbsolutePaths: test.absolute,
+			})
+			t.RequireNoError(err)
+
+			_, err = k.Dependencies()
+			t.CheckNoError(err)
+
+			content, err := ioutil.ReadFile(tmpDir.Path("deps.d"))
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected(tmpDir.Root()), string(content))
+		})
+	}
+}
+
+func TestInvalidDepfileFormat(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		_, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			Depfile:       "deps.d",
+			DepfileFormat: "ninja",
+		})
+
+		t.CheckErrorContains(`invalid depfileFormat "ninja"`, err)
+	})
+}
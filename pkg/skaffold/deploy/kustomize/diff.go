@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// writeRenderDiff compares the rendered manifests to a previous render, resource by resource.
+// Both sides are normalized, so that the order of the resources and of their fields doesn't matter.
+func writeRenderDiff(out io.Writer, previousFile string, rendered string) error {
+	previous, err := ioutil.ReadFile(previousFile)
+	if err != nil {
+		return userErr(fmt.Errorf("reading previous render %q: %w", previousFile, err))
+	}
+
+	before, err := parseObjects(previous)
+	if err != nil {
+		return userErr(fmt.Errorf("reading previous render %q: %w", previousFile, err))
+	}
+	after, err := parseObjects([]byte(rendered))
+	if err != nil {
+		return err
+	}
+
+	beforeByKey := map[string]map[string]interface{}{}
+	for _, obj := range before {
+		beforeByKey[objectKey(obj)] = obj
+	}
+
+	seen := map[string]bool{}
+	for _, obj := range after {
+		key := objectKey(obj)
+		seen[key] = true
+		id := objectResource(obj).kubectlID()
+
+		afterYAML, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+
+		previousObj, found := beforeByKey[key]
+		if !found {
+			printPrefixedLines(out, fmt.Sprintf("+++ %s (added)", id), "+ ", string(afterYAML))
+			continue
+		}
+
+		beforeYAML, err := yaml.Marshal(previousObj)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(beforeYAML, afterYAML) {
+			continue
+		}
+
+		fmt.Fprintf(out, "--- %s (previous)\n+++ %s (rendered)\n", id, id)
+		for _, line := range diffLines(string(beforeYAML), string(afterYAML)) {
+			fmt.Fprintln(out, line)
+		}
+	}
+
+	for _, obj := range before {
+		if seen[objectKey(obj)] {
+			continue
+		}
+
+		beforeYAML, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		printPrefixedLines(out, fmt.Sprintf("--- %s (removed)", objectResource(obj).kubectlID()), "- ", string(beforeYAML))
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestWriteRenderDiff(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir().Write("previous.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: old
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  creationTimestamp: null
+  name: web
+spec:
+  replicas: 1
+---
+`+serviceYAML)
+
+		rendered := serviceYAML + `
+---
+kind: Deployment
+apiVersion: apps/v1
+spec:
+  replicas: 2
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: new`
+
+		var out bytes.Buffer
+		err := writeRenderDiff(&out, tmpDir.Path("previous.yaml"), rendered)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual(`--- deployment.apps/web (previous)
++++ deployment.apps/web (rendered)
+  apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+  spec:
+-   replicas: 1
++   replicas: 2
++++ secret/new (added)
++ apiVersion: v1
++ kind: Secret
++ metadata:
++   name: new
+--- configmap/old (removed)
+- apiVersion: v1
+- kind: ConfigMap
+- metadata:
+-   name: old
+`, out.String())
+	})
+}
+
+func TestWriteRenderDiffMissingFile(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		err := writeRenderDiff(&bytes.Buffer{}, "does-not-exist.yaml", serviceYAML)
+
+		t.CheckErrorContains(`reading previous render "does-not-exist.yaml"`, err)
+	})
+}
+
+func TestKustomizeRenderDiffWithOutputFile(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir().Write("previous.yaml", serviceYAML)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:    []string{"."},
+			RenderDiffAgainst: tmpDir.Path("previous.yaml"),
+		})
+		t.RequireNoError(err)
+
+		err = k.Render(context.Background(), ioutil.Discard, nil, true, tmpDir.Path("output.yaml"))
+
+		t.CheckErrorContains("renderDiffAgainst can't be combined with an output file", err)
+	})
+}
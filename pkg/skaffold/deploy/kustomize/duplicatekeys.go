@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+const (
+	duplicateKeysWarn  = "warn"
+	duplicateKeysError = "error"
+)
+
+// validateDuplicateKeys checks how documents with duplicate mapping keys are reported.
+func validateDuplicateKeys(mode string) error {
+	switch mode {
+	case "", duplicateKeysWarn, duplicateKeysError:
+		return nil
+	default:
+		return fmt.Errorf("invalid duplicateKeys %q: must be either %q or %q", mode, duplicateKeysWarn, duplicateKeysError)
+	}
+}
+
+// checkDuplicateKeys reports the mapping keys set more than once in the documents built from a kustomize path.
+func (k *Deployer) checkDuplicateKeys(kustomizePath string, manifests manifest.ManifestList) error {
+	for i, m := range manifests {
+		var doc yamlv3.Node
+		if err := yamlv3.Unmarshal(m, &doc); err != nil {
+			// Invalid documents are reported by the steps that parse them.
+			continue
+		}
+
+		for _, duplicate := range duplicateKeys(&doc, "") {
+			message := fmt.Sprintf("document %d built from %s sets %s", i+1, kustomizePath, duplicate)
+			if k.DuplicateKeys == duplicateKeysError {
+				return userErr(fmt.Errorf("%s", message))
+			}
+			warnings.Printf("%s", message)
+		}
+	}
+	return nil
+}
+
+// duplicateKeys walks a YAML node and describes each mapping key that is set more than once,
+// e.g. `metadata.labels.app more than once, on lines 5 and 7`.
+func duplicateKeys(node *yamlv3.Node, path string) []string {
+	var duplicates []string
+	switch node.Kind {
+	case yamlv3.DocumentNode, yamlv3.SequenceNode:
+		for i, child := range node.Content {
+			childPath := path
+			if node.Kind == yamlv3.SequenceNode {
+				childPath = fmt.Sprintf("%s[%d]", path, i)
+			}
+			duplicates = append(duplicates, duplicateKeys(child, childPath)...)
+		}
+	case yamlv3.MappingNode:
+		lines := map[string][]string{}
+		var keys []string
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if _, found := lines[key.Value]; !found {
+				keys = append(keys, key.Value)
+			}
+			lines[key.Value] = append(lines[key.Value], fmt.Sprint(key.Line))
+
+			childPath := key.Value
+			if path != "" {
+				childPath = path + "." + key.Value
+			}
+			duplicates = append(duplicates, duplicateKeys(value, childPath)...)
+		}
+		for _, key := range keys {
+			if len(lines[key]) > 1 {
+				keyPath := key
+				if path != "" {
+					keyPath = path + "." + key
+				}
+				duplicates = append(duplicates, fmt.Sprintf("%s more than once, on lines %s", keyPath, strings.Join(lines[key], " and ")))
+			}
+		}
+	}
+	return duplicates
+}
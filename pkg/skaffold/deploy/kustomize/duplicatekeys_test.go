@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeDuplicateKeys(t *testing.T) {
+	const duplicateKeyYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  labels:
+    app: web
+    app: api
+data:
+  LOG_LEVEL: info
+  LOG_LEVEL: debug`
+
+	tests := []struct {
+		description      string
+		mode             string
+		expectedWarnings []string
+		shouldErr        bool
+	}{
+		{
+			description: "warn",
+			mode:        "warn",
+			expectedWarnings: []string{
+				"document 2 built from . sets data.LOG_LEVEL more than once, on lines 9 and 10",
+				"document 2 built from . sets metadata.labels.app more than once, on lines 6 and 7",
+			},
+		},
+		{
+			description: "error",
+			mode:        "error",
+			shouldErr:   true,
+		},
+		{
+			description: "not checked by default",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+			t.Override(&util.DefaultExecCommand, testutil.CmdRunOut("kustomize build .", serviceYAML+"\n---\n"+duplicateKeyYAML))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				DuplicateKeys:  test.mode,
+			})
+			t.RequireNoError(err)
+
+			_, err = k.readManifests(context.Background(), ioutil.Discard)
+
+			t.CheckError(test.shouldErr, err)
+			t.CheckDeepEqual(test.expectedWarnings, fakeWarner.Warnings)
+		})
+	}
+}
+
+func TestValidateDuplicateKeys(t *testing.T) {
+	testutil.CheckError(t, false, validateDuplicateKeys(""))
+	testutil.CheckError(t, false, validateDuplicateKeys("warn"))
+	testutil.CheckError(t, true, validateDuplicateKeys("fail"))
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+)
+
+// allowEmptyAnnotation marks, in the metadata of a kustomization, a kustomize path that is expected to render nothing.
+const allowEmptyAnnotation = "skaffold.dev/allow-empty"
+
+// checkEmptyRender fails when nothing was rendered even though some of the kustomize paths
+// are not marked as intentionally empty.
+func checkEmptyRender(kustomizePaths []string) error {
+	var unexpected []string
+	for _, path := range kustomizePaths {
+		if !intentionallyEmpty(path) {
+			unexpected = append(unexpected, path)
+		}
+	}
+	if len(unexpected) == 0 {
+		return nil
+	}
+
+	return userErr(fmt.Errorf("kustomize rendered no resources for %s: mark the kustomizations that are expected to be empty with the %q annotation", strings.Join(unexpected, ", "), allowEmptyAnnotation))
+}
+
+// intentionallyEmpty returns true if the kustomization of the path carries the `skaffold.dev/allow-empty: "true"` annotation.
+// Remote and OCI paths can't be marked.
+func intentionallyEmpty(path string) bool {
+	if isOCIKustomization(path) || isRemoteBase(path) {
+		return false
+	}
+
+	kFile, err := FindKustomizationConfig(path)
+	if err != nil {
+		return false
+	}
+	content, err := readKustomization(kFile)
+	if err != nil {
+		return false
+	}
+	return content.Metadata.Annotations[allowEmptyAnnotation] == "true"
+}
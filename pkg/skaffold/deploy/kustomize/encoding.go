@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"io/ioutil"
+	"unicode/utf8"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// utf8BOM is the byte order mark some editors write at the start of UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// stripBOMs removes the byte order marks that kustomize copies from generator files into its output,
+// at the start of the output or of any of its lines, since they break the parsing of the manifests.
+func stripBOMs(buf []byte) []byte {
+	buf = bytes.TrimPrefix(buf, utf8BOM)
+	return bytes.ReplaceAll(buf, append([]byte("\n"), utf8BOM...), []byte("\n"))
+}
+
+// warnInvalidEncodings warns about generator files that are not valid UTF-8.
+// Files that contain NUL bytes are considered binary on purpose and skipped.
+func warnInvalidEncodings(dir string, files []string) {
+	for _, path := range invalidEncodings(dir, files) {
+		warnings.Printf("generator file %q is not valid UTF-8, its content might be broken in the generated resource", path)
+	}
+}
+
+// invalidEncodings returns the generator files that exist locally but are neither valid UTF-8 nor binary.
+func invalidEncodings(dir string, files []string) []string {
+	var invalid []string
+	for _, file := range files {
+		if local, mode := pathExistsLocally(file, dir); !local || mode.IsDir() {
+			continue
+		}
+
+		path := util.AbsolutePaths(dir, []string{file})[0]
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if bytes.IndexByte(content, 0) >= 0 {
+			continue
+		}
+		if !utf8.Valid(content) {
+			invalid = append(invalid, path)
+		}
+	}
+	return invalid
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeGeneratorEncoding(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.NewTempDir().
+			Write("app/kustomization.yaml", `configMapGenerator:
+- name: config
+  files:
+  - utf8.txt
+  - legacy=latin1.txt
+  - logo.png
+secretGenerator:
+- name: secret
+  envs:
+  - secret.env`).
+			Write("app/utf8.txt", "héllo").
+			Write("app/latin1.txt", "h\xe9llo").
+			Write("app/logo.png", "\x89PNG\x00\xff").
+			Write("app/secret.env", "PASSWORD=\xe9t\xe9").
+			Chdir()
+		fakeWarner := &warnings.Collect{}
+		t.Override(&warnings.Printf, fakeWarner.Warnf)
+		t.Override(&util.DefaultExecCommand, testutil.CmdRunOut("kustomize build app", serviceYAML))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:            []string{"app"},
+			ValidateGeneratorEncoding: true,
+		})
+		t.RequireNoError(err)
+
+		_, err = k.readManifests(context.Background(), ioutil.Discard)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual([]string{
+			`generator file "app/latin1.txt" is not valid UTF-8, its content might be broken in the generated resource`,
+			`generator file "app/secret.env" is not valid UTF-8, its content might be broken in the generated resource`,
+		}, fakeWarner.Warnings)
+	})
+}
+
+func TestKustomizeBuildOutputBOM(t *testing.T) {
+	tests := []struct {
+		description string
+		output      string
+	}{
+		{
+			description: "at the start of the output",
+			output:      "\xef\xbb\xbf" + serviceYAML + "\n---\n" + deploymentYAML,
+		},
+		{
+			description: "at the start of a document",
+			output:      serviceYAML + "\n---\n\xef\xbb\xbf" + deploymentYAML,
+		},
+		{
+			description: "no byte order mark",
+			output:      serviceYAML + "\n---\n" + deploymentYAML,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, testutil.CmdRunOut("kustomize build .", test.output))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+			})
+			t.RequireNoError(err)
+
+			manifests, err := k.readManifests(context.Background(), ioutil.Discard)
+
+			expected := manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)}
+			t.CheckErrorAndDeepEqual(false, err, expected.String(), manifests.String())
+			_, err = manifests.GetImages()
+			t.CheckNoError(err)
+		})
+	}
+}
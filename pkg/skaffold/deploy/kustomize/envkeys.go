@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// warnDuplicateEnvKeys warns about keys that are set more than once in a generator env file,
+// since kustomize silently keeps the last value.
+func warnDuplicateEnvKeys(dir string, envs []string) {
+	for _, env := range envs {
+		if local, mode := pathExistsLocally(env, dir); !local || mode.IsDir() {
+			continue
+		}
+
+		path := util.AbsolutePaths(dir, []string{env})[0]
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, key := range duplicateEnvKeys(content) {
+			warnings.Printf("generator env file %q sets %q more than once, only the last value is used", path, key)
+		}
+	}
+}
+
+// duplicateEnvKeys returns the keys of an env file that are set more than once, in order of first duplication.
+// Like kustomize, it skips blank lines and comments, and reads lines without `=` as keys.
+func duplicateEnvKeys(content []byte) []string {
+	seen := map[string]int{}
+	var duplicates []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(content, utf8BOM)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key := strings.TrimSpace(strings.SplitN(line, "=", 2)[0])
+		seen[key]++
+		if seen[key] == 2 {
+			duplicates = append(duplicates, key)
+		}
+	}
+	return duplicates
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeDuplicateEnvKeys(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.NewTempDir().
+			Write("app/kustomization.yaml", `configMapGenerator:
+- name: config
+  env: config.env
+secretGenerator:
+- name: secret
+  envs:
+  - secret.env
+  - unique.env`).
+			Write("app/config.env", "# comment\nLOG_LEVEL=info\n\nPORT=8080\nLOG_LEVEL=debug\nLOG_LEVEL=trace\n").
+			Write("app/secret.env", "\xef\xbb\xbfTOKEN=a\nHOME\n  TOKEN = b\nHOME\n").
+			Write("app/unique.env", "USER=admin\n# USER=root\n").
+			Chdir()
+		fakeWarner := &warnings.Collect{}
+		t.Override(&warnings.Printf, fakeWarner.Warnf)
+		t.Override(&util.DefaultExecCommand, testutil.CmdRunOut("kustomize build app", serviceYAML))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:       []string{"app"},
+			WarnDuplicateEnvKeys: true,
+		})
+		t.RequireNoError(err)
+
+		_, err = k.readManifests(context.Background(), ioutil.Discard)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual([]string{
+			`generator env file "app/config.env" sets "LOG_LEVEL" more than once, only the last value is used`,
+			`generator env file "app/secret.env" sets "HOME" more than once, only the last value is used`,
+			`generator env file "app/secret.env" sets "TOKEN" more than once, only the last value is used`,
+		}, fakeWarner.Warnings)
+	})
+}
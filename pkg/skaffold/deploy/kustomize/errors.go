@@ -17,10 +17,26 @@ limitations under the License.
 package kustomize
 
 import (
+	"fmt"
+
 	deployerr "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/error"
+	sErrors "github.com/GoogleContainerTools/skaffold/pkg/skaffold/errors"
 	"github.com/GoogleContainerTools/skaffold/proto/v1"
 )
 
 func userErr(err error) error {
 	return deployerr.UserError(err, proto.StatusCode_DEPLOY_KUSTOMIZE_USER_ERR)
 }
+
+// clusterUnreachableErr is returned by the pre-flight check of Deploy, before anything is rendered or applied.
+func clusterUnreachableErr(err error) error {
+	return sErrors.NewError(err,
+		proto.ActionableErr{
+			Message: fmt.Sprintf("cluster unreachable: unable to connect to Kubernetes: %v", err),
+			ErrCode: proto.StatusCode_DEPLOY_CLUSTER_CONNECTION_ERR,
+			Suggestions: []*proto.Suggestion{{
+				SuggestionCode: proto.SuggestionCode_CHECK_CLUSTER_CONNECTION,
+				Action:         "Check your connection for the cluster",
+			}},
+		})
+}
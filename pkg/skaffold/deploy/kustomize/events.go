@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"regexp"
+	"strings"
+
+	eventV2 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/event/v2"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+const resourceApplyFailed = "failed"
+
+var (
+	// applyStatusPattern matches the line printed by `kubectl apply` for each resource, e.g. `deployment.apps/web created`.
+	applyStatusPattern = regexp.MustCompile(`^(\S+/\S+) (created|configured|unchanged|serverside-applied)$`)
+
+	// resourceApplied is notified of the outcome of applying each resource. For testing.
+	resourceApplied = eventV2.DeployResourceApplied
+)
+
+// kubectlID returns how `kubectl` refers to the resource in its output, e.g. `deployment.apps/web`.
+func (r resource) kubectlID() string {
+	kind := strings.ToLower(r.Kind)
+	if group := r.apiGroup(); group != coreAPIGroup {
+		kind += "." + group
+	}
+	return kind + "/" + r.Metadata.Name
+}
+
+// reportAppliedResources sends a deploy event for each resource reported in the output of `kubectl apply`.
+// When the apply failed, the resources that were not reported are considered failed.
+func reportAppliedResources(manifests manifest.ManifestList, output string, applyErr error) {
+	reported := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		if match := applyStatusPattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			reported[match[1]] = true
+			resourceApplied(match[1], match[2])
+		}
+	}
+
+	if applyErr == nil {
+		return
+	}
+
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			continue
+		}
+		if id := r.kubectlID(); !reported[id] {
+			resourceApplied(id, resourceApplyFailed)
+		}
+	}
+}
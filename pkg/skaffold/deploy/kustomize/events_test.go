@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeResourceEvents(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		var events []string
+		t.Override(&resourceApplied, func(resource, status string) { events = append(events, resource+" "+status) })
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", serviceYAML+"\n---\n"+deploymentYAML).
+			AndRunWithOutput(applyCommand, "service/web unchanged\ndeployment.apps/web configured\n"))
+		t.Override(&client.Client, deployutil.MockK8sClient)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+			ResourceEvents: true,
+		})
+		t.RequireNoError(err)
+
+		err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual([]string{"service/web unchanged", "deployment.apps/web configured"}, events)
+	})
+}
+
+func TestReportAppliedResources(t *testing.T) {
+	tests := []struct {
+		description string
+		output      string
+		applyErr    error
+		expected    []string
+	}{
+		{
+			description: "all statuses",
+			output: `customresourcedefinition.apiextensions.k8s.io/foos.example.com created
+service/web configured
+deployment.apps/web unchanged
+`,
+			expected: []string{
+				"customresourcedefinition.apiextensions.k8s.io/foos.example.com created",
+				"service/web configured",
+				"deployment.apps/web unchanged",
+			},
+		},
+		{
+			description: "partial failure",
+			output: `customresourcedefinition.apiextensions.k8s.io/foos.example.com created
+Error from server (Invalid): error when creating "STDIN": Deployment.apps "web" is invalid
+`,
+			applyErr: errors.New("exit status 1"),
+			expected: []string{
+				"customresourcedefinition.apiextensions.k8s.io/foos.example.com created",
+				"deployment.apps/web failed",
+			},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			var events []string
+			t.Override(&resourceApplied, func(resource, status string) { events = append(events, resource+" "+status) })
+
+			reportAppliedResources(manifest.ManifestList{[]byte(crdYAML), []byte(deploymentYAML)}, test.output, test.applyErr)
+
+			t.CheckDeepEqual(test.expected, events)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+// filterExisting removes the resources that already exist on the cluster.
+// Resources without a namespace match the live resource of the same kind and name in any namespace,
+// since kubectl puts them in the default namespace.
+func (k *Deployer) filterExisting(ctx context.Context, manifests manifest.ManifestList) (manifest.ManifestList, error) {
+	_, created, err := k.splitExisting(ctx, manifests)
+	return created, err
+}
+
+// splitExisting separates the resources that already exist on the cluster from the others.
+// Resources of kinds unknown to the cluster, such as custom resources whose CustomResourceDefinition
+// is created by the same deployment, can't exist yet.
+func (k *Deployer) splitExisting(ctx context.Context, manifests manifest.ManifestList) (manifest.ManifestList, manifest.ManifestList, error) {
+	liveObjects, err := k.liveObjects(ctx, manifests)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	namespaces := liveNamespaces(liveObjects)
+
+	var existing, created manifest.ManifestList
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if existsIn(namespaces[r.kubectlID()], r.Metadata.Namespace) {
+			existing = append(existing, m)
+		} else {
+			created = append(created, m)
+		}
+	}
+	return existing, created, nil
+}
+
+// liveObjects fetches the live version of the given resources, skipping those that don't exist.
+// Resources of kinds unknown to the cluster don't exist.
+func (k *Deployer) liveObjects(ctx context.Context, manifests manifest.ManifestList) ([]map[string]interface{}, error) {
+	live, err := k.getLive(ctx, manifests)
+	if err != nil && isUnknownKindErr(err) {
+		// kubectl fails as soon as one of the kinds is unknown, so the resources are fetched one by one.
+		live, err = k.getLiveOneByOne(ctx, manifests)
+	}
+	if err != nil {
+		return nil, userErr(fmt.Errorf("getting live resources: %w", err))
+	}
+	return parseObjects(live)
+}
+
+// liveNamespaces lists the namespaces of the live objects, by kind and name.
+func liveNamespaces(objects []map[string]interface{}) map[string][]string {
+	namespaces := map[string][]string{}
+	for _, obj := range objects {
+		r := objectResource(obj)
+		namespaces[r.kubectlID()] = append(namespaces[r.kubectlID()], r.Metadata.Namespace)
+	}
+	return namespaces
+}
+
+// getLive fetches the live version of the given resources. Those that don't exist are ignored.
+func (k *Deployer) getLive(ctx context.Context, manifests manifest.ManifestList) ([]byte, error) {
+	return k.kubectl.RunOutInput(ctx, manifests.Reader(), "get", append(append([]string{}, k.kubectl.Flags.Global...), "--ignore-not-found", "-o", "yaml", "-f", "-")...)
+}
+
+// getLiveOneByOne fetches the live version of each resource separately, skipping those of unknown kinds.
+func (k *Deployer) getLiveOneByOne(ctx context.Context, manifests manifest.ManifestList) ([]byte, error) {
+	var live bytes.Buffer
+	for _, m := range manifests {
+		out, err := k.getLive(ctx, manifest.ManifestList{m})
+		if err != nil {
+			if isUnknownKindErr(err) {
+				continue
+			}
+			return nil, err
+		}
+		live.WriteString("---\n")
+		live.Write(out)
+	}
+	return live.Bytes(), nil
+}
+
+// isUnknownKindErr checks whether kubectl failed because the cluster doesn't know the kind of a resource.
+func isUnknownKindErr(err error) bool {
+	return strings.Contains(err.Error(), "no matches for kind") || strings.Contains(err.Error(), "the server doesn't have a resource type")
+}
+
+func existsIn(liveNamespaces []string, namespace string) bool {
+	for _, ns := range liveNamespaces {
+		if namespace == "" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const (
+	getLiveCommand = "kubectl --context kubecontext --namespace testNamespace get --ignore-not-found -o yaml -f -"
+
+	// liveDeploymentYAML is how kubectl lists the existing web Deployment.
+	liveDeploymentYAML = `apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+    namespace: testNamespace
+    resourceVersion: "42"`
+)
+
+func TestKustomizeDeployOnlyNewResources(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", deploymentYAML+"\n---\n"+serviceYAML).
+			AndRunInputOut(getLiveCommand, deploymentYAML+"\n---\n"+serviceYAML, liveDeploymentYAML).
+			AndRunInput(applyCommand, serviceYAML))
+		t.Override(&client.Client, deployutil.MockK8sClient)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:   []string{"."},
+			OnlyNewResources: true,
+		})
+		t.RequireNoError(err)
+
+		err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+		t.CheckNoError(err)
+	})
+}
+
+func TestKustomizeRenderOnlyNewResources(t *testing.T) {
+	tests := []struct {
+		description string
+		offline     bool
+		commands    util.Command
+		expected    string
+	}{
+		{
+			description: "existing resources are left out",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML+"\n---\n"+serviceYAML).
+				AndRunInputOut(getLiveCommand, deploymentYAML+"\n---\n"+serviceYAML, liveDeploymentYAML),
+			expected: serviceYAML + "\n",
+		},
+		{
+			description: "resources in another namespace are new",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML+"\n  namespace: other").
+				AndRunOut(getLiveCommand, liveDeploymentYAML),
+			expected: deploymentYAML + "\n  namespace: other\n",
+		},
+		{
+			description: "custom resources of a crd created by the deployment are new",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crdYAML+"\n---\n"+crYAML+"\n---\n"+deploymentYAML).
+				AndRunOutErr(getLiveCommand, "", errors.New(`error: unable to recognize "STDIN": no matches for kind "Foo" in version "example.com/v1"`)).
+				AndRunInputOut(getLiveCommand, crdYAML, "").
+				AndRunOutErr(getLiveCommand, "", errors.New(`error: unable to recognize "STDIN": no matches for kind "Foo" in version "example.com/v1"`)).
+				AndRunInputOut(getLiveCommand, deploymentYAML, deploymentYAML+"\n  namespace: testNamespace\n"),
+			expected: crdYAML + "\n---\n" + crYAML + "\n",
+		},
+		{
+			description: "skipped in offline mode",
+			offline:     true,
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML+"\n---\n"+serviceYAML),
+			expected: deploymentYAML + "\n---\n" + serviceYAML + "\n",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:   []string{"."},
+				OnlyNewResources: true,
+			})
+			t.RequireNoError(err)
+
+			var out bytes.Buffer
+			err = k.Render(context.Background(), &out, nil, test.offline, "")
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected, out.String())
+		})
+	}
+}
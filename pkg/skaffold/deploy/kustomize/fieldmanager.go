@@ -0,0 +1,49 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"unicode"
+)
+
+const (
+	fieldManagerFlag = "--field-manager"
+
+	// maxFieldManagerLength is the longest field manager name accepted by the API server.
+	maxFieldManagerLength = 128
+)
+
+// validateFieldManager checks the field manager name that attributes the applied changes.
+func validateFieldManager(name string, applyFlags []string) error {
+	if name == "" {
+		return nil
+	}
+
+	if len(name) > maxFieldManagerLength {
+		return fmt.Errorf("invalid fieldManager %q: must be at most %d characters", name, maxFieldManagerLength)
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("invalid fieldManager %q: must only contain printable characters", name)
+		}
+	}
+	if hasFlag(applyFlags, fieldManagerFlag) {
+		return fmt.Errorf("fieldManager can't be combined with a %s apply flag", fieldManagerFlag)
+	}
+	return nil
+}
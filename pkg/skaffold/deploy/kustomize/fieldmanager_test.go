@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeFieldManager(t *testing.T) {
+	tests := []struct {
+		description  string
+		fieldManager string
+		applyFlags   []string
+		commands     util.Command
+		shouldErr    bool
+	}{
+		{
+			description:  "field manager passed to apply",
+			fieldManager: "skaffold-ci",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRun("kubectl --context kubecontext --namespace testNamespace apply --field-manager=skaffold-ci -f -"),
+		},
+		{
+			description: "no field manager",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRun(applyCommand),
+		},
+		{
+			description:  "too long",
+			fieldManager: strings.Repeat("a", 129),
+			shouldErr:    true,
+		},
+		{
+			description:  "not printable",
+			fieldManager: "skaffold\nci",
+			shouldErr:    true,
+		},
+		{
+			description:  "conflicting apply flag",
+			fieldManager: "skaffold-ci",
+			applyFlags:   []string{"--field-manager=other"},
+			shouldErr:    true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				FieldManager:   test.fieldManager,
+				Flags:          latestV1.KubectlFlags{Apply: test.applyFlags},
+			})
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckNoError(err)
+		})
+	}
+}
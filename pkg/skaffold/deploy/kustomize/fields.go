@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// parseFieldPaths parses dot separated field paths, such as `spec.template.metadata.annotations.key`.
+// A dot that is part of a field name, like in most annotation keys, is escaped with a backslash.
+func parseFieldPaths(paths []string) ([][]string, error) {
+	var parsed [][]string
+	for _, path := range paths {
+		fields, err := parseFieldPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field path %q: %w", path, err)
+		}
+		parsed = append(parsed, fields)
+	}
+	return parsed, nil
+}
+
+func parseFieldPath(path string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	escaped := false
+	for _, c := range path {
+		switch {
+		case escaped:
+			field.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '.':
+			if field.Len() == 0 {
+				return nil, fmt.Errorf("empty field name")
+			}
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(c)
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if field.Len() == 0 {
+		return nil, fmt.Errorf("empty field name")
+	}
+	return append(fields, field.String()), nil
+}
+
+// removeFields strips the given fields from the rendered manifests.
+// Manifests that contain none of those fields are left untouched.
+func removeFields(manifests manifest.ManifestList, paths [][]string) (manifest.ManifestList, error) {
+	var updated manifest.ManifestList
+	for _, m := range manifests {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+
+		changed := false
+		for _, path := range paths {
+			if removeField(obj, path) {
+				changed = true
+			}
+		}
+		if !changed {
+			updated = append(updated, m)
+			continue
+		}
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, buf)
+	}
+	return updated, nil
+}
+
+// removeField removes a single field and reports whether it was found.
+func removeField(obj map[string]interface{}, path []string) bool {
+	for _, field := range path[:len(path)-1] {
+		child, ok := obj[field].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		obj = child
+	}
+
+	last := path[len(path)-1]
+	if _, found := obj[last]; !found {
+		return false
+	}
+	delete(obj, last)
+	return true
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestRemoveFields(t *testing.T) {
+	tests := []struct {
+		description string
+		paths       []string
+		manifests   manifest.ManifestList
+		expected    manifest.ManifestList
+	}{
+		{
+			description: "remove nested field",
+			paths:       []string{`spec.template.metadata.annotations.example\.com/build-id`, "metadata.labels.env"},
+			manifests: manifest.ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    app: app
+    env: dev
+  name: app
+spec:
+  template:
+    metadata:
+      annotations:
+        example.com/build-id: "1234"
+        example.com/owner: team`)},
+			expected: manifest.ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    app: app
+  name: app
+spec:
+  template:
+    metadata:
+      annotations:
+        example.com/owner: team
+`)},
+		},
+		{
+			description: "missing field leaves manifest untouched",
+			paths:       []string{"spec.template.metadata.annotations.key", "metadata.name.key"},
+			manifests:   manifest.ManifestList{[]byte(serviceYAML)},
+			expected:    manifest.ManifestList{[]byte(serviceYAML)},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			paths, err := parseFieldPaths(test.paths)
+			t.CheckNoError(err)
+
+			updated, err := removeFields(test.manifests, paths)
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected.String(), updated.String())
+		})
+	}
+}
+
+func TestParseFieldPaths(t *testing.T) {
+	tests := []struct {
+		description string
+		path        string
+		expected    [][]string
+		shouldErr   bool
+	}{
+		{
+			description: "single field",
+			path:        "status",
+			expected:    [][]string{{"status"}},
+		},
+		{
+			description: "escaped dot",
+			path:        `metadata.annotations.example\.com/key`,
+			expected:    [][]string{{"metadata", "annotations", "example.com/key"}},
+		},
+		{
+			description: "empty",
+			path:        "",
+			shouldErr:   true,
+		},
+		{
+			description: "leading dot",
+			path:        ".metadata",
+			shouldErr:   true,
+		},
+		{
+			description: "trailing dot",
+			path:        "metadata.",
+			shouldErr:   true,
+		},
+		{
+			description: "double dot",
+			path:        "metadata..name",
+			shouldErr:   true,
+		},
+		{
+			description: "trailing backslash",
+			path:        `metadata\`,
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			paths, err := parseFieldPaths([]string{test.path})
+
+			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected, paths)
+		})
+	}
+}
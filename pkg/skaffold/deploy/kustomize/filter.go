@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	apimachinery "k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// coreAPIGroup is how the core, unnamed, API group is referred to in the configuration.
+const coreAPIGroup = "core"
+
+// apiGroup returns the API group of a resource, `core` for the core group.
+func (r resource) apiGroup() string {
+	gv, err := apimachinery.ParseGroupVersion(r.APIVersion)
+	if err != nil || gv.Group == "" {
+		return coreAPIGroup
+	}
+	return gv.Group
+}
+
+// filterAPIGroups enforces the configured API group allowlist and denylist.
+// Resources from disallowed groups either fail the deploy or are skipped with a warning.
+func (k *Deployer) filterAPIGroups(manifests manifest.ManifestList) (manifest.ManifestList, error) {
+	if len(k.AllowedAPIGroups) == 0 && len(k.DeniedAPIGroups) == 0 {
+		return manifests, nil
+	}
+
+	allowed := util.NewStringSet()
+	allowed.Insert(k.AllowedAPIGroups...)
+	denied := util.NewStringSet()
+	denied.Insert(k.DeniedAPIGroups...)
+
+	var filtered manifest.ManifestList
+	var rejected []string
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+
+		group := r.apiGroup()
+		if denied.Contains(group) || (len(allowed) > 0 && !allowed.Contains(group)) {
+			rejected = append(rejected, fmt.Sprintf("%s (%s)", r, group))
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+
+	if len(rejected) == 0 {
+		return filtered, nil
+	}
+	if !k.SkipDeniedAPIGroups {
+		return nil, userErr(fmt.Errorf("resources from disallowed API groups: %s", strings.Join(rejected, ", ")))
+	}
+
+	warnings.Printf("skipping resources from disallowed API groups: %s", strings.Join(rejected, ", "))
+	return filtered, nil
+}
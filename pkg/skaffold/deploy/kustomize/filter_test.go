@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const (
+	roleYAML = `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader`
+	roleBindingYAML = `apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: read`
+	serviceYAML = `apiVersion: v1
+kind: Service
+metadata:
+  name: web`
+	deploymentYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web`
+)
+
+func TestFilterAPIGroups(t *testing.T) {
+	tests := []struct {
+		description string
+		kustomize   latestV1.KustomizeDeploy
+		expected    manifest.ManifestList
+		shouldErr   bool
+	}{
+		{
+			description: "no filter",
+			expected:    manifest.ManifestList{[]byte(roleYAML), []byte(serviceYAML), []byte(roleBindingYAML), []byte(deploymentYAML)},
+		},
+		{
+			description: "denied rbac fails",
+			kustomize:   latestV1.KustomizeDeploy{DeniedAPIGroups: []string{"rbac.authorization.k8s.io"}},
+			shouldErr:   true,
+		},
+		{
+			description: "denied rbac skipped",
+			kustomize: latestV1.KustomizeDeploy{
+				DeniedAPIGroups:     []string{"rbac.authorization.k8s.io"},
+				SkipDeniedAPIGroups: true,
+			},
+			expected: manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)},
+		},
+		{
+			description: "allowlist",
+			kustomize: latestV1.KustomizeDeploy{
+				AllowedAPIGroups:    []string{"core", "rbac.authorization.k8s.io"},
+				SkipDeniedAPIGroups: true,
+			},
+			expected: manifest.ManifestList{[]byte(roleYAML), []byte(serviceYAML), []byte(roleBindingYAML)},
+		},
+		{
+			description: "allowlist with everything allowed",
+			kustomize: latestV1.KustomizeDeploy{
+				AllowedAPIGroups: []string{"core", "apps", "rbac.authorization.k8s.io"},
+			},
+			expected: manifest.ManifestList{[]byte(roleYAML), []byte(serviceYAML), []byte(roleBindingYAML), []byte(deploymentYAML)},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			k := &Deployer{KustomizeDeploy: &test.kustomize}
+
+			filtered, err := k.filterAPIGroups(manifest.ManifestList{[]byte(roleYAML), []byte(serviceYAML), []byte(roleBindingYAML), []byte(deploymentYAML)})
+
+			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected, filtered)
+		})
+	}
+}
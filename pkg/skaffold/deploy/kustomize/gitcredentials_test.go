@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestGitCredentialsEnv(t *testing.T) {
+	tests := []struct {
+		description string
+		creds       func(tmpDir *testutil.TempDir) *latestV1.KustomizeGitCredentials
+		expected    func(tmpDir *testutil.TempDir) []string
+		shouldErr   bool
+	}{
+		{
+			description: "none",
+			creds:       func(*testutil.TempDir) *latestV1.KustomizeGitCredentials { return nil },
+			expected:    func(*testutil.TempDir) []string { return nil },
+		},
+		{
+			description: "ssh key and config",
+			creds: func(tmpDir *testutil.TempDir) *latestV1.KustomizeGitCredentials {
+				return &latestV1.KustomizeGitCredentials{SSHKey: tmpDir.Path("id_ed25519"), SSHConfig: tmpDir.Path("ssh_config")}
+			},
+			expected: func(tmpDir *testutil.TempDir) []string {
+				return []string{
+					"GIT_TERMINAL_PROMPT=0",
+					"GIT_SSH_COMMAND=ssh -i '" + tmpDir.Path("id_ed25519") + "' -o IdentitiesOnly=yes -F '" + tmpDir.Path("ssh_config") + "'",
+				}
+			},
+		},
+		{
+			description: "named credential helper",
+			creds: func(*testutil.TempDir) *latestV1.KustomizeGitCredentials {
+				return &latestV1.KustomizeGitCredentials{CredentialHelper: "store"}
+			},
+			expected: func(*testutil.TempDir) []string {
+				return []string{"GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=credential.helper", "GIT_CONFIG_VALUE_0=store"}
+			},
+		},
+		{
+			description: "credential helper path",
+			creds: func(tmpDir *testutil.TempDir) *latestV1.KustomizeGitCredentials {
+				return &latestV1.KustomizeGitCredentials{CredentialHelper: tmpDir.Path("helper")}
+			},
+			expected: func(tmpDir *testutil.TempDir) []string {
+				return []string{"GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=credential.helper", "GIT_CONFIG_VALUE_0=" + tmpDir.Path("helper")}
+			},
+		},
+		{
+			description: "missing ssh key",
+			creds: func(tmpDir *testutil.TempDir) *latestV1.KustomizeGitCredentials {
+				return &latestV1.KustomizeGitCredentials{SSHKey: tmpDir.Path("missing")}
+			},
+			shouldErr: true,
+		},
+		{
+			description: "ssh key is a directory",
+			creds: func(tmpDir *testutil.TempDir) *latestV1.KustomizeGitCredentials {
+				return &latestV1.KustomizeGitCredentials{SSHKey: tmpDir.Root()}
+			},
+			shouldErr: true,
+		},
+		{
+			description: "relative credential helper path",
+			creds: func(*testutil.TempDir) *latestV1.KustomizeGitCredentials {
+				return &latestV1.KustomizeGitCredentials{CredentialHelper: "bin/helper"}
+			},
+			shouldErr: true,
+		},
+		{
+			description: "multiline credential helper",
+			creds: func(*testutil.TempDir) *latestV1.KustomizeGitCredentials {
+				return &latestV1.KustomizeGitCredentials{CredentialHelper: "store\nhelper = evil"}
+			},
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir().
+				Write("id_ed25519", "private key").
+				Write("ssh_config", "Host *").
+				Write("helper", "#!/bin/sh")
+
+			env, err := gitCredentialsEnv(test.creds(tmpDir))
+
+			t.CheckError(test.shouldErr, err)
+			if !test.shouldErr {
+				t.CheckDeepEqual(test.expected(tmpDir), env)
+			}
+		})
+	}
+}
+
+func TestKustomizeGitCredentials(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir().
+			Write("id_rsa", "private key").
+			Chdir()
+		t.Override(&util.DefaultExecCommand, testutil.CmdRunOutEnv("kustomize build .", serviceYAML, []string{
+			"GIT_TERMINAL_PROMPT=0",
+			"GIT_SSH_COMMAND=ssh -i '" + tmpDir.Path("id_rsa") + "' -o IdentitiesOnly=yes",
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=credential.helper",
+			"GIT_CONFIG_VALUE_0=cache",
+		}))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+			GitCredentials: &latestV1.KustomizeGitCredentials{SSHKey: tmpDir.Path("id_rsa"), CredentialHelper: "cache"},
+		})
+		t.RequireNoError(err)
+
+		_, err = k.readManifests(context.Background(), ioutil.Discard)
+		t.CheckNoError(err)
+	})
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+const (
+	defaultContentHashAnnotation = "skaffold.dev/content-hash"
+	defaultFingerprintAnnotation = "skaffold.dev/fingerprint"
+)
+
+// workloadKinds are the kinds of the top-level workloads, annotated with the fingerprint of the deploy.
+var workloadKinds = map[string]bool{
+	"Deployment":            true,
+	"StatefulSet":           true,
+	"DaemonSet":             true,
+	"ReplicaSet":            true,
+	"ReplicationController": true,
+	"Job":                   true,
+	"CronJob":               true,
+	"Pod":                   true,
+}
+
+// annotateContentHash annotates each resource with a hash of its content. The hash is computed
+// on the resource without the annotation itself, so that annotating twice gives the same result.
+func annotateContentHash(manifests manifest.ManifestList, annotation string) (manifest.ManifestList, error) {
+	var annotated manifest.ManifestList
+
+	for _, m := range manifests {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+
+		metadata, ok := obj["metadata"].(map[string]interface{})
+		if !ok {
+			metadata = map[string]interface{}{}
+			obj["metadata"] = metadata
+		}
+		annotations, ok := metadata["annotations"].(map[string]interface{})
+		if ok {
+			delete(annotations, annotation)
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+
+		// Map keys are sorted when marshalled, so the hash doesn't depend on the order of the fields.
+		content, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+
+		if annotations == nil {
+			annotations = map[string]interface{}{}
+		}
+		annotations[annotation] = hex.EncodeToString(sum[:])
+		metadata["annotations"] = annotations
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		annotated = append(annotated, buf)
+	}
+
+	return annotated, nil
+}
+
+// annotateFingerprint annotates the top-level workloads with a fingerprint of the whole set of rendered
+// manifests, so that the resources in the cluster can be traced back to the deploy that created them.
+// Only the metadata of the workloads is annotated, not their pod templates, to not restart pods on every deploy.
+func annotateFingerprint(manifests manifest.ManifestList, annotation string) (manifest.ManifestList, error) {
+	var objs []map[string]interface{}
+
+	// The fingerprint is computed without the annotation itself, so that annotating twice gives the same result.
+	h := sha256.New()
+	for _, m := range manifests {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+				delete(annotations, annotation)
+				if len(annotations) == 0 {
+					delete(metadata, "annotations")
+				}
+			}
+		}
+
+		content, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(h, "%d\n", len(content))
+		h.Write(content)
+		objs = append(objs, obj)
+	}
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+
+	var annotated manifest.ManifestList
+	for i, obj := range objs {
+		kind, _ := obj["kind"].(string)
+		if !workloadKinds[kind] {
+			annotated = append(annotated, manifests[i])
+			continue
+		}
+
+		metadata, ok := obj["metadata"].(map[string]interface{})
+		if !ok {
+			metadata = map[string]interface{}{}
+			obj["metadata"] = metadata
+		}
+		annotations, ok := metadata["annotations"].(map[string]interface{})
+		if !ok {
+			annotations = map[string]interface{}{}
+			metadata["annotations"] = annotations
+		}
+		annotations[annotation] = fingerprint
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		annotated = append(annotated, buf)
+	}
+
+	return annotated, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestAnnotateContentHash(t *testing.T) {
+	const reorderedServiceYAML = `kind: Service
+metadata:
+  name: web
+apiVersion: v1`
+
+	hash := func(t *testutil.T, m string, annotation string) string {
+		annotated, err := annotateContentHash(manifest.ManifestList{[]byte(m)}, annotation)
+		t.RequireNoError(err)
+		r, err := parseResource(annotated[0])
+		t.RequireNoError(err)
+		return r.Metadata.Annotations[annotation]
+	}
+
+	testutil.Run(t, "stable across renders", func(t *testutil.T) {
+		first := hash(t, serviceYAML, defaultContentHashAnnotation)
+
+		t.CheckDeepEqual(64, len(first))
+		t.CheckDeepEqual(first, hash(t, serviceYAML, defaultContentHashAnnotation))
+		t.CheckDeepEqual(first, hash(t, reorderedServiceYAML, defaultContentHashAnnotation))
+	})
+
+	testutil.Run(t, "annotation is excluded from the hash", func(t *testutil.T) {
+		annotated, err := annotateContentHash(manifest.ManifestList{[]byte(serviceYAML)}, defaultContentHashAnnotation)
+		t.RequireNoError(err)
+
+		t.CheckDeepEqual(hash(t, serviceYAML, defaultContentHashAnnotation), hash(t, annotated.String(), defaultContentHashAnnotation))
+	})
+
+	testutil.Run(t, "content changes", func(t *testutil.T) {
+		t.CheckFalse(hash(t, serviceYAML, defaultContentHashAnnotation) == hash(t, deploymentYAML, defaultContentHashAnnotation))
+	})
+
+	testutil.Run(t, "custom annotation", func(t *testutil.T) {
+		t.CheckDeepEqual(hash(t, serviceYAML, defaultContentHashAnnotation), hash(t, serviceYAML, "example.com/hash"))
+	})
+}
+
+func TestAnnotateFingerprint(t *testing.T) {
+	fingerprint := func(t *testutil.T, manifests manifest.ManifestList, annotation string) (string, manifest.ManifestList) {
+		annotated, err := annotateFingerprint(manifests, annotation)
+		t.RequireNoError(err)
+		r, err := parseResource(annotated[0])
+		t.RequireNoError(err)
+		return r.Metadata.Annotations[annotation], annotated
+	}
+
+	testutil.Run(t, "only workloads are annotated", func(t *testutil.T) {
+		value, annotated := fingerprint(t, manifest.ManifestList{[]byte(deploymentYAML), []byte(serviceYAML)}, defaultFingerprintAnnotation)
+
+		t.CheckDeepEqual(64, len(value))
+		t.CheckDeepEqual(serviceYAML, string(annotated[1]))
+	})
+
+	testutil.Run(t, "stable across renders", func(t *testutil.T) {
+		first, annotated := fingerprint(t, manifest.ManifestList{[]byte(deploymentYAML), []byte(serviceYAML)}, defaultFingerprintAnnotation)
+		second, _ := fingerprint(t, annotated, defaultFingerprintAnnotation)
+
+		t.CheckDeepEqual(first, second)
+	})
+
+	testutil.Run(t, "changes with any resource", func(t *testutil.T) {
+		first, _ := fingerprint(t, manifest.ManifestList{[]byte(deploymentYAML), []byte(serviceYAML)}, defaultFingerprintAnnotation)
+		second, _ := fingerprint(t, manifest.ManifestList{[]byte(deploymentYAML), []byte(serviceYAML + "\nspec:\n  type: NodePort")}, defaultFingerprintAnnotation)
+
+		t.CheckFalse(first == second)
+	})
+
+	testutil.Run(t, "custom annotation", func(t *testutil.T) {
+		value, _ := fingerprint(t, manifest.ManifestList{[]byte(deploymentYAML)}, "example.com/deploy-id")
+
+		t.CheckDeepEqual(64, len(value))
+	})
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+const (
+	helmManagedByLabel        = "app.kubernetes.io/managed-by"
+	helmManagedByValue        = "Helm"
+	helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+	helmReleaseNsAnnotation   = "meta.helm.sh/release-namespace"
+
+	// helmOwnershipPatch removes the Helm ownership metadata from a live resource.
+	// It fails, leaving the resource untouched, if the resource is not managed by Helm.
+	helmOwnershipPatch = `[{"op":"test","path":"/metadata/labels/app.kubernetes.io~1managed-by","value":"Helm"},` +
+		`{"op":"remove","path":"/metadata/labels/app.kubernetes.io~1managed-by"},` +
+		`{"op":"remove","path":"/metadata/annotations/meta.helm.sh~1release-name"},` +
+		`{"op":"remove","path":"/metadata/annotations/meta.helm.sh~1release-namespace"}]`
+)
+
+// adoptHelmResources removes the Helm ownership metadata from the rendered manifests
+// and from the matching live resources, so that they can be managed by Skaffold instead of Helm.
+// Live resources are fetched all at once, and only those still managed by Helm are patched,
+// so that resources adopted by a previous deployment cost no extra request.
+func (k *Deployer) adoptHelmResources(ctx context.Context, manifests manifest.ManifestList) (manifest.ManifestList, error) {
+	var adopted manifest.ManifestList
+	for _, m := range manifests {
+		stripped, err := stripHelmMetadata(m)
+		if err != nil {
+			return nil, err
+		}
+		adopted = append(adopted, stripped)
+	}
+
+	liveObjects, err := k.liveObjects(ctx, adopted)
+	if err != nil {
+		return nil, err
+	}
+	var helmManaged []map[string]interface{}
+	for _, obj := range liveObjects {
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			if labels, ok := metadata["labels"].(map[string]interface{}); ok && labels[helmManagedByLabel] == helmManagedByValue {
+				helmManaged = append(helmManaged, obj)
+			}
+		}
+	}
+	if len(helmManaged) == 0 {
+		return adopted, nil
+	}
+	namespaces := liveNamespaces(helmManaged)
+
+	for _, m := range adopted {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+		if !existsIn(namespaces[r.kubectlID()], r.Metadata.Namespace) {
+			continue
+		}
+
+		name := r.Kind + "/" + r.Metadata.Name
+		if err := k.kubectl.RunInNamespace(ctx, nil, ioutil.Discard, "patch", r.Metadata.Namespace, name, "--type=json", "--patch="+helmOwnershipPatch); err != nil {
+			logrus.Debugf("not removing Helm ownership of %s: %v", r, err)
+			continue
+		}
+		logrus.Infof("removed Helm ownership of %s", r)
+	}
+
+	return adopted, nil
+}
+
+// stripHelmMetadata removes the Helm ownership label and annotations from a single manifest.
+// Manifests without Helm metadata are returned unchanged.
+func stripHelmMetadata(m []byte) ([]byte, error) {
+	obj := make(map[string]interface{})
+	if err := yaml.Unmarshal(m, &obj); err != nil {
+		return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+	}
+
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return m, nil
+	}
+
+	changed := false
+	if labels, ok := metadata["labels"].(map[string]interface{}); ok && labels[helmManagedByLabel] == helmManagedByValue {
+		delete(labels, helmManagedByLabel)
+		changed = true
+	}
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		for _, key := range []string{helmReleaseNameAnnotation, helmReleaseNsAnnotation} {
+			if _, found := annotations[key]; found {
+				delete(annotations, key)
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return m, nil
+	}
+	return yaml.Marshal(obj)
+}
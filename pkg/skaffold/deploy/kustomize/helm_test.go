@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const (
+	helmServiceYAML = `apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    meta.helm.sh/release-name: web
+    meta.helm.sh/release-namespace: default
+    owner: team-a
+  labels:
+    app: web
+    app.kubernetes.io/managed-by: Helm
+  name: web
+`
+	adoptedServiceYAML = `apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    owner: team-a
+  labels:
+    app: web
+  name: web
+`
+)
+
+func TestStripHelmMetadata(t *testing.T) {
+	tests := []struct {
+		description string
+		manifest    string
+		expected    string
+	}{
+		{
+			description: "helm metadata",
+			manifest:    helmServiceYAML,
+			expected:    adoptedServiceYAML,
+		},
+		{
+			description: "no helm metadata",
+			manifest:    adoptedServiceYAML,
+			expected:    adoptedServiceYAML,
+		},
+		{
+			description: "managed by another tool",
+			manifest: `apiVersion: v1
+kind: Service
+metadata:
+  labels:
+    app.kubernetes.io/managed-by: kpt
+  name: web
+`,
+			expected: `apiVersion: v1
+kind: Service
+metadata:
+  labels:
+    app.kubernetes.io/managed-by: kpt
+  name: web
+`,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			stripped, err := stripHelmMetadata([]byte(test.manifest))
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected, string(stripped))
+		})
+	}
+}
+
+func TestKustomizeAdoptHelmResources(t *testing.T) {
+	patch := "kubectl --context kubecontext --namespace testNamespace patch Service/web --type=json --patch=" + helmOwnershipPatch
+
+	tests := []struct {
+		description string
+		commands    util.Command
+	}{
+		{
+			description: "live resource managed by helm",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", helmServiceYAML).
+				AndRunInputOut(getLiveCommand, strings.TrimSpace(adoptedServiceYAML), helmServiceYAML).
+				AndRun(patch).
+				AndRunInput(applyCommand, strings.TrimSpace(adoptedServiceYAML)),
+		},
+		{
+			description: "patch fails",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", helmServiceYAML).
+				AndRunInputOut(getLiveCommand, strings.TrimSpace(adoptedServiceYAML), helmServiceYAML).
+				AndRunErr(patch, errors.New("conflict")).
+				AndRunInput(applyCommand, strings.TrimSpace(adoptedServiceYAML)),
+		},
+		{
+			description: "live resource already adopted",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", helmServiceYAML).
+				AndRunInputOut(getLiveCommand, strings.TrimSpace(adoptedServiceYAML), adoptedServiceYAML).
+				AndRunInput(applyCommand, strings.TrimSpace(adoptedServiceYAML)),
+		},
+		{
+			description: "live resource not found",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", helmServiceYAML).
+				AndRunInputOut(getLiveCommand, strings.TrimSpace(adoptedServiceYAML), "").
+				AndRunInput(applyCommand, strings.TrimSpace(adoptedServiceYAML)),
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:     []string{"."},
+				AdoptHelmResources: true,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckNoError(err)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hermeticEnv is added to the environment of hermetic kustomize builds. Git is only allowed to clone local
+// repositories and HTTP requests go through a proxy that can't be reached, so that remote references fail
+// even when they are not detected in the kustomizations.
+var hermeticEnv = []string{
+	"GIT_ALLOW_PROTOCOL=file",
+	"GIT_TERMINAL_PROMPT=0",
+	"HTTP_PROXY=http://127.0.0.1:0",
+	"HTTPS_PROXY=http://127.0.0.1:0",
+	"http_proxy=http://127.0.0.1:0",
+	"https_proxy=http://127.0.0.1:0",
+	"NO_PROXY=",
+	"no_proxy=",
+}
+
+// validateHermetic checks that the configured kustomize paths are all local.
+func validateHermetic(kustomizePaths []string) error {
+	for _, path := range kustomizePaths {
+		if isOCIKustomization(path) || isRemoteBase(localKustomizePath(path)) {
+			return fmt.Errorf("hermetic builds can't use the remote kustomize path %q", path)
+		}
+	}
+	return nil
+}
+
+// checkHermetic fails if the kustomization, or any of its local bases, references a remote base or resource.
+func (k *Deployer) checkHermetic(kustomizePath string) error {
+	var remotes []string
+	opts := k.dependencyOptions()
+	opts.remoteBase = func(target string) { remotes = append(remotes, target) }
+
+	if _, err := dependenciesForKustomization(kustomizePath, opts, 0); err != nil {
+		return err
+	}
+	if len(remotes) > 0 {
+		return fmt.Errorf("hermetic build of %s references remote bases or resources: %s", kustomizePath, strings.Join(remotes, ", "))
+	}
+	return nil
+}
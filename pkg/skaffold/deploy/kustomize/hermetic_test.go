@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeHermetic(t *testing.T) {
+	tests := []struct {
+		description    string
+		files          map[string]string
+		kustomizePaths []string
+		commands       util.Command
+		expectedErr    string
+	}{
+		{
+			description: "local kustomization",
+			files: map[string]string{
+				"kustomization.yaml":      "resources: [base]",
+				"base/kustomization.yaml": "resources: [service.yaml]",
+				"base/service.yaml":       serviceYAML,
+			},
+			kustomizePaths: []string{"."},
+			commands:       testutil.CmdRunOutEnv("kustomize build .", serviceYAML, hermeticEnv),
+		},
+		{
+			description: "remote base",
+			files: map[string]string{
+				"kustomization.yaml": "resources:\n- github.com/org/repo//base?ref=v1",
+			},
+			kustomizePaths: []string{"."},
+			expectedErr:    "hermetic build of . references remote bases or resources: github.com/org/repo//base?ref=v1",
+		},
+		{
+			description: "remote resource in a local base",
+			files: map[string]string{
+				"kustomization.yaml":      "resources: [base]",
+				"base/kustomization.yaml": "resources:\n- https://example.com/service.yaml",
+			},
+			kustomizePaths: []string{"."},
+			expectedErr:    "references remote bases or resources: https://example.com/service.yaml",
+		},
+		{
+			description:    "remote kustomize path",
+			kustomizePaths: []string{"git@github.com:org/repo.git//overlays/dev"},
+			expectedErr:    `hermetic builds can't use the remote kustomize path "git@github.com:org/repo.git//overlays/dev"`,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir()
+			for path, content := range test.files {
+				tmpDir.Write(path, content)
+			}
+			tmpDir.Chdir()
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: test.kustomizePaths,
+				Hermetic:       true,
+			})
+			if err == nil {
+				_, err = k.readManifests(context.Background(), ioutil.Discard)
+			}
+
+			if test.expectedErr != "" {
+				t.CheckErrorContains(test.expectedErr, err)
+			} else {
+				t.CheckNoError(err)
+			}
+		})
+	}
+}
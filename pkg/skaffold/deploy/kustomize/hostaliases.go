@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// validateHostAliases checks the host aliases added to the workloads.
+func validateHostAliases(aliases []latestV1.KustomizeHostAlias) error {
+	for _, a := range aliases {
+		if net.ParseIP(a.IP) == nil {
+			return fmt.Errorf("invalid hostAliases: %q is not an IP address", a.IP)
+		}
+		if len(a.Hostnames) == 0 {
+			return fmt.Errorf("invalid hostAliases %q: at least one hostname is required", a.IP)
+		}
+		for _, hostname := range a.Hostnames {
+			if hostname == "" {
+				return fmt.Errorf("invalid hostAliases %q: hostnames can't be empty", a.IP)
+			}
+		}
+	}
+	return nil
+}
+
+// addHostAliases adds the host aliases to the pod templates of the workloads.
+// Manifests without a pod template are left untouched.
+func addHostAliases(manifests manifest.ManifestList, aliases []latestV1.KustomizeHostAlias) (manifest.ManifestList, error) {
+	var updated manifest.ManifestList
+	for _, m := range manifests {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+
+		spec := podSpec(obj)
+		if spec == nil {
+			updated = append(updated, m)
+			continue
+		}
+		mergeHostAliases(spec, aliases)
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, buf)
+	}
+	return updated, nil
+}
+
+// mergeHostAliases adds the hostnames that are missing from the entry with the same IP,
+// or a new entry when the pod spec has none for that IP.
+func mergeHostAliases(spec map[string]interface{}, aliases []latestV1.KustomizeHostAlias) {
+	existing, _ := spec["hostAliases"].([]interface{})
+	for _, a := range aliases {
+		var entry map[string]interface{}
+		for _, e := range existing {
+			if e, ok := e.(map[string]interface{}); ok && e["ip"] == a.IP {
+				entry = e
+				break
+			}
+		}
+		if entry == nil {
+			entry = map[string]interface{}{"ip": a.IP}
+			existing = append(existing, entry)
+		}
+
+		hostnames, _ := entry["hostnames"].([]interface{})
+		for _, hostname := range a.Hostnames {
+			if !containsValue(hostnames, hostname) {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+		entry["hostnames"] = hostnames
+	}
+	spec["hostAliases"] = existing
+}
+
+func containsValue(values []interface{}, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestAddHostAliases(t *testing.T) {
+	aliases := []latestV1.KustomizeHostAlias{
+		{IP: "10.0.0.2", Hostnames: []string{"db.local", "cache.local"}},
+		{IP: "10.0.0.3", Hostnames: []string{"auth.local"}},
+	}
+
+	tests := []struct {
+		description string
+		manifests   manifest.ManifestList
+		expected    manifest.ManifestList
+	}{
+		{
+			description: "added to pod templates",
+			manifests: manifest.ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web`), []byte(serviceYAML)},
+			expected: manifest.ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+      hostAliases:
+      - hostnames:
+        - db.local
+        - cache.local
+        ip: 10.0.0.2
+      - hostnames:
+        - auth.local
+        ip: 10.0.0.3
+`), []byte(serviceYAML)},
+		},
+		{
+			description: "merged with existing entries",
+			manifests: manifest.ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  hostAliases:
+  - hostnames:
+    - db.local
+    - db.internal
+    ip: 10.0.0.2
+  - hostnames:
+    - mail.local
+    ip: 10.0.0.9`)},
+			expected: manifest.ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  hostAliases:
+  - hostnames:
+    - db.local
+    - db.internal
+    - cache.local
+    ip: 10.0.0.2
+  - hostnames:
+    - mail.local
+    ip: 10.0.0.9
+  - hostnames:
+    - auth.local
+    ip: 10.0.0.3
+`)},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			updated, err := addHostAliases(test.manifests, aliases)
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected.String(), updated.String())
+		})
+	}
+}
+
+func TestValidateHostAliases(t *testing.T) {
+	testutil.CheckError(t, false, validateHostAliases([]latestV1.KustomizeHostAlias{{IP: "fe80::1", Hostnames: []string{"web.local"}}}))
+	testutil.CheckError(t, true, validateHostAliases([]latestV1.KustomizeHostAlias{{IP: "localhost", Hostnames: []string{"web.local"}}}))
+	testutil.CheckError(t, true, validateHostAliases([]latestV1.KustomizeHostAlias{{IP: "10.0.0.2"}}))
+	testutil.CheckError(t, true, validateHostAliases([]latestV1.KustomizeHostAlias{{IP: "10.0.0.2", Hostnames: []string{""}}}))
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/graph"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+// RenderedImages returns the sorted, unique image references used by the rendered manifests, once the images
+// of the given builds are replaced. It's meant for pipelines that scan or promote the images of a release.
+func (k *Deployer) RenderedImages(ctx context.Context, builds []graph.Artifact) ([]string, error) {
+	manifests, err := k.renderManifests(ctx, ioutil.Discard, builds)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := manifests.GetImages()
+	if err != nil {
+		return nil, err
+	}
+
+	unique := util.NewStringSet()
+	for _, image := range images {
+		unique.Insert(image.Tag)
+	}
+	return unique.ToList(), nil
+}
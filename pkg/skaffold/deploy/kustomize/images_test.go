@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/graph"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeRenderedImages(t *testing.T) {
+	const rendered = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: migrate
+        image: app
+      containers:
+      - name: web
+        image: app
+      - name: proxy
+        image: nginx:1.21
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+  - name: debug
+    image: busybox
+  - name: proxy
+    image: nginx:1.21
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web`
+
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", rendered))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+		})
+		t.RequireNoError(err)
+
+		images, err := k.RenderedImages(context.Background(), []graph.Artifact{{ImageName: "app", Tag: "gcr.io/project/app:abc123"}})
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual([]string{"busybox", "gcr.io/project/app:abc123", "nginx:1.21"}, images)
+	})
+}
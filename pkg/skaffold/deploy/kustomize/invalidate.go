@@ -0,0 +1,47 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// InvalidateCaches drops what the Deployer remembers from previous renders and deploys: the images
+// resolved by the image resolver, the images found in the manifests, the commits of the remote bases
+// and the manifests applied last, so that the next render starts from scratch and the next deploy
+// applies all the manifests. It's meant for long-running embedders, for example to reload the
+// configuration on SIGHUP, and can be called from any goroutine: the caches are dropped by the next
+// render, deploy or dependency check. The build cache is keyed by content and needs no invalidation.
+func (k *Deployer) InvalidateCaches() {
+	atomic.StoreInt32(&k.cachesInvalidated, 1)
+}
+
+// dropInvalidatedCaches drops the caches if InvalidateCaches was called since they were last dropped.
+func (k *Deployer) dropInvalidatedCaches() {
+	if !atomic.CompareAndSwapInt32(&k.cachesInvalidated, 1, 0) {
+		return
+	}
+
+	logrus.Debugln("dropping the kustomize caches")
+	k.resolvedImages = map[string]string{}
+	k.originalImages = nil
+	k.remoteBasesChecked = time.Time{}
+	k.kubectl.RememberApplied(nil)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/graph"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeInvalidateCaches(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		// The second deploy has nothing new to apply, the third one applies everything again.
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", serviceYAML).
+			AndRun(applyCommand).
+			AndRunOut("kustomize build .", serviceYAML).
+			AndRunOut("kustomize build .", serviceYAML).
+			AndRun(applyCommand))
+		t.Override(&client.Client, deployutil.MockK8sClient)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+		})
+		t.RequireNoError(err)
+
+		t.CheckNoError(k.Deploy(context.Background(), ioutil.Discard, nil))
+		t.CheckNoError(k.Deploy(context.Background(), ioutil.Discard, nil))
+
+		k.resolvedImages["nginx"] = "nginx:1.21"
+		k.originalImages = []graph.Artifact{{ImageName: "nginx", Tag: "nginx"}}
+		k.remoteBasesChecked = time.Now()
+		k.InvalidateCaches()
+
+		// Nothing is dropped until the next render.
+		t.CheckDeepEqual(map[string]string{"nginx": "nginx:1.21"}, k.resolvedImages)
+
+		t.CheckNoError(k.Deploy(context.Background(), ioutil.Discard, nil))
+		t.CheckDeepEqual(map[string]string{}, k.resolvedImages)
+		t.CheckDeepEqual(time.Time{}, k.remoteBasesChecked)
+	})
+}
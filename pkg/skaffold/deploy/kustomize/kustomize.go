@@ -17,12 +17,16 @@ limitations under the License.
 package kustomize
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/segmentio/textio"
 	"github.com/sirupsen/logrus"
@@ -51,6 +55,18 @@ import (
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
 )
 
+const (
+	kustomizeLogNone  = "none"
+	kustomizeLogDebug = "debug"
+	kustomizeLogInfo  = "info"
+)
+
+const (
+	cascadeBackground = "background"
+	cascadeForeground = "foreground"
+	cascadeOrphan     = "orphan"
+)
+
 var (
 	DefaultKustomizePath = "."
 	KustomizeFilePaths   = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
@@ -60,20 +76,43 @@ var (
 
 // kustomization is the content of a kustomization.yaml file.
 type kustomization struct {
-	Components            []string              `yaml:"components"`
-	Bases                 []string              `yaml:"bases"`
-	Resources             []string              `yaml:"resources"`
-	Patches               []patchWrapper        `yaml:"patches"`
-	PatchesStrategicMerge []strategicMergePatch `yaml:"patchesStrategicMerge"`
-	CRDs                  []string              `yaml:"crds"`
-	PatchesJSON6902       []patchJSON6902       `yaml:"patchesJson6902"`
-	ConfigMapGenerator    []configMapGenerator  `yaml:"configMapGenerator"`
-	SecretGenerator       []secretGenerator     `yaml:"secretGenerator"`
+	Components            []string              `yaml:"components,omitempty"`
+	Bases                 []string              `yaml:"bases,omitempty"`
+	Resources             []string              `yaml:"resources,omitempty"`
+	Patches               []patchWrapper        `yaml:"patches,omitempty"`
+	PatchesStrategicMerge []strategicMergePatch `yaml:"patchesStrategicMerge,omitempty"`
+	CRDs                  []string              `yaml:"crds,omitempty"`
+	PatchesJSON6902       []patchJSON6902       `yaml:"patchesJson6902,omitempty"`
+	ConfigMapGenerator    []configMapGenerator  `yaml:"configMapGenerator,omitempty"`
+	SecretGenerator       []secretGenerator     `yaml:"secretGenerator,omitempty"`
+	Namespace             string                `yaml:"namespace,omitempty"`
+	NamePrefix            string                `yaml:"namePrefix,omitempty"`
+	NameSuffix            string                `yaml:"nameSuffix,omitempty"`
+	CommonLabels          map[string]string     `yaml:"commonLabels,omitempty"`
+	CommonAnnotations     map[string]string     `yaml:"commonAnnotations,omitempty"`
+	Images                []kustomizeImage      `yaml:"images,omitempty"`
+	SortOptions           *sortOptions          `yaml:"sortOptions,omitempty"`
+	Generators            []string              `yaml:"generators,omitempty"`
+	Transformers          []string              `yaml:"transformers,omitempty"`
+	Validators            []string              `yaml:"validators,omitempty"`
+	Metadata              kustomizationMetadata `yaml:"metadata,omitempty"`
+}
+
+type kustomizationMetadata struct {
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type kustomizeImage struct {
+	Name    string `yaml:"name,omitempty"`
+	NewName string `yaml:"newName,omitempty"`
+	NewTag  string `yaml:"newTag,omitempty"`
+	Digest  string `yaml:"digest,omitempty"`
 }
 
 type patchPath struct {
-	Path  string `yaml:"path"`
-	Patch string `yaml:"patch"`
+	Path   string       `yaml:"path,omitempty"`
+	Patch  string       `yaml:"patch,omitempty"`
+	Target *patchTarget `yaml:"target,omitempty"`
 }
 
 type patchWrapper struct {
@@ -86,19 +125,47 @@ type strategicMergePatch struct {
 }
 
 type patchJSON6902 struct {
-	Path string `yaml:"path"`
+	Target *patchTarget `yaml:"target,omitempty"`
+	Path   string       `yaml:"path,omitempty"`
+	Patch  string       `yaml:"patch,omitempty"`
+}
+
+type patchTarget struct {
+	Group              string `yaml:"group,omitempty"`
+	Version            string `yaml:"version,omitempty"`
+	Kind               string `yaml:"kind,omitempty"`
+	Name               string `yaml:"name,omitempty"`
+	Namespace          string `yaml:"namespace,omitempty"`
+	LabelSelector      string `yaml:"labelSelector,omitempty"`
+	AnnotationSelector string `yaml:"annotationSelector,omitempty"`
+}
+
+// sortOptions controls the order of the resources output by kustomize 4.5+.
+// Skaffold applies the resources in that order, unless told to reorder them.
+type sortOptions struct {
+	Order             string             `yaml:"order,omitempty"`
+	LegacySortOptions *legacySortOptions `yaml:"legacySortOptions,omitempty"`
+}
+
+type legacySortOptions struct {
+	OrderFirst []string `yaml:"orderFirst,omitempty"`
+	OrderLast  []string `yaml:"orderLast,omitempty"`
 }
 
 type configMapGenerator struct {
-	Files []string `yaml:"files"`
-	Env   string   `yaml:"env"`
-	Envs  []string `yaml:"envs"`
+	Name     string   `yaml:"name,omitempty"`
+	Behavior string   `yaml:"behavior,omitempty"`
+	Files    []string `yaml:"files,omitempty"`
+	Env      string   `yaml:"env,omitempty"`
+	Envs     []string `yaml:"envs,omitempty"`
 }
 
 type secretGenerator struct {
-	Files []string `yaml:"files"`
-	Env   string   `yaml:"env"`
-	Envs  []string `yaml:"envs"`
+	Name     string   `yaml:"name,omitempty"`
+	Behavior string   `yaml:"behavior,omitempty"`
+	Files    []string `yaml:"files,omitempty"`
+	Env      string   `yaml:"env,omitempty"`
+	Envs     []string `yaml:"envs,omitempty"`
 }
 
 // Deployer deploys workflows using kustomize CLI.
@@ -123,9 +190,40 @@ type Deployer struct {
 	useKubectlKustomize bool
 
 	namespaces *[]string
+
+	remoteBasesFile    string
+	remoteBasesChecked time.Time
+
+	artifactPaths   []string // the kustomize paths derived from the artifacts
+	environmentPath string   // the overlay of the selected environment
+
+	resolvedImages    map[string]string // the images resolved by the image resolver
+	pluginHome        string            // the absolute path of the kustomize plugin home
+	gitEnv            []string          // the environment variables that set the git credentials
+	removedFields     [][]string        // the parsed paths of the fields removed from rendered manifests
+	buildCacheMaxSize int64             // the maximum size of the build cache, in bytes
+	secretResolver    SecretResolver    // resolves the secretRef:// placeholders of rendered Secrets
+	timings           phaseTimings      // the duration of the phases of the last deploy
+	cachesInvalidated int32             // set by InvalidateCaches, accessed atomically
+	manifestSources   map[string]string // the kustomize path each rendered resource comes from
+
+	buildArgsHook func(args []string) []string // customizes the arguments of kustomize builds
 }
 
-func NewDeployer(cfg kubectl.Config, labeller *label.DefaultLabeller, d *latestV1.KustomizeDeploy) (*Deployer, error) {
+// DeployerOption customizes a kustomize Deployer.
+type DeployerOption func(k *Deployer)
+
+// WithBuildArgsHook registers a function that receives the arguments of each `kustomize build`
+// (or `kubectl kustomize`) command, kustomization path included, and returns the arguments to use instead.
+// The hook runs on every build and its result is not validated: it must not drop the kustomization path,
+// and must keep the output on stdout, as Skaffold reads the rendered manifests from there.
+func WithBuildArgsHook(hook func(args []string) []string) DeployerOption {
+	return func(k *Deployer) {
+		k.buildArgsHook = hook
+	}
+}
+
+func NewDeployer(cfg kubectl.Config, labeller *label.DefaultLabeller, d *latestV1.KustomizeDeploy, opts ...DeployerOption) (*Deployer, error) {
 	defaultNamespace := ""
 	if d.DefaultNamespace != nil {
 		var err error
@@ -135,7 +233,247 @@ func NewDeployer(cfg kubectl.Config, labeller *label.DefaultLabeller, d *latestV
 		}
 	}
 
+	if d.CRDWaitTimeout != "" {
+		if _, err := time.ParseDuration(d.CRDWaitTimeout); err != nil {
+			return nil, userErr(fmt.Errorf("invalid crdWaitTimeout %q: %w", d.CRDWaitTimeout, err))
+		}
+	}
+
+	switch d.KustomizeLogLevel {
+	case "", kustomizeLogNone, kustomizeLogDebug, kustomizeLogInfo:
+	default:
+		return nil, userErr(fmt.Errorf("invalid kustomizeLogLevel %q: must be one of %q, %q or %q", d.KustomizeLogLevel, kustomizeLogNone, kustomizeLogDebug, kustomizeLogInfo))
+	}
+
+	if err := validateRenderOptions(d.RenderSeparator, d.RenderLineEndings); err != nil {
+		return nil, userErr(err)
+	}
+
+	if d.BinaryLookupRetries != nil && *d.BinaryLookupRetries < 0 {
+		return nil, userErr(fmt.Errorf("invalid binaryLookupRetries %d: must not be negative", *d.BinaryLookupRetries))
+	}
+
+	if d.ApplyRetries < 0 {
+		return nil, userErr(fmt.Errorf("invalid applyRetries %d: must not be negative", d.ApplyRetries))
+	}
+
+	if d.WebhookTimeoutRetries < 0 {
+		return nil, userErr(fmt.Errorf("invalid webhookTimeoutRetries %d: must not be negative", d.WebhookTimeoutRetries))
+	}
+
+	if d.RemoteBasesPollInterval != "" {
+		if _, err := time.ParseDuration(d.RemoteBasesPollInterval); err != nil {
+			return nil, userErr(fmt.Errorf("invalid remoteBasesPollInterval %q: %w", d.RemoteBasesPollInterval, err))
+		}
+	}
+
+	if d.ApplyConcurrency < 0 {
+		return nil, userErr(fmt.Errorf("invalid applyConcurrency %d: must not be negative", d.ApplyConcurrency))
+	}
+
+	if d.ApplyByKind && d.ApplyByDependencies {
+		return nil, userErr(fmt.Errorf("applyByKind can't be combined with applyByDependencies"))
+	}
+	if d.ApplyByKind && d.ApplyConcurrency > 1 {
+		return nil, userErr(fmt.Errorf("applyByKind can't be combined with applyConcurrency %d", d.ApplyConcurrency))
+	}
+
+	switch d.CleanupCascade {
+	case "", cascadeBackground, cascadeForeground, cascadeOrphan:
+	default:
+		return nil, userErr(fmt.Errorf("invalid cleanupCascade %q: must be one of %q, %q or %q", d.CleanupCascade, cascadeBackground, cascadeForeground, cascadeOrphan))
+	}
+
+	if err := validateDepfileFormat(d.DepfileFormat); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateRolloutStatus(d.RolloutStatus); err != nil {
+		return nil, userErr(err)
+	}
+
+	if d.SkipExistingCRDWait && !d.WaitForCRDs {
+		return nil, userErr(fmt.Errorf("skipExistingCRDWait requires waitForCRDs"))
+	}
+
+	buildCacheMaxSize, err := parseBuildCacheMaxSize(d.BuildCacheDir, d.BuildCacheMaxSize)
+	if err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateCommandPrefix(d.ApplyCommandPrefix); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateRenderSplit(d.RenderSplitDir, d.RenderIndexFile); err != nil {
+		return nil, userErr(err)
+	}
+	if d.RenderSplitDir != "" && d.RenderComponentDir != "" {
+		return nil, userErr(fmt.Errorf("renderSplitDir and renderComponentDir can't be used together"))
+	}
+
+	if err := validateScheduling(d.Scheduling); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateMultiDocumentPatches(d.MultiDocumentPatches); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validatePluginAllowlist(d.PluginAllowlist); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateDuplicateKeys(d.DuplicateKeys); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateWaits(d.WaitFor); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateReadiness(d.ReadinessCommand, d.ReadinessTimeout); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validatePropagationPolicies(d.PropagationPolicies); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateSecurityContext(d.SecurityContext); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateHostAliases(d.HostAliases); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateCommandOverrides(d.CommandOverrides); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateReplaceAnnotation(d.ReplaceAnnotation); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateTerminatingNamespaces(d.TerminatingNamespaces, d.TerminatingNamespacesTimeout); err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateCommonAnnotations(d.CommonAnnotations); err != nil {
+		return nil, userErr(err)
+	}
+
+	if d.Replicas != nil && *d.Replicas < 0 {
+		return nil, userErr(fmt.Errorf("invalid replicas %d: must not be negative", *d.Replicas))
+	}
+	if d.SkipAutoscaledReplicas && d.Replicas == nil {
+		return nil, userErr(fmt.Errorf("skipAutoscaledReplicas requires replicas"))
+	}
+
+	if err := validateAPIVersionOverrides(d.APIVersionOverrides); err != nil {
+		return nil, userErr(err)
+	}
+
+	if d.ClusterCheckTimeout != "" {
+		if timeout, err := time.ParseDuration(d.ClusterCheckTimeout); err != nil || timeout <= 0 {
+			return nil, userErr(fmt.Errorf("invalid clusterCheckTimeout %q: must be a positive duration", d.ClusterCheckTimeout))
+		}
+	}
+
+	if err := validateResourceCounts(d.MinResources, d.MinResourcesPerKind, d.MaxResources); err != nil {
+		return nil, userErr(err)
+	}
+
+	if d.MaxDependencyDepth < 0 {
+		return nil, userErr(fmt.Errorf("invalid maxDependencyDepth %d: must not be negative", d.MaxDependencyDepth))
+	}
+
+	labels, err := filterLabels(labeller.Labels(), d.LabelAllowlist)
+	if err != nil {
+		return nil, userErr(err)
+	}
+
+	if err := validateLongLabelValues(d.LongLabelValues); err != nil {
+		return nil, userErr(err)
+	}
+	labels = shortenLabelValues(labels, d.LongLabelValues)
+	if runID, found := labels[label.RunIDLabel]; d.Prune && found && runID != labeller.Labels()[label.RunIDLabel] {
+		return nil, userErr(fmt.Errorf("prune can't select the resources of the current run when the run id label is shortened"))
+	}
+
+	if err := validatePrune(d); err != nil {
+		return nil, userErr(err)
+	}
+	if _, found := labels[label.RunIDLabel]; d.Prune && !found {
+		return nil, userErr(fmt.Errorf("prune requires the skaffold labels to select the resources of the current run"))
+	}
+
+	removedFields, err := parseFieldPaths(d.RemoveFields)
+	if err != nil {
+		return nil, userErr(err)
+	}
+
+	var pluginHome string
+	if d.PluginHome != "" {
+		if err := validatePluginHome(d.PluginHome); err != nil {
+			return nil, userErr(err)
+		}
+		if pluginHome, err = filepath.Abs(d.PluginHome); err != nil {
+			return nil, userErr(err)
+		}
+	}
+
+	gitEnv, err := gitCredentialsEnv(d.GitCredentials)
+	if err != nil {
+		return nil, userErr(err)
+	}
+
+	environmentPath, err := selectEnvironment(d.Environments, d.Environment)
+	if err != nil {
+		return nil, userErr(err)
+	}
+
+	artifactPaths, err := artifactKustomizePaths(d.ArtifactPathTemplate, cfg.GetPipelines())
+	if err != nil {
+		return nil, userErr(err)
+	}
+	if err := validateKustomizePathSchemes(d.KustomizePaths); err != nil {
+		return nil, userErr(err)
+	}
+	if d.Hermetic {
+		if err := validateHermetic(d.KustomizePaths); err != nil {
+			return nil, userErr(err)
+		}
+		if d.WatchRemoteBases {
+			return nil, userErr(fmt.Errorf("hermetic builds can't be combined with watchRemoteBases"))
+		}
+	}
+	if environmentPath == "" {
+		if err := checkOverlappingPaths(append(append([]string{}, d.KustomizePaths...), artifactPaths...), d.FailOnOverlappingPaths); err != nil {
+			return nil, userErr(err)
+		}
+	}
+
+	if err := validateFieldManager(d.FieldManager, d.Flags.Apply); err != nil {
+		return nil, userErr(err)
+	}
+
 	kubectl := kubectl.NewCLI(cfg, d.Flags, defaultNamespace)
+	kubectl.ApplyCommand = d.ApplyPlugin
+	kubectl.CommandPrefix = d.ApplyCommandPrefix
+	if d.ServerSidePreview && !hasFlag(kubectl.Flags.Apply, serverSideFlag) {
+		kubectl.Flags.Apply = append(append([]string{}, kubectl.Flags.Apply...), serverSideFlag)
+	}
+	if d.FieldManager != "" {
+		kubectl.Flags.Apply = append(append([]string{}, kubectl.Flags.Apply...), fieldManagerFlag+"="+d.FieldManager)
+	}
+	if d.Prune {
+		kubectl.Flags.Apply = append(append([]string{}, kubectl.Flags.Apply...), pruneFlags(labeller.RunIDSelector(), d.PruneAllowlist)...)
+	}
+	if d.CleanupCascade != "" {
+		kubectl.Flags.Delete = append(append([]string{}, kubectl.Flags.Delete...), "--cascade="+d.CleanupCascade)
+	}
 	// if user has kustomize binary, prioritize that over kubectl kustomize
 	useKubectlKustomize := !KustomizeBinaryCheck() && kubectlVersionCheck(kubectl)
 
@@ -145,7 +483,7 @@ func NewDeployer(cfg kubectl.Config, labeller *label.DefaultLabeller, d *latestV
 		logrus.Warnf("unable to parse namespaces - deploy might not work correctly!")
 	}
 
-	return &Deployer{
+	k := &Deployer{
 		KustomizeDeploy:     d,
 		podSelector:         podSelector,
 		namespaces:          &namespaces,
@@ -158,9 +496,24 @@ func NewDeployer(cfg kubectl.Config, labeller *label.DefaultLabeller, d *latestV
 		kubectl:             kubectl,
 		insecureRegistries:  cfg.GetInsecureRegistries(),
 		globalConfig:        cfg.GlobalConfig(),
-		labels:              labeller.Labels(),
+		labels:              labels,
 		useKubectlKustomize: useKubectlKustomize,
-	}, nil
+		artifactPaths:       artifactPaths,
+		environmentPath:     environmentPath,
+		resolvedImages:      map[string]string{},
+		pluginHome:          pluginHome,
+		gitEnv:              gitEnv,
+		removedFields:       removedFields,
+		buildCacheMaxSize:   buildCacheMaxSize,
+	}
+	if len(d.SecretResolver) > 0 {
+		k.secretResolver = commandSecretResolver(d.SecretResolver)
+	}
+
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k, nil
 }
 
 func (k *Deployer) trackNamespaces(namespaces []string) {
@@ -222,10 +575,13 @@ func (k *Deployer) Deploy(ctx context.Context, out io.Writer, builds []graph.Art
 	// Check that the cluster is reachable.
 	// This gives a better error message when the cluster can't
 	// be reached.
-	if err := kubernetes.FailIfClusterIsNotReachable(); err != nil {
-		return fmt.Errorf("unable to connect to Kubernetes: %w", err)
+	if err := k.checkClusterReachable(ctx); err != nil {
+		return err
 	}
 
+	k.timings = phaseTimings{}
+	renderStart := timeNow()
+
 	childCtx, endTrace := instrumentation.StartTrace(ctx, "Deploy_renderManifests")
 	manifests, err := k.renderManifests(childCtx, out, builds)
 	if err != nil {
@@ -233,10 +589,57 @@ func (k *Deployer) Deploy(ctx context.Context, out io.Writer, builds []graph.Art
 		return err
 	}
 
+	manifests, err = k.filterAPIGroups(manifests)
+	if err != nil {
+		endTrace(instrumentation.TraceEndError(err))
+		return err
+	}
+
+	manifests, err = k.filterSkipped(manifests)
+	if err != nil {
+		endTrace(instrumentation.TraceEndError(err))
+		return err
+	}
+
+	if k.DeployGroup != "" {
+		if manifests, err = filterGroup(manifests, k.DeployGroup); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+
+	// The expected counts are checked against the rendered resources, including those that
+	// already exist and are skipped by onlyNewResources.
+	deployed := manifests
+	if k.OnlyNewResources && len(manifests) > 0 {
+		if manifests, err = k.filterExisting(childCtx, manifests); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+
 	if len(manifests) == 0 {
 		endTrace()
-		return nil
+		return checkResourceCounts(deployed, k.MinResources, k.MinResourcesPerKind)
+	}
+
+	if err := checkResourceSizes(manifests, k.FailOnOversizedResources); err != nil {
+		endTrace(instrumentation.TraceEndError(err))
+		return err
 	}
+
+	if k.PolicyPath != "" {
+		if err := k.checkPolicies(childCtx, manifests); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+
+	if manifests, err = sortByDependencies(manifests); err != nil {
+		endTrace(instrumentation.TraceEndError(err))
+		return err
+	}
+	k.timings.Transform = since(renderStart) - k.timings.Build
 	endTrace()
 
 	childCtx, endTrace = instrumentation.StartTrace(ctx, "Deploy_LoadImages")
@@ -254,6 +657,15 @@ func (k *Deployer) Deploy(ctx context.Context, out io.Writer, builds []graph.Art
 	}
 	endTrace()
 
+	if k.TerminatingNamespaces != "" {
+		childCtx, endTrace = instrumentation.StartTrace(ctx, "Deploy_CheckTerminatingNamespaces")
+		if err := k.checkTerminatingNamespaces(childCtx, textio.NewPrefixWriter(out, " - "), namespaces); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+		endTrace()
+	}
+
 	childCtx, endTrace = instrumentation.StartTrace(ctx, "Deploy_WaitForDeletions")
 	if err := k.kubectl.WaitForDeletions(childCtx, textio.NewPrefixWriter(out, " - "), manifests); err != nil {
 		endTrace(instrumentation.TraceEndError(err))
@@ -261,20 +673,91 @@ func (k *Deployer) Deploy(ctx context.Context, out io.Writer, builds []graph.Art
 	}
 	endTrace()
 
-	childCtx, endTrace = instrumentation.StartTrace(ctx, "Deploy_Apply")
-	if err := k.kubectl.Apply(childCtx, textio.NewPrefixWriter(out, " - "), manifests); err != nil {
-		endTrace(instrumentation.TraceEndError(err))
+	replaceAnnotation := k.ReplaceAnnotation
+	if replaceAnnotation == "" {
+		replaceAnnotation = defaultReplaceAnnotation
+	}
+	replaced, applied, err := splitReplaced(manifests, replaceAnnotation)
+	if err != nil {
 		return err
 	}
+	if k.Prune && len(replaced) > 0 {
+		return userErr(fmt.Errorf("resources annotated with %q can't be deployed with prune, since they would be pruned", replaceAnnotation))
+	}
+
+	childCtx, endTrace = instrumentation.StartTrace(ctx, "Deploy_Apply")
+	applyStart := timeNow()
+	// Secrets are only resolved right before they are sent to the cluster,
+	// so that their values are never rendered, hashed or fingerprinted.
+	if k.secretResolver != nil {
+		if applied, err = resolveSecretRefs(childCtx, applied, k.secretResolver); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+		if replaced, err = resolveSecretRefs(childCtx, replaced, k.secretResolver); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+	if len(applied) > 0 {
+		if err := k.apply(childCtx, textio.NewPrefixWriter(out, " - "), applied); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+	if len(replaced) > 0 {
+		if err := k.replace(childCtx, textio.NewPrefixWriter(out, " - "), replaced); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+	k.timings.Apply = since(applyStart)
 
 	k.TrackBuildArtifacts(builds)
 	endTrace()
 
+	if err := checkResourceCounts(deployed, k.MinResources, k.MinResourcesPerKind); err != nil {
+		return err
+	}
+
+	if len(k.RolloutStatus) > 0 {
+		childCtx, endTrace = instrumentation.StartTrace(ctx, "Deploy_WaitForRollouts")
+		if err := k.waitForRollouts(childCtx, textio.NewPrefixWriter(out, " - "), manifests); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+		endTrace()
+	}
+
+	if len(k.WaitFor) > 0 {
+		childCtx, endTrace = instrumentation.StartTrace(ctx, "Deploy_WaitForConditions")
+		if err := k.waitForConditions(childCtx, textio.NewPrefixWriter(out, " - ")); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+		endTrace()
+	}
+
+	if len(k.ReadinessCommand) > 0 {
+		childCtx, endTrace = instrumentation.StartTrace(ctx, "Deploy_CheckReadiness")
+		if err := k.checkReadiness(childCtx, textio.NewPrefixWriter(out, " - "), manifests); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+		endTrace()
+	}
+
+	if k.ReportPhaseTimings {
+		k.reportPhaseTimings(out)
+	}
+
 	k.trackNamespaces(namespaces)
 	return nil
 }
 
 func (k *Deployer) renderManifests(ctx context.Context, out io.Writer, builds []graph.Artifact) (manifest.ManifestList, error) {
+	k.dropInvalidatedCaches()
+
 	if err := k.kubectl.CheckVersion(ctx); err != nil {
 		output.Default.Fprintln(out, "kubectl client version:", k.kubectl.Version(ctx))
 		output.Default.Fprintln(out, err)
@@ -285,15 +768,52 @@ func (k *Deployer) renderManifests(ctx context.Context, out io.Writer, builds []
 		return nil, deployerr.DebugHelperRetrieveErr(err)
 	}
 
-	manifests, err := k.readManifests(ctx)
+	buildStart := timeNow()
+	manifests, err := k.readManifests(ctx, out)
+	k.timings.Build = since(buildStart)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(manifests) == 0 {
+		if k.FailOnEmptyRender {
+			kustomizePaths, err := k.kustomizePaths()
+			if err != nil {
+				return nil, err
+			}
+			return nil, checkEmptyRender(kustomizePaths)
+		}
 		return nil, nil
 	}
 
+	if k.MaxResources > 0 {
+		if err := checkMaxResources(manifests, k.MaxResources); err != nil {
+			return nil, err
+		}
+	}
+
+	if k.CheckPatchTargets {
+		kustomizePaths, err := k.kustomizePaths()
+		if err != nil {
+			return nil, err
+		}
+		if err := warnUnmatchedPatchTargets(kustomizePaths, manifests); err != nil {
+			return nil, err
+		}
+	}
+
+	if k.FailOnUnresolvedVars {
+		if err := checkUnresolvedVars(manifests); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(k.APIVersionOverrides) > 0 {
+		if manifests, err = overrideAPIVersions(manifests, k.APIVersionOverrides); err != nil {
+			return nil, err
+		}
+	}
+
 	if len(k.originalImages) == 0 {
 		k.originalImages, err = manifests.GetImages()
 		if err != nil {
@@ -301,16 +821,107 @@ func (k *Deployer) renderManifests(ctx context.Context, out io.Writer, builds []
 		}
 	}
 
-	manifests, err = manifests.ReplaceImages(ctx, builds)
+	replacements := k.artifactsToReplace(builds)
+	if len(k.ImageResolver) > 0 {
+		resolved, err := k.resolveImages(ctx, manifests, builds)
+		if err != nil {
+			return nil, err
+		}
+		replacements = append(append([]graph.Artifact{}, replacements...), resolved...)
+	}
+
+	manifests, err = manifests.ReplaceImages(ctx, replacements)
 	if err != nil {
 		return nil, err
 	}
 
+	if k.RequireQualifiedImages {
+		if err := checkQualifiedImages(manifests, k.AllowedShortImages); err != nil {
+			return nil, err
+		}
+	}
+
 	if manifests, err = manifest.ApplyTransforms(manifests, builds, k.insecureRegistries, debugHelpersRegistry); err != nil {
 		return nil, err
 	}
 
-	return manifests.SetLabels(k.labels)
+	if manifests, err = manifests.SetLabels(k.labels); err != nil {
+		return nil, err
+	}
+
+	if k.Scheduling != nil {
+		if manifests, err = scheduleWorkloads(manifests, k.Scheduling); err != nil {
+			return nil, err
+		}
+	}
+
+	if k.SecurityContext != nil {
+		if manifests, err = enforceSecurityContext(manifests, k.SecurityContext); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(k.HostAliases) > 0 {
+		if manifests, err = addHostAliases(manifests, k.HostAliases); err != nil {
+			return nil, err
+		}
+	}
+
+	if k.Replicas != nil {
+		if manifests, err = overrideReplicas(manifests, *k.Replicas, k.SkipAutoscaledReplicas); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(k.CommandOverrides) > 0 {
+		if manifests, err = overrideCommands(manifests, k.CommandOverrides); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(k.CommonAnnotations) > 0 {
+		if manifests, err = addCommonAnnotations(manifests, k.CommonAnnotations, k.OverwriteCommonAnnotations); err != nil {
+			return nil, err
+		}
+	}
+
+	if k.ContentHash {
+		annotation := k.ContentHashAnnotation
+		if annotation == "" {
+			annotation = defaultContentHashAnnotation
+		}
+		if manifests, err = annotateContentHash(manifests, annotation); err != nil {
+			return nil, err
+		}
+	}
+
+	if k.Fingerprint {
+		annotation := k.FingerprintAnnotation
+		if annotation == "" {
+			annotation = defaultFingerprintAnnotation
+		}
+		return annotateFingerprint(manifests, annotation)
+	}
+	return manifests, nil
+}
+
+// artifactsToReplace returns the artifacts whose images are replaced in the manifests.
+// When `ReplaceArtifacts` is set, only the listed artifacts are replaced.
+func (k *Deployer) artifactsToReplace(builds []graph.Artifact) []graph.Artifact {
+	if len(k.ReplaceArtifacts) == 0 {
+		return builds
+	}
+
+	scope := util.NewStringSet()
+	scope.Insert(k.ReplaceArtifacts...)
+
+	var scoped []graph.Artifact
+	for _, build := range builds {
+		if scope.Contains(build.ImageName) {
+			scoped = append(scoped, build)
+		}
+	}
+	return scoped
 }
 
 // Cleanup deletes what was deployed by calling Deploy.
@@ -318,7 +929,7 @@ func (k *Deployer) Cleanup(ctx context.Context, out io.Writer) error {
 	instrumentation.AddAttributesToCurrentSpanFromContext(ctx, map[string]string{
 		"DeployerType": "kustomize",
 	})
-	manifests, err := k.readManifests(ctx)
+	manifests, err := k.readManifests(ctx, out)
 	if err != nil {
 		return err
 	}
@@ -332,22 +943,90 @@ func (k *Deployer) Cleanup(ctx context.Context, out io.Writer) error {
 
 // Dependencies lists all the files that describe what needs to be deployed.
 func (k *Deployer) Dependencies() ([]string, error) {
+	k.dropInvalidatedCaches()
+
 	deps := util.NewStringSet()
-	for _, kustomizePath := range k.KustomizePaths {
-		depsForKustomization, err := DependenciesForKustomization(kustomizePath)
+
+	var remotes []string
+	opts := k.dependencyOptions()
+	if k.WatchRemoteBases {
+		opts.remoteBase = func(target string) { remotes = append(remotes, target) }
+	}
+
+	// Without any kustomization to watch, there are no dependencies.
+	kustomizePaths, _ := k.kustomizePaths()
+	for _, kustomizePath := range kustomizePaths {
+		depsForKustomization, err := dependenciesForKustomization(kustomizePath, opts, 0)
 		if err != nil {
 			return nil, userErr(err)
 		}
 		deps.Insert(depsForKustomization...)
 	}
+
+	if len(remotes) > 0 {
+		if fingerprint := k.remoteBasesFingerprint(remotes); fingerprint != "" {
+			deps.Insert(fingerprint)
+		}
+	}
+
+	if k.Depfile != "" {
+		if err := writeDepfile(k.Depfile, k.DepfileFormat, k.DepfileAbsolutePaths, deps.ToList()); err != nil {
+			return nil, err
+		}
+	}
 	return deps.ToList(), nil
 }
 
+// ResolvedKustomizePaths returns the absolute paths of the kustomizations that are deployed.
+// Remote kustomizations, such as git repositories, have no local path and are left out.
+func (k *Deployer) ResolvedKustomizePaths() ([]string, error) {
+	kustomizePaths, err := k.kustomizePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved []string
+	for _, kustomizePath := range kustomizePaths {
+		if isRemoteBase(kustomizePath) {
+			logrus.Debugf("skipping remote kustomization %q", kustomizePath)
+			continue
+		}
+
+		absPath, err := filepath.Abs(kustomizePath)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, absPath)
+	}
+	return resolved, nil
+}
+
+func (k *Deployer) dependencyOptions() dependencyOptions {
+	return dependencyOptions{
+		warnTrackedSecrets:            k.WarnOnTrackedSecrets,
+		maxDepth:                      k.MaxDependencyDepth,
+		failOnUninitializedSubmodules: k.FailOnUninitializedSubmodules,
+	}
+}
+
 func (k *Deployer) Render(ctx context.Context, out io.Writer, builds []graph.Artifact, offline bool, filepath string) error {
 	instrumentation.AddAttributesToCurrentSpanFromContext(ctx, map[string]string{
 		"DeployerType": "kustomize",
 	})
 
+	// These options replace the single output, which would otherwise be silently left empty.
+	if filepath != "" {
+		if k.RenderSplitDir != "" {
+			return userErr(fmt.Errorf("renderSplitDir can't be combined with an output file"))
+		}
+		if k.RenderComponentDir != "" {
+			return userErr(fmt.Errorf("renderComponentDir can't be combined with an output file"))
+		}
+		if k.RenderDiffAgainst != "" {
+			return userErr(fmt.Errorf("renderDiffAgainst can't be combined with an output file"))
+		}
+	}
+
 	childCtx, endTrace := instrumentation.StartTrace(ctx, "Render_renderManifests")
 	manifests, err := k.renderManifests(childCtx, out, builds)
 	if err != nil {
@@ -355,9 +1034,61 @@ func (k *Deployer) Render(ctx context.Context, out io.Writer, builds []graph.Art
 		return err
 	}
 
+	if k.ValidateAPIVersions {
+		if offline {
+			logrus.Infoln("skipping the validation of API versions in offline mode")
+		} else if err := k.checkAPIVersions(childCtx, manifests); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+
+	if k.OnlyNewResources && len(manifests) > 0 {
+		if offline {
+			logrus.Infoln("rendering all the resources in offline mode")
+		} else if manifests, err = k.filterExisting(childCtx, manifests); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+
+	if k.MinifyRendered {
+		if manifests, err = minifyManifests(manifests); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+
+	if len(k.removedFields) > 0 {
+		if manifests, err = removeFields(manifests, k.removedFields); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+
+	if k.RenderSummary {
+		if err := k.writeEditSummary(out); err != nil {
+			endTrace(instrumentation.TraceEndError(err))
+			return err
+		}
+	}
+	endTrace()
+
+	if k.RenderDiffAgainst != "" {
+		return writeRenderDiff(out, k.RenderDiffAgainst, k.outputRenderedManifests(manifests))
+	}
+
+	if k.RenderSplitDir != "" {
+		return writeSplitManifests(k.RenderSplitDir, k.RenderIndexFile, manifests)
+	}
+
+	if k.RenderComponentDir != "" {
+		return writeComponent(k.RenderComponentDir, manifests)
+	}
+
 	_, endTrace = instrumentation.StartTrace(ctx, "Render_manifest.Write")
 	defer endTrace()
-	return manifest.Write(manifests.String(), filepath, out)
+	return manifest.Write(k.outputRenderedManifests(manifests), filepath, out)
 }
 
 // Values of `patchesStrategicMerge` can be either:
@@ -388,6 +1119,25 @@ func (p *patchWrapper) UnmarshalYAML(unmarshal func(interface{}) error) (err err
 	return nil
 }
 
+// MarshalYAML writes a file path as a plain string and an inline patch as a literal block,
+// so that the output can be read back by `UnmarshalYAML`.
+func (p strategicMergePatch) MarshalYAML() (interface{}, error) {
+	if p.Path != "" {
+		return p.Path, nil
+	}
+
+	return &yamlv3.Node{
+		Kind:  yamlv3.ScalarNode,
+		Style: yamlv3.LiteralStyle,
+		Value: p.Patch,
+	}, nil
+}
+
+// MarshalYAML writes the wrapped patch.
+func (p patchWrapper) MarshalYAML() (interface{}, error) {
+	return p.patchPath, nil
+}
+
 func pathExistsLocally(filename string, workingDir string) (bool, os.FileMode) {
 	path := filename
 	if !filepath.IsAbs(filename) {
@@ -399,31 +1149,237 @@ func pathExistsLocally(filename string, workingDir string) (bool, os.FileMode) {
 	return false, 0
 }
 
-func (k *Deployer) readManifests(ctx context.Context) (manifest.ManifestList, error) {
+// kustomizePaths returns the configured kustomize paths. When an environment is selected,
+// only its overlay is returned. When none is configured, it defaults to the current directory,
+// provided it holds a kustomization.
+func (k *Deployer) kustomizePaths() ([]string, error) {
+	if k.environmentPath != "" {
+		return []string{k.environmentPath}, nil
+	}
+
+	if len(k.KustomizePaths) > 0 || len(k.artifactPaths) > 0 {
+		var paths []string
+		for _, path := range k.KustomizePaths {
+			paths = append(paths, localKustomizePath(path))
+		}
+		return append(paths, k.artifactPaths...), nil
+	}
+
+	if _, err := FindKustomizationConfig(DefaultKustomizePath); err != nil {
+		return nil, userErr(fmt.Errorf("no kustomize paths configured and %w", err))
+	}
+	return []string{DefaultKustomizePath}, nil
+}
+
+// selectEnvironment returns the overlay path of the selected environment.
+func selectEnvironment(environments map[string]string, environment string) (string, error) {
+	if len(environments) == 0 {
+		if environment != "" {
+			return "", fmt.Errorf("environment %q is selected but no environments are configured", environment)
+		}
+		return "", nil
+	}
+
+	selected, err := util.ExpandEnvTemplate(environment, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid environment %q: %w", environment, err)
+	}
+
+	var names []string
+	for name := range environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if selected == "" {
+		return "", fmt.Errorf("no environment selected: must be one of %s", strings.Join(names, ", "))
+	}
+	path, found := environments[selected]
+	if !found {
+		return "", fmt.Errorf("unknown environment %q: must be one of %s", selected, strings.Join(names, ", "))
+	}
+	return path, nil
+}
+
+// artifactKustomizePaths derives a kustomize path from each artifact of the pipelines, using the
+// given template, e.g. `overlays/{{.ImageName}}`. Each derived path must be an existing directory.
+func artifactKustomizePaths(pathTemplate string, pipelines []latestV1.Pipeline) ([]string, error) {
+	if pathTemplate == "" {
+		return nil, nil
+	}
+
+	tmpl, err := util.ParseEnvTemplate(pathTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifactPathTemplate %q: %w", pathTemplate, err)
+	}
+
+	var paths []string
+	for _, p := range pipelines {
+		for _, a := range p.Build.Artifacts {
+			path, err := util.ExecuteEnvTemplate(tmpl, map[string]string{"ImageName": a.ImageName})
+			if err != nil {
+				return nil, fmt.Errorf("deriving kustomize path for artifact %q: %w", a.ImageName, err)
+			}
+			if local, mode := pathExistsLocally(path, ""); !local || !mode.IsDir() {
+				return nil, fmt.Errorf("kustomize path %q derived for artifact %q is not a directory", path, a.ImageName)
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+func (k *Deployer) readManifests(ctx context.Context, out io.Writer) (manifest.ManifestList, error) {
+	kustomizePaths, err := k.kustomizePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	if k.RenderSourceMarkers {
+		k.manifestSources = map[string]string{}
+	}
+
 	var manifests manifest.ManifestList
-	for _, kustomizePath := range k.KustomizePaths {
-		var out []byte
-		var err error
+	for _, kustomizePath := range kustomizePaths {
+		if k.ValidateGeneratorEncoding || k.WarnDuplicateEnvKeys {
+			opts := dependencyOptions{
+				maxDepth:             k.MaxDependencyDepth,
+				warnInvalidEncodings: k.ValidateGeneratorEncoding,
+				warnDuplicateEnvKeys: k.WarnDuplicateEnvKeys,
+			}
+			// Kustomize reports its own errors for broken kustomizations.
+			if _, err := dependenciesForKustomization(kustomizePath, opts, 0); err != nil {
+				logrus.Debugf("unable to check the generator files: %v", err)
+			}
+		}
+
+		if k.Hermetic {
+			if err := k.checkHermetic(kustomizePath); err != nil {
+				return nil, userErr(err)
+			}
+		}
+
+		if len(k.PluginAllowlist) > 0 {
+			if err := k.checkPluginAllowlist(kustomizePath); err != nil {
+				return nil, userErr(err)
+			}
+		}
+
+		if k.MultiDocumentPatches != "" {
+			if err := k.checkMultiDocumentPatches(kustomizePath); err != nil {
+				return nil, userErr(err)
+			}
+		}
 
-		if k.useKubectlKustomize {
-			out, err = k.kubectl.Kustomize(ctx, BuildCommandArgs(k.BuildArgs, kustomizePath))
-		} else {
-			cmd := exec.CommandContext(ctx, "kustomize", append([]string{"build"}, BuildCommandArgs(k.BuildArgs, kustomizePath)...)...)
-			out, err = util.RunCmdOut(cmd)
+		if k.ReportRemoteBaseRefs || k.WarnFloatingRemoteBases {
+			if err := k.checkRemoteBasePins(out, kustomizePath); err != nil {
+				return nil, userErr(err)
+			}
 		}
 
+		buildPath, removePulled := kustomizePath, func() {}
+		if isOCIKustomization(kustomizePath) {
+			if buildPath, removePulled, err = k.pullOCIKustomization(ctx, kustomizePath); err != nil {
+				return nil, userErr(err)
+			}
+		}
+
+		cleanup, err := stageFiles(buildPath, k.StagedFiles)
 		if err != nil {
+			removePulled()
 			return nil, userErr(err)
 		}
 
-		if len(out) == 0 {
+		buf, err := k.buildWithRetry(ctx, buildPath, out)
+		cleanup()
+		removePulled()
+		if err != nil {
+			if !k.AllowPartialBuildOutput || !usablePartialOutput(buf, err) {
+				return nil, userErr(err)
+			}
+			warnings.Printf("kustomize build of %s failed, using its partial output: %v", kustomizePath, err)
+		}
+
+		buf = stripBOMs(buf)
+		if len(buf) == 0 {
 			continue
 		}
-		manifests.Append(out)
+		built := len(manifests)
+		manifests.Append(buf)
+
+		if k.DuplicateKeys != "" {
+			if err := k.checkDuplicateKeys(kustomizePath, manifests[built:]); err != nil {
+				return nil, err
+			}
+		}
+
+		if k.RenderSourceMarkers {
+			if err := k.recordSources(kustomizePath, manifests[built:]); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return manifests, nil
 }
 
+// kustomizeBuildCmd creates the command that builds the given kustomize path,
+// either with `kustomize build` or with `kubectl kustomize`.
+func (k *Deployer) kustomizeBuildCmd(ctx context.Context, kustomizePath string) *exec.Cmd {
+	args := BuildCommandArgs(k.BuildArgs, kustomizePath)
+	if k.buildArgsHook != nil {
+		args = k.buildArgsHook(append([]string{}, args...))
+	}
+
+	var cmd *exec.Cmd
+	if k.useKubectlKustomize {
+		cmd = k.kubectl.Command(ctx, "kustomize", append(append([]string{}, k.kubectl.Flags.Global...), args...)...)
+	} else {
+		cmd = exec.CommandContext(ctx, "kustomize", append([]string{"build"}, args...)...)
+	}
+
+	if env := k.kustomizeBuildEnv(); len(env) > 0 {
+		cmd.Env = append(util.OSEnviron(), env...)
+	}
+	return cmd
+}
+
+// kustomizeBuildEnv returns the environment variables added to the environment of kustomize builds.
+func (k *Deployer) kustomizeBuildEnv() []string {
+	var env []string
+	if k.pluginHome != "" {
+		env = append(env, pluginHomeEnv+"="+k.pluginHome)
+	}
+	env = append(env, k.gitEnv...)
+	if k.Hermetic {
+		env = append(env, hermeticEnv...)
+	}
+	return env
+}
+
+// runKustomizeBuild runs a kustomize build command and returns its output.
+// Depending on `KustomizeLogLevel`, the command's stderr is either discarded, logged or streamed to `out`.
+func (k *Deployer) runKustomizeBuild(cmd *exec.Cmd, out io.Writer) ([]byte, error) {
+	var stderr io.Writer
+	switch k.KustomizeLogLevel {
+	case kustomizeLogInfo:
+		stderr = out
+	case kustomizeLogDebug:
+		w := logrus.StandardLogger().WriterLevel(logrus.DebugLevel)
+		defer w.Close()
+		stderr = w
+	default:
+		return util.RunCmdOut(cmd)
+	}
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+	if err := util.RunCmd(cmd); err != nil {
+		return stdout.Bytes(), fmt.Errorf("running %s: %w", strings.Join(cmd.Args, " "), err)
+	}
+	return stdout.Bytes(), nil
+}
+
 func IsKustomizationBase(path string) bool {
 	return filepath.Dir(path) == basePath
 }
@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"io/ioutil"
+	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
@@ -34,6 +35,7 @@ import (
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
 	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
 	"github.com/GoogleContainerTools/skaffold/testutil"
 )
 
@@ -227,6 +229,16 @@ func TestKustomizeCleanup(t *testing.T) {
 				AndRunOut("kustomize build "+tmpDir.Path("b"), kubectl.DeploymentAppYAML).
 				AndRun("kubectl --context kubecontext --namespace testNamespace delete --ignore-not-found=true --wait=false -f -"),
 		},
+		{
+			description: "cleanup with cascade policy",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{tmpDir.Root()},
+				CleanupCascade: "foreground",
+			},
+			commands: testutil.
+				CmdRunOut("kustomize build "+tmpDir.Root(), kubectl.DeploymentWebYAML).
+				AndRun("kubectl --context kubecontext --namespace testNamespace delete --cascade=foreground --ignore-not-found=true --wait=false -f -"),
+		},
 		{
 			description: "cleanup error",
 			kustomize: latestV1.KustomizeDeploy{
@@ -265,6 +277,203 @@ func TestKustomizeCleanup(t *testing.T) {
 	}
 }
 
+func TestInvalidCleanupCascade(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		_, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			CleanupCascade: "true",
+		})
+
+		t.CheckErrorContains(`invalid cleanupCascade "true"`, err)
+	})
+}
+
+func TestKustomizeEmptyPaths(t *testing.T) {
+	tests := []struct {
+		description   string
+		kustomization string
+		commands      util.Command
+		expected      string
+		shouldErr     bool
+	}{
+		{
+			description:   "default to current directory",
+			kustomization: "kustomization.yaml",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion112).
+				AndRunOut("kustomize build .", serviceYAML),
+			expected: serviceYAML + "\n",
+		},
+		{
+			description:   "alternative kustomization name",
+			kustomization: "Kustomization",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion112).
+				AndRunOut("kustomize build .", serviceYAML),
+			expected: serviceYAML + "\n",
+		},
+		{
+			description: "no kustomization in current directory",
+			commands:    testutil.CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion112),
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir().Chdir()
+			if test.kustomization != "" {
+				tmpDir.Write(test.kustomization, "resources: [deployment.yaml]")
+			}
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{})
+			t.RequireNoError(err)
+
+			var b bytes.Buffer
+			err = k.Render(context.Background(), &b, nil, true, "")
+
+			t.CheckError(test.shouldErr, err)
+			t.CheckDeepEqual(test.expected, b.String())
+		})
+	}
+}
+
+func TestArtifactKustomizePaths(t *testing.T) {
+	tests := []struct {
+		description string
+		template    string
+		createDirs  []string
+		expected    []string
+		shouldErr   bool
+	}{
+		{
+			description: "paths derived from artifacts",
+			template:    "overlays/{{.ImageName}}",
+			createDirs:  []string{"overlays/frontend", "overlays/backend"},
+			expected:    []string{"base", "overlays/frontend", "overlays/backend"},
+		},
+		{
+			description: "missing overlay",
+			template:    "overlays/{{.ImageName}}",
+			createDirs:  []string{"overlays/frontend"},
+			shouldErr:   true,
+		},
+		{
+			description: "no template",
+			expected:    []string{"base"},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir().Chdir()
+			for _, dir := range test.createDirs {
+				tmpDir.Mkdir(dir)
+			}
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{
+				RunContext: runcontext.RunContext{
+					Pipelines: runcontext.NewPipelines([]latestV1.Pipeline{{
+						Build: latestV1.BuildConfig{
+							Artifacts: []*latestV1.Artifact{{ImageName: "frontend"}, {ImageName: "backend"}},
+						},
+					}}),
+				},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:       []string{"base"},
+				ArtifactPathTemplate: test.template,
+			})
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+
+			paths, err := k.kustomizePaths()
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected, paths)
+		})
+	}
+}
+
+func TestKustomizeEnvironments(t *testing.T) {
+	environments := map[string]string{
+		"dev":  "overlays/dev",
+		"prod": "overlays/prod",
+	}
+
+	tests := []struct {
+		description string
+		environment string
+		env         map[string]string
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "dev",
+			environment: "dev",
+			commands:    testutil.CmdRunOut("kustomize build overlays/dev", serviceYAML),
+		},
+		{
+			description: "prod selected at runtime",
+			environment: "{{.DEPLOY_ENV}}",
+			env:         map[string]string{"DEPLOY_ENV": "prod"},
+			commands:    testutil.CmdRunOut("kustomize build overlays/prod", deploymentYAML),
+		},
+		{
+			description: "unknown environment",
+			environment: "staging",
+			shouldErr:   true,
+		},
+		{
+			description: "no environment selected",
+			environment: "{{.DEPLOY_ENV}}",
+			env:         map[string]string{"DEPLOY_ENV": ""},
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.SetEnvs(test.env)
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"base"},
+				Environments:   environments,
+				Environment:    test.environment,
+			})
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+
+			manifests, err := k.readManifests(context.Background(), ioutil.Discard)
+			t.CheckNoError(err)
+			t.CheckDeepEqual(1, len(manifests))
+		})
+	}
+}
+
+func TestResolvedKustomizePaths(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		tmpDir := t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"overlays/dev", tmpDir.Path("base"), "github.com/org/repo//overlays/dev?ref=v1", "file://overlays/prod", "oci://registry.example.com/app/kustomize:v1"},
+		})
+		t.RequireNoError(err)
+
+		paths, err := k.ResolvedKustomizePaths()
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual([]string{tmpDir.Path("overlays/dev"), tmpDir.Path("base"), tmpDir.Path("overlays/prod")}, paths)
+	})
+}
+
 func TestDependenciesForKustomization(t *testing.T) {
 	tests := []struct {
 		description    string
@@ -273,6 +482,22 @@ func TestDependenciesForKustomization(t *testing.T) {
 		createFiles    map[string]string
 		kustomizations map[string]string
 	}{
+		{
+			description: "inline json6902 patch",
+			kustomizations: map[string]string{"kustomization.yaml": `patchesJson6902:
+- target:
+    kind: Deployment
+    name: web
+  patch: |-
+    - op: remove
+      path: /spec/replicas
+- target:
+    kind: Service
+    name: web
+  path: patch.json`},
+			expected:    []string{"kustomization.yaml", "patch.json"},
+			createFiles: map[string]string{"patch.json": ""},
+		},
 		{
 			description:    "resources",
 			kustomizations: map[string]string{"kustomization.yaml": `resources: [pod1.yaml, path/pod2.yaml]`},
@@ -346,6 +571,18 @@ func TestDependenciesForKustomization(t *testing.T) {
 - envs: [app2.env, app3.env]`},
 			expected: []string{"app1.env", "app1.properties", "app2.env", "app2.properties", "app3.env", "app3.properties", "kustomization.yaml"},
 		},
+		{
+			description: "generators with behavior",
+			kustomizations: map[string]string{"kustomization.yaml": `configMapGenerator:
+- name: settings
+  behavior: merge
+  envs: [settings.env]
+secretGenerator:
+- name: creds
+  behavior: replace
+  files: [password.txt]`},
+			expected: []string{"kustomization.yaml", "password.txt", "settings.env"},
+		},
 		{
 			description: "secretGenerator",
 			kustomizations: map[string]string{"kustomization.yaml": `secretGenerator:
@@ -468,6 +705,273 @@ func TestDependenciesForKustomization(t *testing.T) {
 	}
 }
 
+func TestDependenciesMaxDepth(t *testing.T) {
+	tests := []struct {
+		description string
+		maxDepth    int
+		shouldErr   bool
+	}{
+		{
+			description: "within limit",
+			maxDepth:    2,
+		},
+		{
+			description: "limit exceeded",
+			maxDepth:    1,
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir().
+				Write("kustomization.yaml", `bases: [base1]`).
+				Write("base1/kustomization.yaml", `bases: [../base2]`).
+				Write("base2/kustomization.yaml", `resources: [app.yaml]`).
+				Write("base2/app.yaml", "")
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:     []string{tmpDir.Root()},
+				MaxDependencyDepth: test.maxDepth,
+			})
+			t.RequireNoError(err)
+
+			_, err = k.Dependencies()
+
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				t.CheckErrorContains("nested more than 1 levels deep", err)
+			}
+		})
+	}
+}
+
+func TestCanonicalKustomization(t *testing.T) {
+	tests := []struct {
+		description   string
+		kustomization string
+		expected      string
+		shouldErr     bool
+	}{
+		{
+			description: "keys are sorted and empty fields dropped",
+			kustomization: `secretGenerator:
+- env: secret.env
+configMapGenerator:
+- files: [app.properties]
+  envs: [app2.env]
+  env: app1.env
+patchesJson6902:
+- path: patch.json
+crds: [crd.yaml]
+patchesStrategicMerge:
+- patch.yaml
+- |-
+  apiVersion: v1
+  kind: Pod
+patches:
+- path: patch1.yaml
+- patch: |-
+    inline: patch
+resources: [app.yaml]
+bases: [base]
+components: [component]
+`,
+			expected: `bases:
+- base
+components:
+- component
+configMapGenerator:
+- envs:
+  - app2.env
+  - app1.env
+  files:
+  - app.properties
+crds:
+- crd.yaml
+patches:
+- path: patch1.yaml
+- patch: 'inline: patch'
+patchesJson6902:
+- path: patch.json
+patchesStrategicMerge:
+- patch.yaml
+- |-
+  apiVersion: v1
+  kind: Pod
+resources:
+- app.yaml
+secretGenerator:
+- envs:
+  - secret.env
+`,
+		},
+		{
+			description: "inline json6902 patch",
+			kustomization: `patchesJson6902:
+- target:
+    version: v1
+    kind: Deployment
+    name: web
+    group: apps
+  patch: |-
+    - op: replace
+      path: /spec/replicas
+      value: 3
+`,
+			expected: `patchesJson6902:
+- patch: |-
+    - op: replace
+      path: /spec/replicas
+      value: 3
+  target:
+    group: apps
+    kind: Deployment
+    name: web
+    version: v1
+`,
+		},
+		{
+			description: "sort options",
+			kustomization: `sortOptions:
+  legacySortOptions:
+    orderLast: [ValidatingWebhookConfiguration]
+    orderFirst: [Namespace, CustomResourceDefinition]
+  order: legacy
+resources: [app.yaml]
+`,
+			expected: `resources:
+- app.yaml
+sortOptions:
+  legacySortOptions:
+    orderFirst:
+    - Namespace
+    - CustomResourceDefinition
+    orderLast:
+    - ValidatingWebhookConfiguration
+  order: legacy
+`,
+		},
+		{
+			description: "apiVersion, kind and unknown fields are kept",
+			kustomization: `resources: [app.yaml]
+kind: Kustomization
+apiVersion: kustomize.config.k8s.io/v1beta1
+openapi:
+  path: schema.json
+namePrefix: ""
+images: []
+`,
+			expected: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+openapi:
+  path: schema.json
+resources:
+- app.yaml
+`,
+		},
+		{
+			description:   "empty kustomization",
+			kustomization: ``,
+			expected:      "{}\n",
+		},
+		{
+			description: "invalid kustomization",
+			kustomization: `resources:
+- [`,
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir().Write("kustomization.yaml", test.kustomization)
+
+			canonical, err := CanonicalKustomization(tmpDir.Root())
+			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected, string(canonical))
+			if test.shouldErr {
+				return
+			}
+
+			// The canonical form is stable.
+			tmpDir.Write("kustomization.yaml", string(canonical))
+			again, err := CanonicalKustomization(tmpDir.Root())
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected, string(again))
+		})
+	}
+}
+
+func TestPatchJSON6902UnmarshalStrict(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		var content kustomization
+		err := yaml.UnmarshalStrict([]byte(`patchesJson6902:
+- target:
+    group: apps
+    version: v1
+    kind: Deployment
+    name: web
+    namespace: default
+  patch: '[{"op": "remove", "path": "/spec/replicas"}]'
+`), &content)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual([]patchJSON6902{{
+			Target: &patchTarget{Group: "apps", Version: "v1", Kind: "Deployment", Name: "web", Namespace: "default"},
+			Patch:  `[{"op": "remove", "path": "/spec/replicas"}]`,
+		}}, content.PatchesJSON6902)
+	})
+}
+
+func TestGeneratorBehaviorUnmarshalStrict(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		var content kustomization
+		err := yaml.UnmarshalStrict([]byte(`configMapGenerator:
+- name: settings
+  behavior: merge
+  envs: [settings.env]
+secretGenerator:
+- name: creds
+  behavior: replace
+  files: [password.txt]
+`), &content)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual([]configMapGenerator{{Name: "settings", Behavior: "merge", Envs: []string{"settings.env"}}}, content.ConfigMapGenerator)
+		t.CheckDeepEqual([]secretGenerator{{Name: "creds", Behavior: "replace", Files: []string{"password.txt"}}}, content.SecretGenerator)
+	})
+}
+
+func TestKustomizeBuildArgsHook(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&util.DefaultExecCommand, testutil.CmdRunOut("kustomize build --load-restrictor=LoadRestrictionsNone --enable-helm .", serviceYAML))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+			BuildArgs:      []string{"--load-restrictor=LoadRestrictionsNone"},
+		}, WithBuildArgsHook(func(args []string) []string {
+			path := args[len(args)-1]
+			return append(append(args[:len(args)-1], "--enable-helm"), path)
+		}))
+		t.RequireNoError(err)
+
+		manifests, err := k.readManifests(context.Background(), ioutil.Discard)
+		t.CheckNoError(err)
+		t.CheckDeepEqual(serviceYAML, manifests.String())
+	})
+}
+
+func TestSortOptionsUnmarshalStrict(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		var content kustomization
+		err := yaml.UnmarshalStrict([]byte(`sortOptions:
+  order: fifo
+`), &content)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual(&sortOptions{Order: "fifo"}, content.SortOptions)
+	})
+}
+
 func TestKustomizeBuildCommandArgs(t *testing.T) {
 	tests := []struct {
 		description   string
@@ -542,6 +1046,7 @@ func TestKustomizeRender(t *testing.T) {
 		description    string
 		builds         []graph.Artifact
 		labels         []string
+		scope          []string
 		kustomizations []kustomizationCall
 		expected       string
 		shouldErr      bool
@@ -684,6 +1189,47 @@ spec:
   containers:
   - image: gcr.io/project/image2:tag2
     name: image2
+`,
+		},
+		{
+			description: "image replacement scoped to some artifacts",
+			builds: []graph.Artifact{
+				{
+					ImageName: "gcr.io/project/image1",
+					Tag:       "gcr.io/project/image1:tag1",
+				},
+				{
+					ImageName: "gcr.io/project/image2",
+					Tag:       "gcr.io/project/image2:tag2",
+				},
+			},
+			scope: []string{"gcr.io/project/image2"},
+			kustomizations: []kustomizationCall{
+				{
+					folder: ".",
+					buildResult: `apiVersion: v1
+kind: Pod
+metadata:
+  namespace: default
+spec:
+  containers:
+  - image: gcr.io/project/image1:stable
+    name: image1
+  - image: gcr.io/project/image2
+    name: image2
+`,
+				},
+			},
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  namespace: default
+spec:
+  containers:
+  - image: gcr.io/project/image1:stable
+    name: image1
+  - image: gcr.io/project/image2:tag2
+    name: image2
 `,
 		},
 	}
@@ -705,7 +1251,8 @@ spec:
 				workingDir: ".",
 				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
 			}, labeller, &latestV1.KustomizeDeploy{
-				KustomizePaths: kustomizationPaths,
+				KustomizePaths:   kustomizationPaths,
+				ReplaceArtifacts: test.scope,
 			})
 			t.RequireNoError(err)
 
@@ -717,6 +1264,84 @@ spec:
 	}
 }
 
+func TestKustomizeLogLevel(t *testing.T) {
+	tests := []struct {
+		description string
+		logLevel    string
+		expected    string
+		shouldErr   bool
+	}{
+		{
+			description: "suppressed by default",
+			expected:    serviceYAML + "\n",
+		},
+		{
+			description: "suppressed",
+			logLevel:    "none",
+			expected:    serviceYAML + "\n",
+		},
+		{
+			description: "debug",
+			logLevel:    "debug",
+			expected:    serviceYAML + "\n",
+		},
+		{
+			description: "streamed to output",
+			logLevel:    "info",
+			expected:    "# Warning: 'bases' is deprecated\n" + serviceYAML + "\n",
+		},
+		{
+			description: "invalid level",
+			logLevel:    "verbose",
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, &stderrCommand{stdout: serviceYAML, stderr: "# Warning: 'bases' is deprecated\n"})
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:    []string{"."},
+				KustomizeLogLevel: test.logLevel,
+			})
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+
+			var b bytes.Buffer
+			err = k.Render(context.Background(), &b, nil, true, "")
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected, b.String())
+		})
+	}
+}
+
+// stderrCommand fakes commands that print to both stdout and stderr.
+type stderrCommand struct {
+	stdout string
+	stderr string
+}
+
+func (c *stderrCommand) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Args[0] == "kubectl" {
+		return []byte(kubectl.KubectlVersion112), nil
+	}
+	return []byte(c.stdout), nil
+}
+
+func (c *stderrCommand) RunCmd(cmd *exec.Cmd) error {
+	cmd.Stderr.Write([]byte(c.stderr))
+	cmd.Stdout.Write([]byte(c.stdout))
+	return nil
+}
+
 type kustomizeConfig struct {
 	runcontext.RunContext // Embedded to provide the default values.
 	force                 bool
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+const (
+	// maxLabelValueLength is the longest label value accepted by Kubernetes.
+	maxLabelValueLength = 63
+
+	longLabelValuesTruncate = "truncate"
+	longLabelValuesHash     = "hash"
+
+	// labelHashLength is the number of hex characters of the hash that ends a hashed label value.
+	labelHashLength = 10
+)
+
+// filterLabels returns the labels whose key is in the allowlist.
+// An empty allowlist keeps all the labels. The run id label is always kept, since
+// the status check, port forwarding and prune rely on it to find the deployed resources.
+func filterLabels(labels map[string]string, allowlist []string) (map[string]string, error) {
+	if len(allowlist) == 0 {
+		return labels, nil
+	}
+
+	filtered := map[string]string{}
+	if runID, found := labels[label.RunIDLabel]; found {
+		filtered[label.RunIDLabel] = runID
+	}
+	for _, key := range allowlist {
+		if key == "" {
+			return nil, fmt.Errorf("invalid labelAllowlist: label keys must not be empty")
+		}
+		if value, found := labels[key]; found {
+			filtered[key] = value
+		}
+	}
+	return filtered, nil
+}
+
+// validateLongLabelValues checks how label values that are too long are shortened.
+func validateLongLabelValues(mode string) error {
+	switch mode {
+	case "", longLabelValuesTruncate, longLabelValuesHash:
+		return nil
+	default:
+		return fmt.Errorf("invalid longLabelValues %q: must be either %q or %q", mode, longLabelValuesTruncate, longLabelValuesHash)
+	}
+}
+
+// shortenLabelValues shortens the label values longer than Kubernetes accepts, either by truncating them or
+// by replacing their end with a hash of the whole value, which keeps distinct values distinct.
+// Without a mode, the labels are returned unchanged.
+func shortenLabelValues(labels map[string]string, mode string) map[string]string {
+	if mode == "" {
+		return labels
+	}
+
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	shortened := make(map[string]string, len(labels))
+	for _, key := range keys {
+		value := labels[key]
+		if len(value) <= maxLabelValueLength {
+			shortened[key] = value
+			continue
+		}
+
+		if mode == longLabelValuesHash {
+			sum := sha256.Sum256([]byte(value))
+			hash := hex.EncodeToString(sum[:])[:labelHashLength]
+			shortened[key] = trimLabelValue(value[:maxLabelValueLength-labelHashLength-1]) + "-" + hash
+		} else {
+			shortened[key] = trimLabelValue(value[:maxLabelValueLength])
+		}
+		warnings.Printf("value of label %s is longer than %d characters, it was shortened to %q", key, maxLabelValueLength, shortened[key])
+	}
+	return shortened
+}
+
+// trimLabelValue removes the trailing characters a label value can't end with.
+func trimLabelValue(value string) string {
+	return strings.TrimRightFunc(value, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	})
+}
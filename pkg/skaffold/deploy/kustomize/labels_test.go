@@ -0,0 +1,245 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestFilterLabels(t *testing.T) {
+	labels := map[string]string{
+		label.RunIDLabel:           "run-id",
+		label.K8sManagedByLabelKey: "skaffold",
+		"team":                     "web",
+	}
+
+	tests := []struct {
+		description string
+		allowlist   []string
+		expected    map[string]string
+		shouldErr   bool
+	}{
+		{
+			description: "no allowlist",
+			expected:    labels,
+		},
+		{
+			description: "run id only",
+			allowlist:   []string{label.RunIDLabel},
+			expected:    map[string]string{label.RunIDLabel: "run-id"},
+		},
+		{
+			description: "unknown keys are ignored",
+			allowlist:   []string{"team", "owner"},
+			expected:    map[string]string{label.RunIDLabel: "run-id", "team": "web"},
+		},
+		{
+			description: "run id is always kept",
+			allowlist:   []string{label.K8sManagedByLabelKey},
+			expected:    map[string]string{label.RunIDLabel: "run-id", label.K8sManagedByLabelKey: "skaffold"},
+		},
+		{
+			description: "empty key",
+			allowlist:   []string{""},
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			filtered, err := filterLabels(labels, test.allowlist)
+
+			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected, filtered)
+		})
+	}
+}
+
+func TestKustomizeRenderLabelAllowlist(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", deploymentYAML))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, label.NewLabeller(true, []string{"team=web"}, "run-id"), &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+			LabelAllowlist: []string{label.RunIDLabel},
+		})
+		t.RequireNoError(err)
+
+		var out bytes.Buffer
+		err = k.Render(context.Background(), &out, nil, true, "")
+		t.RequireNoError(err)
+
+		t.CheckContains(label.RunIDLabel+": run-id", out.String())
+		t.CheckFalse(bytes.Contains(out.Bytes(), []byte(label.K8sManagedByLabelKey)))
+		t.CheckFalse(bytes.Contains(out.Bytes(), []byte("team: web")))
+	})
+}
+
+func TestKustomizeRenderLabelAllowlistKeepsRunID(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", deploymentYAML))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, label.NewLabeller(true, []string{"team=web"}, "run-id"), &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+			LabelAllowlist: []string{"team"},
+		})
+		t.RequireNoError(err)
+
+		var out bytes.Buffer
+		err = k.Render(context.Background(), &out, nil, true, "")
+		t.RequireNoError(err)
+
+		t.CheckContains(label.RunIDLabel+": run-id", out.String())
+		t.CheckContains("team: web", out.String())
+		t.CheckFalse(bytes.Contains(out.Bytes(), []byte(label.K8sManagedByLabelKey)))
+	})
+}
+
+func TestShortenLabelValues(t *testing.T) {
+	const longRunID = "feature-very-long-branch-name-with-a-ticket-number-1234-and-a-suffix"
+	labels := map[string]string{
+		label.RunIDLabel: longRunID,
+		"team":           strings.Repeat("x", 62) + "-yyy",
+		"short":          "web",
+	}
+
+	tests := []struct {
+		description      string
+		mode             string
+		expected         map[string]string
+		expectedWarnings []string
+	}{
+		{
+			description: "unchanged by default",
+			expected:    labels,
+		},
+		{
+			description: "truncate",
+			mode:        longLabelValuesTruncate,
+			expected: map[string]string{
+				label.RunIDLabel: "feature-very-long-branch-name-with-a-ticket-number-1234-and-a-s",
+				"team":           strings.Repeat("x", 62),
+				"short":          "web",
+			},
+			expectedWarnings: []string{
+				`value of label skaffold.dev/run-id is longer than 63 characters, it was shortened to "feature-very-long-branch-name-with-a-ticket-number-1234-and-a-s"`,
+				`value of label team is longer than 63 characters, it was shortened to "` + strings.Repeat("x", 62) + `"`,
+			},
+		},
+		{
+			description: "hash",
+			mode:        longLabelValuesHash,
+			expected: map[string]string{
+				label.RunIDLabel: "feature-very-long-branch-name-with-a-ticket-number-1-77daa7beb8",
+				"team":           strings.Repeat("x", 52) + "-7c63780d77",
+				"short":          "web",
+			},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+
+			shortened := shortenLabelValues(labels, test.mode)
+
+			t.CheckDeepEqual(test.expected, shortened)
+			if test.expectedWarnings != nil {
+				t.CheckDeepEqual(test.expectedWarnings, fakeWarner.Warnings)
+			}
+		})
+	}
+}
+
+func TestKustomizeRenderLongLabelValues(t *testing.T) {
+	tests := []struct {
+		description string
+		mode        string
+		prune       bool
+		expected    string
+		shouldErr   bool
+	}{
+		{
+			description: "hashed run id",
+			mode:        longLabelValuesHash,
+			expected:    label.RunIDLabel + ": feature-very-long-branch-name-with-a-ticket-number-1-77daa7beb8",
+		},
+		{
+			description: "invalid mode",
+			mode:        "drop",
+			shouldErr:   true,
+		},
+		{
+			description: "prune needs the original run id",
+			mode:        longLabelValuesTruncate,
+			prune:       true,
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, label.NewLabeller(true, nil, "feature-very-long-branch-name-with-a-ticket-number-1234-and-a-suffix"), &latestV1.KustomizeDeploy{
+				KustomizePaths:  []string{"."},
+				LongLabelValues: test.mode,
+				Prune:           test.prune,
+			})
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+
+			var out bytes.Buffer
+			err = k.Render(context.Background(), &out, nil, true, "")
+
+			t.CheckNoError(err)
+			t.CheckContains(test.expected, out.String())
+		})
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+// maxClientSideApplySize is the maximum size of the annotations of a resource. Client-side apply stores
+// the whole resource in the `kubectl.kubernetes.io/last-applied-configuration` annotation, so larger
+// resources, typically CustomResourceDefinitions with big OpenAPI schemas, can only be applied server-side.
+const maxClientSideApplySize = 256 * 1024
+
+// splitLargeCRDs separates the CustomResourceDefinitions that are too large for client-side apply from
+// the other resources. Their size is estimated with the size of the rendered manifest.
+func splitLargeCRDs(manifests manifest.ManifestList) (manifest.ManifestList, []string, manifest.ManifestList, error) {
+	var large, others manifest.ManifestList
+	var names []string
+
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if r.Kind == crdKind && len(m) > maxClientSideApplySize {
+			large = append(large, m)
+			names = append(names, r.Metadata.Name)
+		} else {
+			others = append(others, m)
+		}
+	}
+
+	return large, names, others, nil
+}
+
+// applyLargeCRDs applies server-side the CustomResourceDefinitions that are too large for client-side apply,
+// and returns the remaining resources. With `waitForCRDs`, it also waits for those to be established.
+func (k *Deployer) applyLargeCRDs(ctx context.Context, out io.Writer, manifests manifest.ManifestList) (manifest.ManifestList, error) {
+	if hasFlag(k.kubectl.Flags.Apply, serverSideFlag) {
+		return manifests, nil
+	}
+
+	large, names, others, err := splitLargeCRDs(manifests)
+	if err != nil || len(large) == 0 {
+		return manifests, err
+	}
+
+	if k.Prune {
+		// The resources that are not part of the pruning apply would be deleted right after being created.
+		return nil, userErr(fmt.Errorf("CustomResourceDefinitions %q are too large for client-side apply and can't be applied separately with prune", names))
+	}
+
+	logrus.Infof("Applying CustomResourceDefinitions %q server-side, as they are too large for client-side apply", names)
+	cli := k.kubectl
+	cli.Flags.Apply = append(append([]string{}, cli.Flags.Apply...), serverSideFlag)
+	if err := k.kubectlApplyWith(ctx, &cli, out, large); err != nil {
+		return nil, err
+	}
+
+	if k.WaitForCRDs {
+		if err := k.waitForCRDs(ctx, out, names); err != nil {
+			return nil, err
+		}
+	}
+
+	return others, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeDeployLargeCRDs(t *testing.T) {
+	largeCRDYAML := barCRDYAML + "\nspec:\n  description: " + strings.Repeat("x", maxClientSideApplySize)
+
+	tests := []struct {
+		description string
+		labeller    *label.DefaultLabeller
+		kustomize   latestV1.KustomizeDeploy
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "large crd applied server-side",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crdYAML+"\n---\n"+largeCRDYAML+"\n---\n"+crYAML).
+				AndRun("kubectl --context kubecontext --namespace testNamespace apply --server-side -f -").
+				AndRunInput(applyCommand, crdYAML+"\n---\n"+crYAML),
+		},
+		{
+			description: "large crd applied server-side then established",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				WaitForCRDs:    true,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", largeCRDYAML+"\n---\n"+crYAML).
+				AndRun("kubectl --context kubecontext --namespace testNamespace apply --server-side -f -").
+				AndRun("kubectl --context kubecontext --namespace testNamespace wait --for=condition=established --timeout=60s crd/bars.example.com").
+				AndRunInput(applyCommand, crYAML),
+		},
+		{
+			description: "disabled",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths:           []string{"."},
+				ServerSideApplyLargeCRDs: util.BoolPtr(false),
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", largeCRDYAML).
+				AndRun(applyCommand),
+		},
+		{
+			description: "small crds stay client-side",
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", crdYAML+"\n---\n"+crYAML).
+				AndRunInput(applyCommand, crdYAML+"\n---\n"+crYAML),
+		},
+		{
+			description: "large crd with prune",
+			labeller:    label.NewLabeller(true, nil, "run-id"),
+			kustomize: latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				Prune:          true,
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", largeCRDYAML),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+			labeller := test.labeller
+			if labeller == nil {
+				labeller = &label.DefaultLabeller{}
+			}
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, labeller, &test.kustomize)
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
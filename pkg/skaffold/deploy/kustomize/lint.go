@@ -0,0 +1,171 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LintSeverity ranks the findings of LintKustomization.
+type LintSeverity int
+
+const (
+	LintInfo LintSeverity = iota
+	LintWarning
+	LintError
+)
+
+func (s LintSeverity) String() string {
+	switch s {
+	case LintInfo:
+		return "info"
+	case LintWarning:
+		return "warning"
+	case LintError:
+		return "error"
+	default:
+		return fmt.Sprintf("LintSeverity(%d)", int(s))
+	}
+}
+
+// LintFinding is an issue found in a kustomization.
+type LintFinding struct {
+	Severity LintSeverity
+	Rule     string
+	Message  string
+}
+
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%s: %s (%s)", f.Severity, f.Message, f.Rule)
+}
+
+// LintKustomization reports common issues with the kustomization found in the provided dir
+// and with the way it's built:
+// + use of the deprecated `bases` field
+// + load restrictions disabled through build args
+// + secret generators reading files that are not ignored by git
+// + generators with an unknown `behavior`, or that merge into or replace a generator of a base
+// + no `resources`, `components` or generators
+func (k *Deployer) LintKustomization(dir string) ([]LintFinding, error) {
+	path, err := FindKustomizationConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := readKustomization(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []LintFinding
+
+	if len(content.Bases) > 0 {
+		findings = append(findings, LintFinding{
+			Severity: LintWarning,
+			Rule:     "deprecated-bases",
+			Message:  fmt.Sprintf("%s uses the deprecated `bases` field, list them in `resources` instead", path),
+		})
+	}
+
+	if disablesLoadRestrictions(BuildCommandArgs(k.BuildArgs, "")) {
+		findings = append(findings, LintFinding{
+			Severity: LintWarning,
+			Rule:     "load-restrictor",
+			Message:  "build args disable load restrictions, allowing kustomizations to read any file on the machine",
+		})
+	}
+
+	for _, generator := range content.SecretGenerator {
+		envs := generator.Envs
+		if generator.Env != "" {
+			envs = append(envs, generator.Env)
+		}
+		for _, file := range trackedSecrets(dir, append(generatorFilePaths(generator.Files), envs...)) {
+			findings = append(findings, LintFinding{
+				Severity: LintError,
+				Rule:     "tracked-secret",
+				Message:  fmt.Sprintf("secret generator file %q is not ignored by git and could be committed by mistake", file),
+			})
+		}
+	}
+
+	for _, generator := range generatorBehaviors(content) {
+		switch generator.behavior {
+		case "", "create":
+		case "merge", "replace":
+			findings = append(findings, LintFinding{
+				Severity: LintInfo,
+				Rule:     "generator-behavior",
+				Message:  fmt.Sprintf("%s %q uses behavior %q and requires a generator of the same name in a base", generator.kind, generator.name, generator.behavior),
+			})
+		default:
+			findings = append(findings, LintFinding{
+				Severity: LintError,
+				Rule:     "generator-behavior",
+				Message:  fmt.Sprintf("%s %q has unknown behavior %q, it must be `create`, `merge` or `replace`", generator.kind, generator.name, generator.behavior),
+			})
+		}
+	}
+
+	if len(content.Resources) == 0 && len(content.Bases) == 0 && len(content.Components) == 0 &&
+		len(content.ConfigMapGenerator) == 0 && len(content.SecretGenerator) == 0 {
+		findings = append(findings, LintFinding{
+			Severity: LintError,
+			Rule:     "missing-resources",
+			Message:  fmt.Sprintf("%s doesn't declare any resources", path),
+		})
+	}
+
+	return findings, nil
+}
+
+// generatorBehavior is the behavior of a configMapGenerator or secretGenerator.
+type generatorBehavior struct {
+	kind     string
+	name     string
+	behavior string
+}
+
+// generatorBehaviors lists the behaviors of the generators of a kustomization, in order.
+func generatorBehaviors(content kustomization) []generatorBehavior {
+	var behaviors []generatorBehavior
+	for _, generator := range content.ConfigMapGenerator {
+		behaviors = append(behaviors, generatorBehavior{"configMapGenerator", generator.Name, generator.Behavior})
+	}
+	for _, generator := range content.SecretGenerator {
+		behaviors = append(behaviors, generatorBehavior{"secretGenerator", generator.Name, generator.Behavior})
+	}
+	return behaviors
+}
+
+// disablesLoadRestrictions checks whether build args let kustomize load files from outside the kustomization root.
+func disablesLoadRestrictions(args []string) bool {
+	for i, arg := range args {
+		value := ""
+		switch {
+		case strings.HasPrefix(arg, "--load-restrictor=") || strings.HasPrefix(arg, "--load_restrictor="):
+			value = arg[strings.Index(arg, "=")+1:]
+		case (arg == "--load-restrictor" || arg == "--load_restrictor") && i+1 < len(args):
+			value = args[i+1]
+		}
+		if strings.EqualFold(value, "LoadRestrictionsNone") || strings.EqualFold(value, "none") {
+			return true
+		}
+	}
+	return false
+}
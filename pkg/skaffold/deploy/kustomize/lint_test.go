@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestLintKustomization(t *testing.T) {
+	tests := []struct {
+		description   string
+		kustomization string
+		buildArgs     []string
+		files         map[string]string
+		expected      []LintFinding
+	}{
+		{
+			description:   "no findings",
+			kustomization: `resources: [app.yaml]`,
+			buildArgs:     []string{"--enable-helm"},
+		},
+		{
+			description:   "deprecated bases",
+			kustomization: `bases: [../base]`,
+			expected: []LintFinding{{
+				Severity: LintWarning,
+				Rule:     "deprecated-bases",
+				Message:  "{{kustomization.yaml}} uses the deprecated `bases` field, list them in `resources` instead",
+			}},
+		},
+		{
+			description:   "load restrictions disabled",
+			kustomization: `resources: [app.yaml]`,
+			buildArgs:     []string{"--load-restrictor LoadRestrictionsNone"},
+			expected: []LintFinding{{
+				Severity: LintWarning,
+				Rule:     "load-restrictor",
+				Message:  "build args disable load restrictions, allowing kustomizations to read any file on the machine",
+			}},
+		},
+		{
+			description:   "load restrictions disabled with legacy flag",
+			kustomization: `resources: [app.yaml]`,
+			buildArgs:     []string{"--load_restrictor=none"},
+			expected: []LintFinding{{
+				Severity: LintWarning,
+				Rule:     "load-restrictor",
+				Message:  "build args disable load restrictions, allowing kustomizations to read any file on the machine",
+			}},
+		},
+		{
+			description: "generator behaviors",
+			kustomization: `resources: [../base]
+configMapGenerator:
+- name: settings
+  behavior: merge
+  literals: [LOG_LEVEL=debug]
+- name: flags
+  behavior: create
+secretGenerator:
+- name: creds
+  behavior: override`,
+			expected: []LintFinding{
+				{
+					Severity: LintInfo,
+					Rule:     "generator-behavior",
+					Message:  `configMapGenerator "settings" uses behavior "merge" and requires a generator of the same name in a base`,
+				},
+				{
+					Severity: LintError,
+					Rule:     "generator-behavior",
+					Message:  "secretGenerator \"creds\" has unknown behavior \"override\", it must be `create`, `merge` or `replace`",
+				},
+			},
+		},
+		{
+			description: "tracked secret",
+			kustomization: `secretGenerator:
+- name: creds
+  files: [password=tracked.txt, ignored.txt]`,
+			files: map[string]string{"tracked.txt": "secret", "ignored.txt": "secret"},
+			expected: []LintFinding{{
+				Severity: LintError,
+				Rule:     "tracked-secret",
+				Message:  `secret generator file "{{tracked.txt}}" is not ignored by git and could be committed by mistake`,
+			}},
+		},
+		{
+			description:   "missing resources",
+			kustomization: `namePrefix: dev-`,
+			expected: []LintFinding{{
+				Severity: LintError,
+				Rule:     "missing-resources",
+				Message:  "{{kustomization.yaml}} doesn't declare any resources",
+			}},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&isGitIgnored, func(path string) bool { return filepath.Base(path) == "ignored.txt" })
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			tmpDir := t.NewTempDir().Write("kustomization.yaml", test.kustomization)
+			for path, content := range test.files {
+				tmpDir.Write(path, content)
+			}
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{BuildArgs: test.buildArgs})
+			t.RequireNoError(err)
+
+			findings, err := k.LintKustomization(tmpDir.Root())
+
+			var expected []LintFinding
+			for _, finding := range test.expected {
+				finding.Message = expandPaths(tmpDir, finding.Message)
+				expected = append(expected, finding)
+			}
+			t.CheckNoError(err)
+			t.CheckDeepEqual(expected, findings)
+		})
+	}
+}
+
+// expandPaths replaces `{{file}}` placeholders with the path of the file in the temporary directory.
+func expandPaths(tmpDir *testutil.TempDir, message string) string {
+	for _, file := range []string{"kustomization.yaml", "tracked.txt"} {
+		message = strings.ReplaceAll(message, "{{"+file+"}}", tmpDir.Path(file))
+	}
+	return message
+}
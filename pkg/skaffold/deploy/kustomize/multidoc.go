@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+const (
+	multiDocumentPatchesWarn  = "warn"
+	multiDocumentPatchesError = "error"
+)
+
+// validateMultiDocumentPatches checks how patches made of several YAML documents are reported.
+func validateMultiDocumentPatches(mode string) error {
+	switch mode {
+	case "", multiDocumentPatchesWarn, multiDocumentPatchesError:
+		return nil
+	default:
+		return fmt.Errorf("invalid multiDocumentPatches %q: must be either %q or %q", mode, multiDocumentPatchesWarn, multiDocumentPatchesError)
+	}
+}
+
+// visitPatch calls visit with the content of an inline patch, or of a local patch file.
+// Patch directories and files that don't exist locally are skipped.
+func visitPatch(visit func(name string, content []byte), kustomizationPath, dir, path, inline string) {
+	if inline != "" {
+		visit("inline patch of "+kustomizationPath, []byte(inline))
+		return
+	}
+
+	if local, mode := pathExistsLocally(path, dir); !local || mode.IsDir() {
+		return
+	}
+	file := filepath.Join(dir, path)
+	if content, err := ioutil.ReadFile(file); err == nil {
+		visit(file, content)
+	}
+}
+
+// countDocuments returns the number of non-empty YAML documents in the content.
+func countDocuments(content []byte) (int, error) {
+	decoder := yamlv3.NewDecoder(bytes.NewReader(content))
+	count := 0
+	for {
+		var doc yamlv3.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return count, nil
+			}
+			return 0, err
+		}
+		if len(doc.Content) > 0 && doc.Content[0].Tag != "!!null" {
+			count++
+		}
+	}
+}
+
+// checkMultiDocumentPatches reports the patches of a kustomization, or of its local bases, that hold
+// more than one YAML document, since a patch is expected to be a single document.
+func (k *Deployer) checkMultiDocumentPatches(kustomizePath string) error {
+	var multiDocument []string
+	opts := k.dependencyOptions()
+	opts.patch = func(name string, content []byte) {
+		// Kustomize reports the patches that are not valid YAML itself.
+		if count, err := countDocuments(content); err == nil && count > 1 {
+			multiDocument = append(multiDocument, fmt.Sprintf("%s (%d documents)", name, count))
+		}
+	}
+
+	if _, err := dependenciesForKustomization(kustomizePath, opts, 0); err != nil {
+		return err
+	}
+	if len(multiDocument) == 0 {
+		return nil
+	}
+
+	if k.MultiDocumentPatches == multiDocumentPatchesError {
+		return fmt.Errorf("patches must be a single YAML document: %s", strings.Join(multiDocument, ", "))
+	}
+	for _, patch := range multiDocument {
+		warnings.Printf("patch %s holds more than one YAML document, kustomize expects a single patch document", patch)
+	}
+	return nil
+}
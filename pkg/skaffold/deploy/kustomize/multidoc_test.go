@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeMultiDocumentPatches(t *testing.T) {
+	const kustomization = `resources: [base]
+patchesStrategicMerge:
+- single.yaml
+- multi.yaml
+patches:
+- patch: |-
+    apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: web
+    ---
+    apiVersion: v1
+    kind: Service
+    metadata:
+      name: web`
+
+	tests := []struct {
+		description      string
+		mode             string
+		commands         util.Command
+		expectedWarnings []string
+		expectedErr      string
+	}{
+		{
+			description: "warn",
+			mode:        "warn",
+			commands:    testutil.CmdRunOut("kustomize build .", serviceYAML),
+			expectedWarnings: []string{
+				"patch base/multi.yaml (2 documents) holds more than one YAML document, kustomize expects a single patch document",
+				"patch inline patch of kustomization.yaml (2 documents) holds more than one YAML document, kustomize expects a single patch document",
+				"patch multi.yaml (3 documents) holds more than one YAML document, kustomize expects a single patch document",
+			},
+		},
+		{
+			description: "error",
+			mode:        "error",
+			expectedErr: "patches must be a single YAML document: base/multi.yaml (2 documents), multi.yaml (3 documents), inline patch of kustomization.yaml (2 documents)",
+		},
+		{
+			description: "not checked by default",
+			commands:    testutil.CmdRunOut("kustomize build .", serviceYAML),
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.NewTempDir().
+				Write("kustomization.yaml", kustomization).
+				Write("single.yaml", "---\n"+deploymentYAML+"\n---\n").
+				Write("multi.yaml", deploymentYAML+"\n---\n"+serviceYAML+"\n---\n"+deploymentYAML).
+				Write("base/kustomization.yaml", "patchesStrategicMerge: [multi.yaml]").
+				Write("base/multi.yaml", deploymentYAML+"\n---\n"+serviceYAML).
+				Chdir()
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:       []string{"."},
+				MultiDocumentPatches: test.mode,
+			})
+			t.RequireNoError(err)
+
+			_, err = k.readManifests(context.Background(), ioutil.Discard)
+
+			if test.expectedErr != "" {
+				t.CheckErrorContains(test.expectedErr, err)
+			} else {
+				t.CheckNoError(err)
+			}
+			t.CheckDeepEqual(test.expectedWarnings, fakeWarner.Warnings)
+		})
+	}
+}
+
+func TestValidateMultiDocumentPatches(t *testing.T) {
+	testutil.CheckError(t, false, validateMultiDocumentPatches(""))
+	testutil.CheckError(t, false, validateMultiDocumentPatches("warn"))
+	testutil.CheckError(t, false, validateMultiDocumentPatches("error"))
+	testutil.CheckError(t, true, validateMultiDocumentPatches("ignore"))
+}
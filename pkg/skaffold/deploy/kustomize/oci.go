@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+const (
+	fileScheme = "file://"
+	ociScheme  = "oci://"
+)
+
+// validateKustomizePathSchemes checks the kustomize paths that are URLs. `oci://` paths must
+// be valid artifact references, pinned to a tag or a digest.
+func validateKustomizePathSchemes(paths []string) error {
+	for _, path := range paths {
+		switch {
+		case strings.HasPrefix(path, fileScheme):
+			if strings.TrimPrefix(path, fileScheme) == "" {
+				return fmt.Errorf("invalid kustomize path %q: missing file path", path)
+			}
+		case strings.HasPrefix(path, ociScheme):
+			ref, err := docker.ParseReference(strings.TrimPrefix(path, ociScheme))
+			if err != nil {
+				return fmt.Errorf("invalid kustomize path %q: %w", path, err)
+			}
+			if ref.Tag == "" && ref.Digest == "" {
+				return fmt.Errorf("invalid kustomize path %q: must be pinned to a tag or a digest", path)
+			}
+		}
+	}
+	return nil
+}
+
+// localKustomizePath turns a `file://` kustomize path into a plain local path.
+func localKustomizePath(path string) string {
+	return strings.TrimPrefix(path, fileScheme)
+}
+
+// isOCIKustomization checks whether a kustomize path points to an OCI artifact.
+func isOCIKustomization(path string) bool {
+	return strings.HasPrefix(path, ociScheme)
+}
+
+// pullOCIKustomization pulls the kustomization stored as an OCI artifact with `oras` and returns the
+// directory it was pulled to, along with a function that cleans it up once built.
+// With `ociCacheDir`, artifacts pinned by digest are pulled once and kept, since their content can't change.
+// Those pinned to a tag are pulled again every time, as the tag might have moved.
+func (k *Deployer) pullOCIKustomization(ctx context.Context, path string) (string, func(), error) {
+	ref := strings.TrimPrefix(path, ociScheme)
+	pinned := strings.Contains(ref, "@")
+
+	var dir string
+	cleanup := func() {}
+	if k.OCICacheDir != "" {
+		dir = filepath.Join(k.OCICacheDir, checksum([]byte(ref)))
+		if _, err := os.Stat(dir); err == nil && pinned {
+			logrus.Debugf("using cached kustomization %q from %s", path, dir)
+			return dir, cleanup, nil
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return "", nil, err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", nil, err
+		}
+	} else {
+		tmpDir, err := ioutil.TempDir("", "skaffold-kustomize-oci")
+		if err != nil {
+			return "", nil, err
+		}
+		dir = tmpDir
+		cleanup = func() { os.RemoveAll(tmpDir) }
+	}
+
+	if _, err := util.RunCmdOut(exec.CommandContext(ctx, "oras", "pull", ref, "--output", dir)); err != nil {
+		cleanup()
+		if k.OCICacheDir != "" {
+			// Don't leave a partial pull in the cache.
+			os.RemoveAll(dir)
+		}
+		return "", nil, fmt.Errorf("pulling kustomization %q: %w", path, err)
+	}
+	return dir, cleanup, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const ociDigest = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+func TestValidateKustomizePathSchemes(t *testing.T) {
+	tests := []struct {
+		description string
+		path        string
+		shouldErr   bool
+	}{
+		{description: "local path", path: "overlays/dev"},
+		{description: "file url", path: "file://overlays/dev"},
+		{description: "empty file url", path: "file://", shouldErr: true},
+		{description: "oci with tag", path: "oci://registry.example.com/app/kustomize:v1"},
+		{description: "oci with digest", path: "oci://registry.example.com/app/kustomize@" + ociDigest},
+		{description: "oci without tag", path: "oci://registry.example.com/app/kustomize", shouldErr: true},
+		{description: "invalid oci reference", path: "oci://registry.example.com/app:v1:v2", shouldErr: true},
+		{description: "git url", path: "https://github.com/org/repo//overlays/dev?ref=v1"},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			err := validateKustomizePathSchemes([]string{test.path})
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestKustomizeRenderOCIPath(t *testing.T) {
+	tests := []struct {
+		description string
+		ref         string
+		cached      bool
+		pull        bool
+		pullErr     error
+		shouldErr   bool
+	}{
+		{
+			description: "pull tag",
+			ref:         "registry.example.com/app/kustomize:v1",
+			pull:        true,
+		},
+		{
+			description: "tags are always pulled again",
+			ref:         "registry.example.com/app/kustomize:v1",
+			cached:      true,
+			pull:        true,
+		},
+		{
+			description: "cached digest",
+			ref:         "registry.example.com/app/kustomize@" + ociDigest,
+			cached:      true,
+		},
+		{
+			description: "pull failure",
+			ref:         "registry.example.com/app/kustomize:v1",
+			pull:        true,
+			pullErr:     errors.New("not found"),
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			cacheDir := t.NewTempDir()
+			dir := filepath.Join(cacheDir.Root(), checksum([]byte(test.ref)))
+			if test.cached {
+				cacheDir.Write(filepath.Join(checksum([]byte(test.ref)), "kustomization.yaml"), "resources: [service.yaml]")
+			}
+
+			commands := testutil.CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118)
+			if test.pull {
+				commands = commands.AndRunOutErr("oras pull "+test.ref+" --output "+dir, "", test.pullErr)
+			}
+			if test.pullErr == nil {
+				commands = commands.AndRunOut("kustomize build "+dir, serviceYAML)
+			}
+			t.Override(&util.DefaultExecCommand, commands)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"oci://" + test.ref},
+				OCICacheDir:    cacheDir.Root(),
+			})
+			t.RequireNoError(err)
+
+			var out bytes.Buffer
+			err = k.Render(context.Background(), &out, nil, true, "")
+
+			t.CheckError(test.shouldErr, err)
+			if !test.shouldErr {
+				t.CheckDeepEqual(serviceYAML+"\n", out.String())
+			}
+		})
+	}
+}
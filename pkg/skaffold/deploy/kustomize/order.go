@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// dependsOnAnnotation lists the resources, as comma separated `Kind/name`, that must be applied before the annotated one.
+const dependsOnAnnotation = "skaffold.dev/depends-on"
+
+// sortByDependencies orders the resources so that each one comes after the resources listed in its
+// `skaffold.dev/depends-on` annotation. Otherwise, the original order is preserved.
+// Dependencies on resources that are not part of the manifests are ignored.
+func sortByDependencies(manifests manifest.ManifestList) (manifest.ManifestList, error) {
+	resources := make([]resource, len(manifests))
+	byID := map[string][]int{}
+	annotated := false
+
+	for i, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+		resources[i] = r
+		id := r.Kind + "/" + r.Metadata.Name
+		byID[id] = append(byID[id], i)
+		if r.Metadata.Annotations[dependsOnAnnotation] != "" {
+			annotated = true
+		}
+	}
+	if !annotated {
+		return manifests, nil
+	}
+
+	// dependents[i] lists the resources that depend on resource i.
+	dependents := make([][]int, len(manifests))
+	pending := make([]int, len(manifests))
+	for i, r := range resources {
+		for _, dep := range strings.Split(r.Metadata.Annotations[dependsOnAnnotation], ",") {
+			dep = strings.TrimSpace(dep)
+			if dep == "" {
+				continue
+			}
+			targets, found := byID[dep]
+			if !found {
+				warnings.Printf("%s depends on %s, which is not deployed", r, dep)
+				continue
+			}
+			for _, target := range targets {
+				dependents[target] = append(dependents[target], i)
+				pending[i]++
+			}
+		}
+	}
+
+	sorted := make(manifest.ManifestList, 0, len(manifests))
+	done := make([]bool, len(manifests))
+	for len(sorted) < len(manifests) {
+		next := -1
+		for i := range manifests {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			var cycle []string
+			for i, r := range resources {
+				if !done[i] {
+					cycle = append(cycle, r.String())
+				}
+			}
+			return nil, userErr(fmt.Errorf("cycle in %s annotations between %s", dependsOnAnnotation, strings.Join(cycle, ", ")))
+		}
+
+		done[next] = true
+		sorted = append(sorted, manifests[next])
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+		}
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSortByDependencies(t *testing.T) {
+	const (
+		webYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  annotations:
+    skaffold.dev/depends-on: ConfigMap/config, Job/migrate
+  name: web`
+		migrateYAML = `apiVersion: batch/v1
+kind: Job
+metadata:
+  annotations:
+    skaffold.dev/depends-on: StatefulSet/db
+  name: migrate`
+		dbYAML = `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db`
+		configYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config`
+		cyclicDBYAML = `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  annotations:
+    skaffold.dev/depends-on: Deployment/web
+  name: db`
+		missingYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  annotations:
+    skaffold.dev/depends-on: Secret/missing
+  name: web`
+	)
+
+	tests := []struct {
+		description      string
+		manifests        []string
+		expected         []string
+		expectedWarnings []string
+		shouldErr        bool
+	}{
+		{
+			description: "no annotations",
+			manifests:   []string{serviceYAML, deploymentYAML},
+			expected:    []string{serviceYAML, deploymentYAML},
+		},
+		{
+			description: "dependency graph",
+			manifests:   []string{webYAML, serviceYAML, migrateYAML, dbYAML, configYAML},
+			expected:    []string{serviceYAML, dbYAML, migrateYAML, configYAML, webYAML},
+		},
+		{
+			description:      "missing dependency",
+			manifests:        []string{missingYAML, serviceYAML},
+			expected:         []string{missingYAML, serviceYAML},
+			expectedWarnings: []string{"Deployment/web depends on Secret/missing, which is not deployed"},
+		},
+		{
+			description: "cycle",
+			manifests:   []string{webYAML, migrateYAML, cyclicDBYAML, configYAML},
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+
+			var manifests manifest.ManifestList
+			for _, m := range test.manifests {
+				manifests = append(manifests, []byte(m))
+			}
+
+			sorted, err := sortByDependencies(manifests)
+
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				t.CheckErrorContains("cycle in skaffold.dev/depends-on annotations between Deployment/web, Job/migrate, StatefulSet/db", err)
+				return
+			}
+			var actual []string
+			for _, m := range sorted {
+				actual = append(actual, string(m))
+			}
+			t.CheckDeepEqual(test.expected, actual)
+			t.CheckDeepEqual(test.expectedWarnings, fakeWarner.Warnings)
+		})
+	}
+}
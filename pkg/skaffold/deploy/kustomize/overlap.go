@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// checkOverlappingPaths reports the kustomize paths that are the same as, or nested inside, another one.
+// Their resources are likely to be rendered twice. When strict, overlaps fail instead of being warned about.
+// Only local paths are compared: remote bases and OCI artifacts are left out.
+func checkOverlappingPaths(paths []string, strict bool) error {
+	abs := make([]string, len(paths))
+	for i, path := range paths {
+		if isRemoteBase(path) && !strings.HasPrefix(path, fileScheme) || isOCIKustomization(path) {
+			continue
+		}
+		p, err := filepath.Abs(localKustomizePath(path))
+		if err != nil {
+			return err
+		}
+		abs[i] = p
+	}
+
+	var overlaps []string
+	for i := range paths {
+		for j := range paths {
+			if i == j || abs[i] == "" || abs[j] == "" {
+				continue
+			}
+			switch {
+			case abs[i] == abs[j] && i < j:
+				overlaps = append(overlaps, fmt.Sprintf("%s and %s are the same path", paths[i], paths[j]))
+			case strings.HasPrefix(abs[j], abs[i]+string(filepath.Separator)):
+				overlaps = append(overlaps, fmt.Sprintf("%s is nested inside %s", paths[j], paths[i]))
+			}
+		}
+	}
+
+	if len(overlaps) == 0 {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("overlapping kustomize paths: %s", strings.Join(overlaps, ", "))
+	}
+	warnings.Printf("overlapping kustomize paths, resources might be deployed twice: %s", strings.Join(overlaps, ", "))
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeOverlappingPaths(t *testing.T) {
+	tests := []struct {
+		description      string
+		paths            []string
+		strict           bool
+		expectedWarnings []string
+		shouldErr        bool
+	}{
+		{
+			description: "distinct paths",
+			paths:       []string{"overlays/dev", "overlays/development"},
+		},
+		{
+			description:      "parent and child",
+			paths:            []string{"base", "overlays/dev", "base/nested"},
+			expectedWarnings: []string{"overlapping kustomize paths, resources might be deployed twice: base/nested is nested inside base"},
+		},
+		{
+			description:      "same path",
+			paths:            []string{"base", "./base/"},
+			expectedWarnings: []string{"overlapping kustomize paths, resources might be deployed twice: base and ./base/ are the same path"},
+		},
+		{
+			description: "remote and oci paths are not compared",
+			paths: []string{
+				"github.com/example/platform//base",
+				"github.com/example/platform//base/nested",
+				"oci://registry.example.com/app:v1",
+				"base",
+			},
+		},
+		{
+			description:      "file paths are compared",
+			paths:            []string{"file://base", "base/nested"},
+			expectedWarnings: []string{"overlapping kustomize paths, resources might be deployed twice: base/nested is nested inside file://base"},
+		},
+		{
+			description: "parent and child in strict mode",
+			paths:       []string{"base/nested", "base"},
+			strict:      true,
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			_, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:         test.paths,
+				FailOnOverlappingPaths: test.strict,
+			})
+
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				t.CheckErrorContains("base/nested is nested inside base", err)
+				return
+			}
+			t.CheckDeepEqual(test.expectedWarnings, fakeWarner.Warnings)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+// usablePartialOutput checks whether the output of a failed kustomize build can be deployed anyway:
+// the build must have run and every document it printed must be a complete resource.
+func usablePartialOutput(buf []byte, buildErr error) bool {
+	if len(buf) == 0 || errors.Is(buildErr, exec.ErrNotFound) {
+		return false
+	}
+
+	var manifests manifest.ManifestList
+	manifests.Append(buf)
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil || r.APIVersion == "" || r.Kind == "" || r.Metadata.Name == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizePartialBuildOutput(t *testing.T) {
+	tests := []struct {
+		description      string
+		allowPartial     bool
+		output           string
+		expected         manifest.ManifestList
+		expectedWarnings []string
+		shouldErr        bool
+	}{
+		{
+			description:  "partial output used",
+			allowPartial: true,
+			output:       serviceYAML + "\n---\n" + deploymentYAML,
+			expected:     manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)},
+			expectedWarnings: []string{
+				"kustomize build of . failed, using its partial output: exit status 1",
+			},
+		},
+		{
+			description: "strict by default",
+			output:      serviceYAML,
+			shouldErr:   true,
+		},
+		{
+			description:  "incomplete resource",
+			allowPartial: true,
+			output:       serviceYAML + "\n---\napiVersion: apps/v1\nkind: Deployment",
+			shouldErr:    true,
+		},
+		{
+			description:  "no output",
+			allowPartial: true,
+			shouldErr:    true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+			t.Override(&util.DefaultExecCommand, testutil.CmdRunOutErr("kustomize build .", test.output, errors.New("exit status 1")))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:          []string{"."},
+				AllowPartialBuildOutput: test.allowPartial,
+			})
+			t.RequireNoError(err)
+
+			manifests, err := k.readManifests(context.Background(), ioutil.Discard)
+
+			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected.String(), manifests.String())
+			t.CheckDeepEqual(test.expectedWarnings, fakeWarner.Warnings)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// strategicMergePatchFiles lists the files of a strategic merge patch directory, recursively.
+// Each file is checked to be a valid strategic merge patch, and a warning is printed otherwise.
+func strategicMergePatchFiles(dir string) ([]string, error) {
+	var files []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		files = append(files, path)
+		if err := validateStrategicMergePatch(path); err != nil {
+			warnings.Printf("%s is not a valid strategic merge patch: %v", path, err)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// validateStrategicMergePatch checks that each document of a file parses as YAML
+// and identifies the resource it patches, with an `apiVersion`, a `kind` and a `metadata.name`.
+func validateStrategicMergePatch(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yamlv3.NewDecoder(bytes.NewReader(buf))
+	for {
+		var r resource
+		if err := decoder.Decode(&r); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("reading YAML: %w", err)
+		}
+
+		switch {
+		case r.APIVersion == "":
+			return errors.New("missing apiVersion")
+		case r.Kind == "":
+			return errors.New("missing kind")
+		case r.Metadata.Name == "":
+			return errors.New("missing metadata.name")
+		}
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestStrategicMergePatchDirectory(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		fakeWarner := &warnings.Collect{}
+		t.Override(&warnings.Printf, fakeWarner.Warnf)
+
+		tmpDir := t.NewTempDir().
+			Write("kustomization.yaml", `patchesStrategicMerge:
+- patches`).
+			Write("patches/replicas.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3`).
+			Write("patches/nested/ports.yaml", serviceYAML+"\n---\n"+deploymentYAML).
+			Write("patches/no-name.yaml", `apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: 3`).
+			Write("patches/broken.yaml", `kind: [Deployment`)
+
+		deps, err := DependenciesForKustomization(tmpDir.Root())
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual(tmpDir.Paths("kustomization.yaml", "patches/broken.yaml", "patches/nested/ports.yaml", "patches/no-name.yaml", "patches/replicas.yaml"), deps)
+		t.CheckDeepEqual(2, len(fakeWarner.Warnings))
+		t.CheckContains("patches/broken.yaml is not a valid strategic merge patch: reading YAML", fakeWarner.Warnings[0])
+		t.CheckContains("patches/no-name.yaml is not a valid strategic merge patch: missing metadata.name", fakeWarner.Warnings[1])
+	})
+}
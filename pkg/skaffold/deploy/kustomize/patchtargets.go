@@ -0,0 +1,155 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// warnUnmatchedPatchTargets warns about the patch targets of the given kustomizations that match none of the
+// rendered resources. Kustomize silently skips such patches, which usually hides a typo in the selector.
+// Only the targets of the top-level kustomizations are checked, not those of their bases.
+func warnUnmatchedPatchTargets(kustomizePaths []string, manifests manifest.ManifestList) error {
+	var resources []resource
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, r)
+	}
+
+	for _, kustomizePath := range kustomizePaths {
+		path, err := FindKustomizationConfig(kustomizePath)
+		if err != nil {
+			// Remote kustomizations can't be checked.
+			continue
+		}
+		content, err := readKustomization(path)
+		if err != nil {
+			return err
+		}
+
+		for _, target := range patchTargets(content) {
+			if !matchesAny(target, content, resources) {
+				warnings.Printf("patch target %s in %s matches no resource", describePatchTarget(target), path)
+			}
+		}
+	}
+	return nil
+}
+
+// patchTargets lists the targets of the `patches` and `patchesJson6902` of a kustomization.
+func patchTargets(content kustomization) []patchTarget {
+	var targets []patchTarget
+	for _, patch := range content.Patches {
+		if patch.patchPath != nil && patch.Target != nil {
+			targets = append(targets, *patch.Target)
+		}
+	}
+	for _, patch := range content.PatchesJSON6902 {
+		if patch.Target != nil {
+			targets = append(targets, *patch.Target)
+		}
+	}
+	return targets
+}
+
+// matchesAny checks whether a patch target selects at least one of the rendered resources.
+// Names are compared both as rendered and without the name prefix and suffix of the kustomization,
+// since patches target the names the resources have before those are added.
+func matchesAny(target patchTarget, content kustomization, resources []resource) bool {
+	for _, r := range resources {
+		if matchesTarget(target, content, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesTarget(target patchTarget, content kustomization, r resource) bool {
+	group, version := "", r.APIVersion
+	if i := strings.LastIndex(r.APIVersion, "/"); i >= 0 {
+		group, version = r.APIVersion[:i], r.APIVersion[i+1:]
+	}
+
+	switch {
+	case target.Group != "" && target.Group != group:
+		return false
+	case target.Version != "" && target.Version != version:
+		return false
+	case target.Kind != "" && !matchesPattern(target.Kind, r.Kind):
+		return false
+	case target.Namespace != "" && content.Namespace == "" && !matchesPattern(target.Namespace, r.Metadata.Namespace):
+		return false
+	}
+
+	if target.Name != "" {
+		original := strings.TrimSuffix(strings.TrimPrefix(r.Metadata.Name, content.NamePrefix), content.NameSuffix)
+		if !matchesPattern(target.Name, r.Metadata.Name) && !matchesPattern(target.Name, original) {
+			return false
+		}
+	}
+
+	return matchesSelector(target.LabelSelector, r.Metadata.Labels) && matchesSelector(target.AnnotationSelector, r.Metadata.Annotations)
+}
+
+// matchesPattern compares a value to a target field, which kustomize reads as an anchored regular expression.
+func matchesPattern(pattern, value string) bool {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return pattern == value
+	}
+	return re.MatchString(value)
+}
+
+// matchesSelector checks a label or annotation selector. Invalid selectors are left to kustomize to report.
+func matchesSelector(selector string, values map[string]string) bool {
+	if selector == "" {
+		return true
+	}
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return true
+	}
+	return parsed.Matches(labels.Set(values))
+}
+
+// describePatchTarget formats the fields set on a patch target.
+func describePatchTarget(target patchTarget) string {
+	var fields []string
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+	add("group", target.Group)
+	add("version", target.Version)
+	add("kind", target.Kind)
+	add("name", target.Name)
+	add("namespace", target.Namespace)
+	add("labelSelector", target.LabelSelector)
+	add("annotationSelector", target.AnnotationSelector)
+	return "[" + strings.Join(fields, " ") + "]"
+}
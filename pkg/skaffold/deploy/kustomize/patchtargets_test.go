@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeCheckPatchTargets(t *testing.T) {
+	tests := []struct {
+		description   string
+		kustomization string
+		rendered      string
+		expected      []string
+	}{
+		{
+			description: "matching targets",
+			kustomization: `patches:
+- path: replicas.yaml
+  target:
+    kind: Deployment
+    name: web
+- path: service.yaml
+  target:
+    version: v1
+    kind: Service
+patchesJson6902:
+- target:
+    group: apps
+    version: v1
+    kind: Deployment
+    name: w.*
+  path: patch.json`,
+			rendered: deploymentYAML + "\n---\n" + serviceYAML,
+		},
+		{
+			description: "name with prefix",
+			kustomization: `namePrefix: dev-
+patches:
+- path: replicas.yaml
+  target:
+    kind: Deployment
+    name: web`,
+			rendered: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dev-web`,
+		},
+		{
+			description: "misspelled name",
+			kustomization: `patches:
+- path: replicas.yaml
+  target:
+    kind: Deployment
+    name: wbe`,
+			rendered: deploymentYAML,
+			expected: []string{"patch target [kind=Deployment name=wbe] in kustomization.yaml matches no resource"},
+		},
+		{
+			description: "wrong group and label selector",
+			kustomization: `patches:
+- path: replicas.yaml
+  target:
+    group: extensions
+    kind: Deployment
+- path: replicas.yaml
+  target:
+    labelSelector: app=web`,
+			rendered: deploymentYAML,
+			expected: []string{
+				"patch target [group=extensions kind=Deployment] in kustomization.yaml matches no resource",
+				"patch target [labelSelector=app=web] in kustomization.yaml matches no resource",
+			},
+		},
+		{
+			description: "patches without target",
+			kustomization: `patches:
+- path: replicas.yaml`,
+			rendered: deploymentYAML,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.NewTempDir().
+				Write("kustomization.yaml", test.kustomization).
+				Chdir()
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+			t.Override(&util.DefaultExecCommand, testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", test.rendered))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:    []string{"."},
+				CheckPatchTargets: true,
+			})
+			t.RequireNoError(err)
+
+			err = k.Render(context.Background(), &bytes.Buffer{}, nil, true, "")
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected, fakeWarner.Warnings)
+		})
+	}
+}
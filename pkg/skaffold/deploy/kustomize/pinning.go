@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/output"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// floatingRefs are the refs that follow the latest commit of a repository rather than pin one.
+var floatingRefs = map[string]bool{
+	"HEAD":   true,
+	"main":   true,
+	"master": true,
+}
+
+// remoteBaseRef returns the ref a remote base points to and whether that ref pins its content.
+// Remote bases without a ref, or pointing to the default branches, are floating.
+func remoteBaseRef(target string) (string, bool) {
+	_, ref := parseRemoteBase(target)
+	return ref, !floatingRefs[ref]
+}
+
+// checkRemoteBasePins reports the refs the remote bases of a kustomization are pinned to,
+// and warns about the remote bases that are not pinned.
+func (k *Deployer) checkRemoteBasePins(out io.Writer, kustomizePath string) error {
+	var remotes []string
+	opts := k.dependencyOptions()
+	opts.remoteBase = func(target string) { remotes = append(remotes, target) }
+
+	if _, err := dependenciesForKustomization(kustomizePath, opts, 0); err != nil {
+		return fmt.Errorf("listing the remote bases of %s: %w", kustomizePath, err)
+	}
+
+	unique := util.NewStringSet()
+	unique.Insert(remotes...)
+	for _, remote := range unique.ToList() {
+		ref, pinned := remoteBaseRef(remote)
+		switch {
+		case pinned && k.ReportRemoteBaseRefs:
+			output.Default.Fprintf(out, "Remote base %s is pinned to %s\n", remote, ref)
+		case !pinned && k.WarnFloatingRemoteBases:
+			warnings.Printf("remote base %s is not pinned to a tag or commit, its content can change between deployments", remote)
+		case !pinned && k.ReportRemoteBaseRefs:
+			output.Default.Fprintf(out, "Remote base %s follows %s\n", remote, ref)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestRemoteBaseRef(t *testing.T) {
+	tests := []struct {
+		target         string
+		expectedRef    string
+		expectedPinned bool
+	}{
+		{target: "github.com/org/repo//base?ref=v1.2.3", expectedRef: "v1.2.3", expectedPinned: true},
+		{target: "https://github.com/org/repo.git//base?version=0d1f3b2", expectedRef: "0d1f3b2", expectedPinned: true},
+		{target: "github.com/org/repo/base", expectedRef: "HEAD"},
+		{target: "git@github.com:org/repo.git//base?ref=main", expectedRef: "main"},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.target, func(t *testutil.T) {
+			ref, pinned := remoteBaseRef(test.target)
+
+			t.CheckDeepEqual(test.expectedRef, ref)
+			t.CheckDeepEqual(test.expectedPinned, pinned)
+		})
+	}
+}
+
+func TestKustomizeRemoteBasePins(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.NewTempDir().
+			Write("kustomization.yaml", `resources:
+- github.com/org/repo//base?ref=v1.2.3
+- github.com/org/other/base
+- overlay`).
+			Write("overlay/kustomization.yaml", `resources:
+- github.com/org/repo//extra?ref=master`).
+			Chdir()
+		fakeWarner := &warnings.Collect{}
+		t.Override(&warnings.Printf, fakeWarner.Warnf)
+		t.Override(&util.DefaultExecCommand, testutil.CmdRunOut("kustomize build .", serviceYAML))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:          []string{"."},
+			ReportRemoteBaseRefs:    true,
+			WarnFloatingRemoteBases: true,
+		})
+		t.RequireNoError(err)
+
+		var out bytes.Buffer
+		_, err = k.readManifests(context.Background(), &out)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual("Remote base github.com/org/repo//base?ref=v1.2.3 is pinned to v1.2.3\n", out.String())
+		t.CheckDeepEqual([]string{
+			"remote base github.com/org/other/base is not pinned to a tag or commit, its content can change between deployments",
+			"remote base github.com/org/repo//extra?ref=master is not pinned to a tag or commit, its content can change between deployments",
+		}, fakeWarner.Warnings)
+	})
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+const (
+	pluginHomeEnv = "KUSTOMIZE_PLUGIN_HOME"
+
+	// builtinPluginAPIVersion is the apiVersion of the plugins compiled into kustomize.
+	builtinPluginAPIVersion = "builtin"
+)
+
+// validatePluginHome checks that the plugins found in the plugin home are laid out the way kustomize
+// looks them up: `<group>/<version>/<kind in lowercase>/<Kind>`, or `<version>/<kind in lowercase>/<Kind>`
+// for plugins without a group. Go plugins use the same layout, with a `.so` extension.
+func validatePluginHome(pluginHome string) error {
+	info, err := os.Stat(pluginHome)
+	if err != nil {
+		return fmt.Errorf("invalid pluginHome %q: %w", pluginHome, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid pluginHome %q: not a directory", pluginHome)
+	}
+
+	return filepath.Walk(pluginHome, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		// Only executables and Go plugins are loaded by kustomize.
+		kind := strings.TrimSuffix(info.Name(), ".so")
+		if kind == info.Name() && info.Mode()&0111 == 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(pluginHome, path)
+		if err != nil {
+			return err
+		}
+		depth := len(strings.Split(filepath.ToSlash(rel), "/"))
+		if (depth != 3 && depth != 4) || strings.ToLower(kind) != filepath.Base(filepath.Dir(path)) {
+			return fmt.Errorf("plugin %q is misplaced in pluginHome %q: plugins must be laid out as <group>/<version>/%s/%s", rel, pluginHome, strings.ToLower(kind), info.Name())
+		}
+		return nil
+	})
+}
+
+// validatePluginAllowlist checks that the allowed plugins are written `<apiVersion>/<kind>`.
+func validatePluginAllowlist(allowlist []string) error {
+	for _, plugin := range allowlist {
+		i := strings.LastIndex(plugin, "/")
+		if i <= 0 || i == len(plugin)-1 {
+			return fmt.Errorf("invalid pluginAllowlist entry %q: must be <apiVersion>/<kind>", plugin)
+		}
+	}
+	return nil
+}
+
+// checkPluginAllowlist fails if the kustomizations reference a plugin that is not allowed.
+// Plugin configs that can't be read are rejected too, since the plugins they use are unknown,
+// and so are remote bases, whose kustomizations can't be inspected.
+func (k *Deployer) checkPluginAllowlist(kustomizePath string) error {
+	allowed := util.NewStringSet()
+	allowed.Insert(k.PluginAllowlist...)
+
+	var disallowed, remotes []string
+	var firstErr error
+	opts := k.dependencyOptions()
+	opts.remoteBase = func(target string) {
+		remotes = append(remotes, target)
+	}
+	opts.plugin = func(kustomizationPath, dir, entry string) {
+		plugins, err := referencedPlugins(dir, entry)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("checking the plugins of %s: %w", kustomizationPath, err)
+			}
+			return
+		}
+		for _, plugin := range plugins {
+			if !strings.HasPrefix(plugin, builtinPluginAPIVersion+"/") && !allowed.Contains(plugin) {
+				disallowed = append(disallowed, fmt.Sprintf("%s (in %s)", plugin, kustomizationPath))
+			}
+		}
+	}
+
+	if _, err := dependenciesForKustomization(kustomizePath, opts, 0); err != nil {
+		return err
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if len(remotes) > 0 {
+		return fmt.Errorf("the plugins of remote bases can't be checked against pluginAllowlist: %s", strings.Join(remotes, ", "))
+	}
+	if len(disallowed) > 0 {
+		return fmt.Errorf("kustomize plugins not in pluginAllowlist: %s", strings.Join(disallowed, ", "))
+	}
+	return nil
+}
+
+// referencedPlugins returns the `<apiVersion>/<kind>` of the plugin configs of a generators, transformers or
+// validators entry. The entry is either an inline config or the path of a local file.
+func referencedPlugins(dir, entry string) ([]string, error) {
+	content := []byte(entry)
+	if !strings.Contains(entry, "\n") {
+		local, mode := pathExistsLocally(entry, dir)
+		switch {
+		case !local:
+			return nil, fmt.Errorf("plugin config %q is not a local file", entry)
+		case mode.IsDir():
+			return nil, fmt.Errorf("plugin configs of directory %q can't be checked", entry)
+		}
+
+		var err error
+		if content, err = ioutil.ReadFile(filepath.Join(dir, entry)); err != nil {
+			return nil, err
+		}
+	}
+
+	var plugins []string
+	decoder := yamlv3.NewDecoder(bytes.NewReader(content))
+	for {
+		var config struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+		}
+		err := decoder.Decode(&config)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading plugin config %q: %w", entry, err)
+		}
+		if config.APIVersion == "" && config.Kind == "" {
+			continue
+		}
+		plugins = append(plugins, config.APIVersion+"/"+config.Kind)
+	}
+	return plugins, nil
+}
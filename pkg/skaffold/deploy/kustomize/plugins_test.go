@@ -0,0 +1,191 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestValidatePluginHome(t *testing.T) {
+	tests := []struct {
+		description string
+		executables []string
+		files       []string
+		expectedErr string
+	}{
+		{
+			description: "exec and go plugins",
+			executables: []string{"example.com/v1/secretsfromdatabase/SecretsFromDatabase"},
+			files:       []string{"example.com/v1/valueadder/ValueAdder.so", "example.com/v1/valueadder/ValueAdder.go", "README.md"},
+		},
+		{
+			description: "plugin without group",
+			executables: []string{"v1/chartinflator/ChartInflator"},
+		},
+		{
+			description: "plugin at the root",
+			executables: []string{"SecretsFromDatabase"},
+			expectedErr: `plugin "SecretsFromDatabase" is misplaced`,
+		},
+		{
+			description: "kind directory not in lowercase",
+			files:       []string{"example.com/v1/ValueAdder/ValueAdder.so"},
+			expectedErr: `plugin "example.com/v1/ValueAdder/ValueAdder.so" is misplaced`,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir()
+			for _, file := range test.files {
+				tmpDir.Write(file, "")
+				t.CheckNoError(os.Chmod(tmpDir.Path(file), 0644))
+			}
+			for _, file := range test.executables {
+				tmpDir.Write(file, "")
+				t.CheckNoError(os.Chmod(tmpDir.Path(file), 0755))
+			}
+
+			err := validatePluginHome(tmpDir.Root())
+
+			if test.expectedErr == "" {
+				t.CheckNoError(err)
+			} else {
+				t.CheckErrorContains(test.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestKustomizePluginHome(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir().
+			Write("plugins/example.com/v1/valueadder/ValueAdder.so", "").
+			Chdir()
+		t.Override(&util.DefaultExecCommand, testutil.CmdRunOutEnv("kustomize build --enable-alpha-plugins .", serviceYAML,
+			[]string{"KUSTOMIZE_PLUGIN_HOME=" + tmpDir.Path("plugins")}))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+			BuildArgs:      []string{"--enable-alpha-plugins"},
+			PluginHome:     "plugins",
+		})
+		t.RequireNoError(err)
+
+		_, err = k.readManifests(context.Background(), ioutil.Discard)
+		t.CheckNoError(err)
+	})
+}
+
+func TestInvalidPluginHome(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		_, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			PluginHome: "does-not-exist",
+		})
+
+		t.CheckErrorContains(`invalid pluginHome "does-not-exist"`, err)
+	})
+}
+
+func TestKustomizePluginAllowlist(t *testing.T) {
+	const sedTransformer = `apiVersion: someteam.example.com/v1
+kind: SedTransformer
+metadata:
+  name: sed
+argsOneLiner: s/dev/prod/g`
+
+	tests := []struct {
+		description   string
+		kustomization string
+		shouldErr     bool
+	}{
+		{
+			description: "allowed plugins",
+			kustomization: `resources: [deployment.yaml]
+transformers: [sed.yaml]
+generators:
+- |-
+  apiVersion: builtin
+  kind: ConfigMapGenerator
+  metadata:
+    name: settings`,
+		},
+		{
+			description: "disallowed plugin",
+			kustomization: `generators:
+- |-
+  apiVersion: someteam.example.com/v1
+  kind: SecretsFromVault
+  metadata:
+    name: creds`,
+			shouldErr: true,
+		},
+		{
+			description:   "disallowed plugin in a base",
+			kustomization: `resources: [base]`,
+			shouldErr:     true,
+		},
+		{
+			description: "remote base can't be checked",
+			kustomization: `resources:
+- deployment.yaml
+- github.com/example/platform//base?ref=v1`,
+			shouldErr: true,
+		},
+		{
+			description:   "missing plugin config",
+			kustomization: `validators: [missing.yaml]`,
+			shouldErr:     true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.NewTempDir().
+				Write("kustomization.yaml", test.kustomization).
+				Write("sed.yaml", sedTransformer).
+				Write("base/kustomization.yaml", "validators: [kubeval.yaml]").
+				Write("base/kubeval.yaml", "apiVersion: untrusted.example.com/v1\nkind: Kubeval").
+				Chdir()
+			t.Override(&util.DefaultExecCommand, testutil.CmdRunOut("kustomize build .", serviceYAML))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:  []string{"."},
+				PluginAllowlist: []string{"someteam.example.com/v1/SedTransformer"},
+			})
+			t.RequireNoError(err)
+
+			_, err = k.readManifests(context.Background(), ioutil.Discard)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestValidatePluginAllowlist(t *testing.T) {
+	testutil.CheckError(t, false, validatePluginAllowlist([]string{"someteam.example.com/v1/SedTransformer", "v1/Widget"}))
+	testutil.CheckError(t, true, validatePluginAllowlist([]string{"SedTransformer"}))
+	testutil.CheckError(t, true, validatePluginAllowlist([]string{"someteam.example.com/v1/"}))
+}
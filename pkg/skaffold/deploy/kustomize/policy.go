@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+const defaultPolicyBinary = "conftest"
+
+// checkPolicies runs the rendered manifests through conftest and fails on policy violations.
+// The checks are skipped when the policies or the binary are missing.
+func (k *Deployer) checkPolicies(ctx context.Context, manifests manifest.ManifestList) error {
+	binary := k.PolicyBinary
+	if binary == "" {
+		binary = defaultPolicyBinary
+	}
+
+	if !util.IsDir(k.PolicyPath) {
+		warnings.Printf("policy directory %q not found, skipping the policy checks", k.PolicyPath)
+		return nil
+	}
+	if _, err := lookPath(binary); err != nil {
+		warnings.Printf("%s not found in PATH, skipping the policy checks", binary)
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, binary, "test", "--no-color", "--policy", k.PolicyPath, "-")
+	cmd.Stdin = manifests.Reader()
+	out, err := util.RunCmdOut(cmd)
+	if err == nil {
+		return nil
+	}
+
+	violations := policyViolations(out)
+	if len(violations) == 0 {
+		return userErr(fmt.Errorf("checking the policies of %s: %w", k.PolicyPath, err))
+	}
+	return userErr(fmt.Errorf("rendered manifests violate the policies of %s:\n%s", k.PolicyPath, strings.Join(violations, "\n")))
+}
+
+// policyViolations extracts the failures reported by conftest, such as
+// `FAIL - - main - Deployment web must set resource limits`.
+func policyViolations(out []byte) []string {
+	var violations []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "FAIL - ") || strings.HasPrefix(line, "ERROR - ") {
+			violations = append(violations, " - "+line)
+		}
+	}
+	return violations
+}
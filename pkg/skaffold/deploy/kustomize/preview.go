@@ -0,0 +1,258 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+const serverSideFlag = "--server-side"
+
+// volatileMetadata are the metadata fields maintained by the API server, which are left out of the preview.
+var volatileMetadata = []string{"managedFields", "resourceVersion", "generation", "uid", "creationTimestamp", "selfLink"}
+
+// previewServerSideApply shows how a server-side apply would change the live resources.
+// The predicted resources come from `kubectl apply --server-side --dry-run=server`, so that
+// defaulting, admission and field ownership are taken into account, unlike a client-side diff.
+// The apply flags are expected to already hold `--server-side`.
+func (k *Deployer) previewServerSideApply(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	args := append(append([]string{}, k.kubectl.Flags.Global...), k.kubectl.Flags.Apply...)
+	predicted, err := k.kubectl.RunOutInputWithPrefix(ctx, manifests.Reader(), "apply", append(args, "--dry-run=server", "-o", "yaml", "-f", "-")...)
+	if err != nil {
+		return userErr(fmt.Errorf("server-side apply dry-run: %w", err))
+	}
+
+	live, err := k.kubectl.RunOutInput(ctx, manifests.Reader(), "get", append(append([]string{}, k.kubectl.Flags.Global...), "--ignore-not-found", "-o", "yaml", "-f", "-")...)
+	if err != nil {
+		return userErr(fmt.Errorf("getting live resources: %w", err))
+	}
+
+	predictedObjects, err := parseObjects(predicted)
+	if err != nil {
+		return err
+	}
+	liveObjects, err := parseObjects(live)
+	if err != nil {
+		return err
+	}
+
+	liveByID := map[string]map[string]interface{}{}
+	for _, obj := range liveObjects {
+		liveByID[objectKey(obj)] = obj
+	}
+
+	for _, obj := range predictedObjects {
+		liveObj, found := liveByID[objectKey(obj)]
+		maskSecretData(liveObj, obj)
+
+		after, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+
+		id := objectResource(obj).kubectlID()
+		if !found {
+			printPrefixedLines(out, fmt.Sprintf("+++ %s (new)", id), "+ ", string(after))
+			continue
+		}
+
+		before, err := yaml.Marshal(liveObj)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(before, after) {
+			continue
+		}
+
+		fmt.Fprintf(out, "--- %s (live)\n+++ %s (server-side dry-run)\n", id, id)
+		for _, line := range diffLines(string(before), string(after)) {
+			fmt.Fprintln(out, line)
+		}
+	}
+
+	return nil
+}
+
+// printPrefixedLines prints a header followed by each line of a text, with the given prefix.
+func printPrefixedLines(out io.Writer, header, prefix, text string) {
+	fmt.Fprintln(out, header)
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		fmt.Fprintf(out, "%s%s\n", prefix, line)
+	}
+}
+
+// parseObjects reads the resources printed by kubectl, either as a YAML stream or as a `List`.
+// The fields maintained by the API server are removed.
+func parseObjects(buf []byte) ([]map[string]interface{}, error) {
+	var objects []map[string]interface{}
+
+	decoder := yamlv3.NewDecoder(bytes.NewReader(buf))
+	for {
+		obj := map[string]interface{}{}
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+
+		if obj["kind"] != "List" {
+			if len(obj) > 0 {
+				objects = append(objects, normalizeObject(obj))
+			}
+			continue
+		}
+
+		items, _ := obj["items"].([]interface{})
+		for _, item := range items {
+			if itemObj, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, normalizeObject(itemObj))
+			}
+		}
+	}
+}
+
+// normalizeObject removes the status and the metadata fields maintained by the API server.
+func normalizeObject(obj map[string]interface{}) map[string]interface{} {
+	delete(obj, "status")
+
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return obj
+	}
+	for _, field := range volatileMetadata {
+		delete(metadata, field)
+	}
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+		if len(annotations) == 0 {
+			delete(metadata, "annotations")
+		}
+	}
+	return obj
+}
+
+// maskSecretData replaces the values of the `data` and `stringData` of Secrets, so that they are never printed.
+// Like `kubectl diff`, values that differ between the live and the predicted Secret are
+// masked as `*** (before)` and `*** (after)`, and all the other values as `***`.
+// The live object is nil for new resources.
+func maskSecretData(live, predicted map[string]interface{}) {
+	if predicted["kind"] != "Secret" {
+		return
+	}
+
+	for _, field := range []string{"data", "stringData"} {
+		liveValues, _ := live[field].(map[string]interface{})
+		predictedValues, _ := predicted[field].(map[string]interface{})
+
+		for key, value := range liveValues {
+			predictedValue, found := predictedValues[key]
+			if found && !reflect.DeepEqual(predictedValue, value) {
+				liveValues[key] = "*** (before)"
+				predictedValues[key] = "*** (after)"
+			} else {
+				liveValues[key] = "***"
+			}
+		}
+		for key, value := range predictedValues {
+			if value != "*** (after)" {
+				predictedValues[key] = "***"
+			}
+		}
+	}
+}
+
+// objectResource reads the identifying fields of a parsed object.
+func objectResource(obj map[string]interface{}) resource {
+	var r resource
+	r.APIVersion, _ = obj["apiVersion"].(string)
+	r.Kind, _ = obj["kind"].(string)
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		r.Metadata.Name, _ = metadata["name"].(string)
+		r.Metadata.Namespace, _ = metadata["namespace"].(string)
+	}
+	return r
+}
+
+// objectKey uniquely identifies a parsed object.
+func objectKey(obj map[string]interface{}) string {
+	r := objectResource(obj)
+	return r.Metadata.Namespace + "/" + r.kubectlID()
+}
+
+// diffLines returns a line by line diff of two texts. Removed lines are prefixed with `-`,
+// added lines with `+` and unchanged lines with a space.
+func diffLines(before, after string) []string {
+	a := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(after, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	return lines
+}
+
+// hasFlag checks whether a flag is part of the given args, either as `--flag` or `--flag=value`.
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
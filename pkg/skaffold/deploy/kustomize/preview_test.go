@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeServerSidePreview(t *testing.T) {
+	dryRun := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  managedFields:
+  - manager: kubectl
+    operation: Apply
+  name: web
+  namespace: testNamespace
+  resourceVersion: "42"
+spec:
+  replicas: 2
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: testNamespace
+`
+	live := `apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    managedFields:
+    - manager: kubectl
+      operation: Apply
+    name: web
+    namespace: testNamespace
+    resourceVersion: "41"
+  spec:
+    replicas: 1
+  status:
+    readyReplicas: 1
+`
+
+	testutil.Run(t, "", func(t *testutil.T) {
+		manifests := serviceYAML + "\n---\n" + deploymentYAML
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", manifests).
+			AndRunInputOut("kubectl --context kubecontext --namespace testNamespace apply --server-side --dry-run=server -o yaml -f -", manifests, dryRun).
+			AndRunInputOut("kubectl --context kubecontext --namespace testNamespace get --ignore-not-found -o yaml -f -", manifests, live).
+			AndRunInput("kubectl --context kubecontext --namespace testNamespace apply --server-side -f -", manifests))
+		t.Override(&client.Client, deployutil.MockK8sClient)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:    []string{"."},
+			ServerSidePreview: true,
+		})
+		t.RequireNoError(err)
+
+		var out bytes.Buffer
+		err = k.Deploy(context.Background(), &out, nil)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual(` - --- deployment.apps/web (live)
+ - +++ deployment.apps/web (server-side dry-run)
+ -   apiVersion: apps/v1
+ -   kind: Deployment
+ -   metadata:
+ -     name: web
+ -     namespace: testNamespace
+ -   spec:
+ - -   replicas: 1
+ - +   replicas: 2
+ - +++ service/web (new)
+ - + apiVersion: v1
+ - + kind: Service
+ - + metadata:
+ - +   name: web
+ - +   namespace: testNamespace
+`, out.String())
+	})
+}
+
+func TestKustomizeServerSidePreviewSecrets(t *testing.T) {
+	secrets := `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+stringData:
+  password: new-password
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: api
+stringData:
+  token: api-token`
+	dryRun := `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: testNamespace
+data:
+  password: bmV3LXBhc3N3b3Jk
+  user: YWRtaW4=
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: api
+  namespace: testNamespace
+data:
+  token: YXBpLXRva2Vu
+`
+	live := `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: testNamespace
+data:
+  password: b2xkLXBhc3N3b3Jk
+  user: YWRtaW4=
+`
+
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", secrets).
+			AndRunInputOut("kubectl --context kubecontext --namespace testNamespace apply --server-side --dry-run=server -o yaml -f -", secrets, dryRun).
+			AndRunInputOut("kubectl --context kubecontext --namespace testNamespace get --ignore-not-found -o yaml -f -", secrets, live).
+			AndRunInput("kubectl --context kubecontext --namespace testNamespace apply --server-side -f -", secrets))
+		t.Override(&client.Client, deployutil.MockK8sClient)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:    []string{"."},
+			ServerSidePreview: true,
+		})
+		t.RequireNoError(err)
+
+		var out bytes.Buffer
+		err = k.Deploy(context.Background(), &out, nil)
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual(` - --- secret/db (live)
+ - +++ secret/db (server-side dry-run)
+ -   apiVersion: v1
+ -   data:
+ - -   password: '*** (before)'
+ - +   password: '*** (after)'
+ -     user: '***'
+ -   kind: Secret
+ -   metadata:
+ -     name: db
+ -     namespace: testNamespace
+ - +++ secret/api (new)
+ - + apiVersion: v1
+ - + data:
+ - +   token: '***'
+ - + kind: Secret
+ - + metadata:
+ - +   name: api
+ - +   namespace: testNamespace
+`, out.String())
+		for _, value := range []string{"bmV3LXBhc3N3b3Jk", "b2xkLXBhc3N3b3Jk", "YWRtaW4=", "YXBpLXRva2Vu"} {
+			testutil.CheckNotContains(t.T, value, out.String())
+		}
+	})
+}
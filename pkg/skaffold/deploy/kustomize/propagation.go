@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+// propagationPolicies are the values of kubectl's `--cascade` flag.
+var propagationPolicies = map[string]bool{
+	"background": true,
+	"foreground": true,
+	"orphan":     true,
+}
+
+// kindsWithoutDependents are the built-in kinds that never own other resources,
+// so a propagation policy has no effect on them.
+var kindsWithoutDependents = map[string]bool{
+	"ConfigMap":             true,
+	"Secret":                true,
+	"Service":               true,
+	"ServiceAccount":        true,
+	"Pod":                   true,
+	"PersistentVolumeClaim": true,
+	"Ingress":               true,
+	"NetworkPolicy":         true,
+	"Role":                  true,
+	"RoleBinding":           true,
+	"ClusterRole":           true,
+	"ClusterRoleBinding":    true,
+}
+
+// validatePropagationPolicies checks the propagation policy of each kind.
+func validatePropagationPolicies(policies map[string]string) error {
+	var kinds []string
+	for kind := range policies {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		switch {
+		case kind == "":
+			return fmt.Errorf("invalid propagationPolicies: kind can't be empty")
+		case kindsWithoutDependents[kind]:
+			return fmt.Errorf("invalid propagationPolicies: %s resources have no dependents to propagate to", kind)
+		case !propagationPolicies[policies[kind]]:
+			return fmt.Errorf("invalid propagationPolicies %q: policy must be `background`, `foreground` or `orphan`, not %q", kind, policies[kind])
+		}
+	}
+	return nil
+}
+
+// splitByPropagationPolicy separates the resources of the kinds that have a propagation policy, grouped by policy,
+// from the others. The policies are returned in sorted order.
+func splitByPropagationPolicy(manifests manifest.ManifestList, policies map[string]string) (manifest.ManifestList, []string, map[string]manifest.ManifestList, error) {
+	var others manifest.ManifestList
+	groups := map[string]manifest.ManifestList{}
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if policy, found := policies[r.Kind]; found {
+			groups[policy] = append(groups[policy], m)
+		} else {
+			others = append(others, m)
+		}
+	}
+
+	var order []string
+	for policy := range groups {
+		order = append(order, policy)
+	}
+	sort.Strings(order)
+	return others, order, groups, nil
+}
+
+// applyWithPropagationPolicies applies the resources without a propagation policy first, then the resources of
+// each policy with their own `kubectl apply --cascade`.
+func (k *Deployer) applyWithPropagationPolicies(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	others, order, groups, err := splitByPropagationPolicy(manifests, k.PropagationPolicies)
+	if err != nil {
+		return err
+	}
+
+	// Each group is compared to the manifests applied by the previous deploy.
+	previous := k.kubectl
+
+	if len(others) > 0 {
+		if err := k.applyManifests(ctx, out, others); err != nil {
+			return err
+		}
+	}
+
+	for _, policy := range order {
+		cli := previous
+		cli.Flags.Apply = append(append([]string{}, cli.Flags.Apply...), "--cascade="+policy)
+		if err := k.kubectlApplyWith(ctx, &cli, out, groups[policy]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
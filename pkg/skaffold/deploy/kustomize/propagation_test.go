@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeDeployPropagationPolicies(t *testing.T) {
+	const statefulSetYAML = `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db`
+
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", statefulSetYAML+"\n---\n"+serviceYAML+"\n---\n"+deploymentYAML).
+			AndRunInput(applyCommand, serviceYAML).
+			AndRunInput("kubectl --context kubecontext --namespace testNamespace apply --cascade=foreground -f -", deploymentYAML).
+			AndRunInput("kubectl --context kubecontext --namespace testNamespace apply --cascade=orphan -f -", statefulSetYAML))
+		t.Override(&client.Client, deployutil.MockK8sClient)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:      []string{"."},
+			PropagationPolicies: map[string]string{"StatefulSet": "orphan", "Deployment": "foreground"},
+		})
+		t.RequireNoError(err)
+
+		err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+		t.CheckNoError(err)
+	})
+}
+
+func TestValidatePropagationPolicies(t *testing.T) {
+	testutil.CheckError(t, false, validatePropagationPolicies(nil))
+	testutil.CheckError(t, false, validatePropagationPolicies(map[string]string{"Deployment": "orphan", "Widget": "background"}))
+	testutil.CheckError(t, true, validatePropagationPolicies(map[string]string{"ConfigMap": "orphan"}))
+	testutil.CheckError(t, true, validatePropagationPolicies(map[string]string{"Deployment": "Orphan"}))
+	testutil.CheckError(t, true, validatePropagationPolicies(map[string]string{"": "orphan"}))
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+)
+
+// validatePrune checks the prune options. Pruning deletes the resources that are not part of the applied
+// manifests, so it's only allowed when all the resources are applied with a single `kubectl apply`.
+func validatePrune(d *latestV1.KustomizeDeploy) error {
+	if !d.Prune {
+		if len(d.PruneAllowlist) > 0 {
+			return fmt.Errorf("pruneAllowlist is set but prune is not enabled")
+		}
+		return nil
+	}
+
+	switch {
+	case d.ApplyConcurrency > 1:
+		return fmt.Errorf("prune can't be combined with applyConcurrency %d", d.ApplyConcurrency)
+	case d.ApplyByKind:
+		return fmt.Errorf("prune can't be combined with applyByKind")
+	case d.ApplyByDependencies:
+		return fmt.Errorf("prune can't be combined with applyByDependencies")
+	case d.WaitForCRDs:
+		return fmt.Errorf("prune can't be combined with waitForCRDs")
+	case d.OnlyNewResources:
+		return fmt.Errorf("prune can't be combined with onlyNewResources")
+	case len(d.PropagationPolicies) > 0:
+		return fmt.Errorf("prune can't be combined with propagationPolicies")
+	}
+
+	for _, gvk := range d.PruneAllowlist {
+		if err := validatePruneType(gvk); err != nil {
+			return fmt.Errorf("invalid pruneAllowlist entry %q: %w", gvk, err)
+		}
+	}
+	return nil
+}
+
+// validatePruneType checks a `group/version/Kind` entry, where the core group is written `core`.
+func validatePruneType(gvk string) error {
+	parts := strings.Split(gvk, "/")
+	if len(parts) != 3 {
+		return fmt.Errorf("must be group/version/Kind, such as apps/v1/Deployment or core/v1/ConfigMap")
+	}
+	for _, part := range parts {
+		if part == "" || strings.ContainsAny(part, " \t") {
+			return fmt.Errorf("must be group/version/Kind, such as apps/v1/Deployment or core/v1/ConfigMap")
+		}
+	}
+	if !unicode.IsUpper([]rune(parts[2])[0]) {
+		return fmt.Errorf("kind %q must start with an uppercase letter", parts[2])
+	}
+	return nil
+}
+
+// pruneFlags returns the `kubectl apply` flags that prune the resources of the current run.
+func pruneFlags(selector string, allowlist []string) []string {
+	flags := []string{"--prune", "--selector=" + selector}
+	for _, gvk := range allowlist {
+		flags = append(flags, "--prune-allowlist="+gvk)
+	}
+	return flags
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizePrune(t *testing.T) {
+	const pruneApplyCommand = "kubectl --context kubecontext --namespace testNamespace apply --prune --selector=skaffold.dev/run-id=run-id --prune-allowlist=apps/v1/Deployment --prune-allowlist=core/v1/Service -f -"
+
+	testutil.Run(t, "", func(t *testutil.T) {
+		// The unchanged manifests are applied again, otherwise they would be pruned.
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", deploymentYAML).
+			AndRun(pruneApplyCommand).
+			AndRunOut("kustomize build .", deploymentYAML).
+			AndRun(pruneApplyCommand))
+		t.Override(&client.Client, deployutil.MockK8sClient)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, label.NewLabeller(true, nil, "run-id"), &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+			Prune:          true,
+			PruneAllowlist: []string{"apps/v1/Deployment", "core/v1/Service"},
+		})
+		t.RequireNoError(err)
+
+		t.CheckNoError(k.Deploy(context.Background(), ioutil.Discard, nil))
+		t.CheckNoError(k.Deploy(context.Background(), ioutil.Discard, nil))
+	})
+}
+
+func TestKustomizePruneValidation(t *testing.T) {
+	tests := []struct {
+		description string
+		labeller    *label.DefaultLabeller
+		config      latestV1.KustomizeDeploy
+		shouldErr   bool
+	}{
+		{
+			description: "valid allowlist",
+			config:      latestV1.KustomizeDeploy{Prune: true, PruneAllowlist: []string{"apps/v1/Deployment", "core/v1/ConfigMap", "example.com/v1alpha1/Widget"}},
+		},
+		{
+			description: "allowlist without prune",
+			config:      latestV1.KustomizeDeploy{PruneAllowlist: []string{"apps/v1/Deployment"}},
+			shouldErr:   true,
+		},
+		{
+			description: "missing group",
+			config:      latestV1.KustomizeDeploy{Prune: true, PruneAllowlist: []string{"v1/ConfigMap"}},
+			shouldErr:   true,
+		},
+		{
+			description: "empty version",
+			config:      latestV1.KustomizeDeploy{Prune: true, PruneAllowlist: []string{"apps//Deployment"}},
+			shouldErr:   true,
+		},
+		{
+			description: "lowercase kind",
+			config:      latestV1.KustomizeDeploy{Prune: true, PruneAllowlist: []string{"apps/v1/deployments"}},
+			shouldErr:   true,
+		},
+		{
+			description: "combined with applyByKind",
+			config:      latestV1.KustomizeDeploy{Prune: true, ApplyByKind: true},
+			shouldErr:   true,
+		},
+		{
+			description: "combined with propagationPolicies",
+			config:      latestV1.KustomizeDeploy{Prune: true, PropagationPolicies: map[string]string{"Deployment": "orphan"}},
+			shouldErr:   true,
+		},
+		{
+			description: "without skaffold labels",
+			labeller:    label.NewLabeller(false, nil, "run-id"),
+			config:      latestV1.KustomizeDeploy{Prune: true},
+			shouldErr:   true,
+		},
+		{
+			description: "run id label kept without being allowlisted",
+			config:      latestV1.KustomizeDeploy{Prune: true, LabelAllowlist: []string{label.K8sManagedByLabelKey}},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			labeller := test.labeller
+			if labeller == nil {
+				labeller = label.NewLabeller(true, nil, "run-id")
+			}
+
+			_, err := NewDeployer(&kustomizeConfig{}, labeller, &test.config)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+// checkQualifiedImages fails if an image of the manifests doesn't name its registry, or has neither a tag nor a digest.
+// Images listed in the allowlist, either with or without their tag, are accepted as they are.
+func checkQualifiedImages(manifests manifest.ManifestList, allowlist []string) error {
+	images, err := manifests.GetImages()
+	if err != nil {
+		return err
+	}
+
+	allowed := util.NewStringSet()
+	allowed.Insert(allowlist...)
+
+	var unqualified []string
+	for _, image := range images {
+		if allowed.Contains(image.Tag) || allowed.Contains(image.ImageName) {
+			continue
+		}
+
+		parsed, err := docker.ParseReference(image.Tag)
+		if err != nil {
+			return err
+		}
+
+		var missing []string
+		if !hasRegistry(parsed.BaseName) {
+			missing = append(missing, "registry")
+		}
+		if parsed.Tag == "" && parsed.Digest == "" {
+			missing = append(missing, "tag or digest")
+		}
+		if len(missing) > 0 {
+			unqualified = append(unqualified, fmt.Sprintf("%s (no %s)", image.Tag, strings.Join(missing, ", no ")))
+		}
+	}
+
+	if len(unqualified) > 0 {
+		return userErr(fmt.Errorf("image references are not fully qualified: %s", strings.Join(unqualified, ", ")))
+	}
+	return nil
+}
+
+// hasRegistry checks whether an image name starts with a registry host, e.g. `gcr.io/project/app`.
+func hasRegistry(name string) bool {
+	i := strings.Index(name, "/")
+	if i == -1 {
+		return false
+	}
+	host := name[:i]
+	return strings.ContainsAny(host, ".:") || host == "localhost"
+}
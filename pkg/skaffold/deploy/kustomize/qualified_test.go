@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestCheckQualifiedImages(t *testing.T) {
+	podWith := func(image string) []byte {
+		return []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - image: ` + image + `
+    name: app`)
+	}
+
+	tests := []struct {
+		description string
+		images      []string
+		allowlist   []string
+		expectedErr string
+	}{
+		{
+			description: "qualified images",
+			images:      []string{"gcr.io/project/app:v1", "localhost:5000/app@sha256:" + "0123456789012345678901234567890123456789012345678901234567890123", "localhost/app:dev"},
+		},
+		{
+			description: "no registry",
+			images:      []string{"library/nginx:1.21"},
+			expectedErr: "library/nginx:1.21 (no registry)",
+		},
+		{
+			description: "no registry nor tag",
+			images:      []string{"gcr.io/project/app:v1", "nginx"},
+			expectedErr: "nginx (no registry, no tag or digest)",
+		},
+		{
+			description: "no tag",
+			images:      []string{"gcr.io/project/app"},
+			expectedErr: "gcr.io/project/app (no tag or digest)",
+		},
+		{
+			description: "allowlist",
+			images:      []string{"busybox", "redis:6"},
+			allowlist:   []string{"busybox", "redis:6"},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			var manifests manifest.ManifestList
+			for _, image := range test.images {
+				manifests = append(manifests, podWith(image))
+			}
+
+			err := checkQualifiedImages(manifests, test.allowlist)
+
+			if test.expectedErr == "" {
+				t.CheckNoError(err)
+			} else {
+				t.CheckErrorContains(test.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+const defaultReadinessTimeout = 5 * time.Minute
+
+// validateReadiness checks the readiness command and its timeout.
+func validateReadiness(command []string, timeout string) error {
+	if len(command) == 0 {
+		if timeout != "" {
+			return fmt.Errorf("readinessTimeout is set but readinessCommand is not")
+		}
+		return nil
+	}
+
+	if command[0] == "" {
+		return fmt.Errorf("invalid readinessCommand: the command can't be empty")
+	}
+	if timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid readinessTimeout %q, must be a positive duration", timeout)
+		}
+	}
+	return nil
+}
+
+// checkReadiness runs the readiness command with the deployed resources as arguments, streaming its output.
+func (k *Deployer) checkReadiness(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	timeout := defaultReadinessTimeout
+	if k.ReadinessTimeout != "" {
+		// Validated when the deployer is created.
+		timeout, _ = time.ParseDuration(k.ReadinessTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	args := append([]string{}, k.ReadinessCommand[1:]...)
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+
+		namespace := r.Metadata.Namespace
+		if namespace == "" {
+			namespace = k.kubectl.Namespace
+		}
+		args = append(args, namespace+"/"+r.kubectlID())
+	}
+
+	cmd := exec.CommandContext(ctx, k.ReadinessCommand[0], args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := util.RunCmd(cmd); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return userErr(fmt.Errorf("readiness command didn't succeed within %v", timeout))
+		}
+		return userErr(fmt.Errorf("readiness command failed: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeDeployReadinessCommand(t *testing.T) {
+	const probeCommand = "./probe.sh --strict testNamespace/service/web other/deployment.apps/web"
+
+	tests := []struct {
+		description string
+		commands    util.Command
+		expectedOut string
+		shouldErr   bool
+	}{
+		{
+			description: "probe passes",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", serviceYAML+"\n---\n"+deploymentYAML+"\n  namespace: other").
+				AndRun(applyCommand).
+				AndRunWithOutput(probeCommand, "all checks passed\n"),
+			expectedOut: " - all checks passed\n",
+		},
+		{
+			description: "probe fails",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", serviceYAML+"\n---\n"+deploymentYAML+"\n  namespace: other").
+				AndRun(applyCommand).
+				AndRunErr(probeCommand, errors.New("exit status 1")),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:   []string{"."},
+				ReadinessCommand: []string{"./probe.sh", "--strict"},
+				ReadinessTimeout: "30s",
+			})
+			t.RequireNoError(err)
+
+			var out bytes.Buffer
+			err = k.Deploy(context.Background(), &out, nil)
+
+			t.CheckError(test.shouldErr, err)
+			if !test.shouldErr {
+				t.CheckDeepEqual(test.expectedOut, out.String())
+			}
+		})
+	}
+}
+
+func TestValidateReadiness(t *testing.T) {
+	testutil.CheckError(t, false, validateReadiness(nil, ""))
+	testutil.CheckError(t, false, validateReadiness([]string{"./probe.sh"}, "2m"))
+	testutil.CheckError(t, true, validateReadiness(nil, "2m"))
+	testutil.CheckError(t, true, validateReadiness([]string{""}, ""))
+	testutil.CheckError(t, true, validateReadiness([]string{"./probe.sh"}, "-1s"))
+}
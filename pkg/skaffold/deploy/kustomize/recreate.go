@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+var (
+	// recreatableKinds are the kinds that are safe to delete and create again when a field that
+	// can't be updated in place changes, since they hold no state that would be lost.
+	recreatableKinds = map[string]bool{
+		"ConfigMap": true,
+		"Secret":    true,
+		"Job":       true,
+	}
+
+	// immutableFieldPattern matches the errors reported by `kubectl apply` when an immutable field changes, e.g.
+	// `The Job "migrate" is invalid: spec.template: Invalid value: ...: field is immutable`.
+	immutableFieldPattern = regexp.MustCompile(`The (\S+) "([^"]+)" is invalid: .*field is immutable`)
+)
+
+// immutableConflicts returns the resources that failed to apply because one of their immutable fields changed.
+// Only resources of the recreatable kinds are returned: the others can't be fixed by recreating them.
+func immutableConflicts(manifests manifest.ManifestList, applyErr error, output string) ([]resource, error) {
+	conflicting := map[string]bool{}
+	for _, match := range immutableFieldPattern.FindAllStringSubmatch(applyErr.Error()+"\n"+output, -1) {
+		conflicting[match[1]+"/"+match[2]] = true
+	}
+	if len(conflicting) == 0 {
+		return nil, nil
+	}
+
+	var conflicts []resource
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+		if conflicting[r.Kind+"/"+r.Metadata.Name] && recreatableKinds[r.Kind] {
+			conflicts = append(conflicts, r)
+		}
+	}
+	return conflicts, nil
+}
+
+// deleteForRecreate deletes the given resources and waits for them to be gone, so that they can be created again.
+func deleteForRecreate(ctx context.Context, cli *kubectl.CLI, out io.Writer, resources []resource) error {
+	for _, r := range resources {
+		fmt.Fprintf(out, "recreating %s: an immutable field changed\n", r)
+		if err := cli.RunInNamespace(ctx, nil, out, "delete", r.Metadata.Namespace, r.kubectlID(), "--ignore-not-found", "--wait"); err != nil {
+			return userErr(fmt.Errorf("deleting %s to recreate it: %w", r, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeRecreateImmutable(t *testing.T) {
+	const jobYAML = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate`
+	immutableJobErr := errors.New(`The Job "migrate" is invalid: spec.template: Invalid value: core.PodTemplateSpec{}: field is immutable`)
+	immutableDeploymentErr := errors.New(`The Deployment "web" is invalid: spec.selector: Invalid value: v1.LabelSelector{}: field is immutable`)
+
+	tests := []struct {
+		description string
+		recreate    bool
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "job recreated",
+			recreate:    true,
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", jobYAML+"\n---\n"+serviceYAML).
+				AndRunErr(applyCommand, immutableJobErr).
+				AndRun("kubectl --context kubecontext --namespace testNamespace delete job.batch/migrate --ignore-not-found --wait").
+				AndRun(applyCommand),
+		},
+		{
+			description: "recreated only once",
+			recreate:    true,
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", jobYAML).
+				AndRunErr(applyCommand, immutableJobErr).
+				AndRun("kubectl --context kubecontext --namespace testNamespace delete job.batch/migrate --ignore-not-found --wait").
+				AndRunErr(applyCommand, immutableJobErr),
+			shouldErr: true,
+		},
+		{
+			description: "deployments are never recreated",
+			recreate:    true,
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRunErr(applyCommand, immutableDeploymentErr),
+			shouldErr: true,
+		},
+		{
+			description: "not recreated by default",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", jobYAML).
+				AndRunErr(applyCommand, immutableJobErr),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:    []string{"."},
+				RecreateImmutable: test.recreate,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
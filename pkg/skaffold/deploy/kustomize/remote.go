@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+const defaultRemoteBasesPollInterval = time.Minute
+
+// isRemoteBase checks whether a base or resource of a kustomization points to a git repository.
+func isRemoteBase(target string) bool {
+	return strings.Contains(target, "://") ||
+		strings.HasPrefix(target, "git@") ||
+		strings.HasPrefix(target, "github.com/") ||
+		strings.Contains(target, "?ref=")
+}
+
+// parseRemoteBase splits a remote target, e.g. `github.com/org/repo//overlays/dev?ref=v1`,
+// into the git repository and the ref it points to.
+func parseRemoteBase(target string) (string, string) {
+	ref := "HEAD"
+	if i := strings.Index(target, "?"); i >= 0 {
+		if query, err := url.ParseQuery(target[i+1:]); err == nil {
+			if r := query.Get("ref"); r != "" {
+				ref = r
+			} else if v := query.Get("version"); v != "" {
+				ref = v
+			}
+		}
+		target = target[:i]
+	}
+
+	scheme := ""
+	if i := strings.Index(target, "://"); i >= 0 {
+		scheme, target = target[:i+3], target[i+3:]
+	}
+
+	// The repository and the path inside the repository are separated by `//`.
+	if i := strings.Index(target, "//"); i >= 0 {
+		return scheme + target[:i], ref
+	}
+
+	if scheme == "" && !strings.HasPrefix(target, "git@") {
+		// Short form, e.g. `github.com/org/repo/path`.
+		if parts := strings.SplitN(target, "/", 4); len(parts) > 3 {
+			target = strings.Join(parts[:3], "/")
+		}
+		return "https://" + target, ref
+	}
+
+	return scheme + target, ref
+}
+
+// remoteBaseCommit returns the commit that a ref of a git repository currently points to.
+func remoteBaseCommit(repo, ref string) (string, error) {
+	out, err := util.RunCmdOut(exec.CommandContext(context.Background(), "git", "ls-remote", repo, ref))
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("ref %q not found in %s", ref, repo)
+	}
+	return fields[0], nil
+}
+
+// remoteBasesFingerprint returns a file that lists the commits the remote bases point to.
+// The commits are refreshed at most once per poll interval and the file is only rewritten
+// when one of them changes, so that the file watcher sees it change when a remote base is updated.
+func (k *Deployer) remoteBasesFingerprint(remotes []string) string {
+	interval := defaultRemoteBasesPollInterval
+	if k.RemoteBasesPollInterval != "" {
+		interval, _ = time.ParseDuration(k.RemoteBasesPollInterval)
+	}
+	if k.remoteBasesFile != "" && time.Since(k.remoteBasesChecked) < interval {
+		return k.remoteBasesFile
+	}
+	k.remoteBasesChecked = time.Now()
+
+	unique := util.NewStringSet()
+	unique.Insert(remotes...)
+
+	var fingerprint strings.Builder
+	for _, remote := range unique.ToList() {
+		commit, err := remoteBaseCommit(parseRemoteBase(remote))
+		if err != nil {
+			logrus.Warnf("unable to check remote base %q for updates: %v", remote, err)
+			return k.remoteBasesFile
+		}
+		fmt.Fprintf(&fingerprint, "%s %s\n", remote, commit)
+	}
+
+	if k.remoteBasesFile == "" {
+		f, err := ioutil.TempFile("", "skaffold-kustomize-remote-bases")
+		if err != nil {
+			logrus.Warnf("unable to track remote bases: %v", err)
+			return ""
+		}
+		f.Close()
+		k.remoteBasesFile = f.Name()
+	}
+
+	if current, err := ioutil.ReadFile(k.remoteBasesFile); err == nil && string(current) == fingerprint.String() {
+		return k.remoteBasesFile
+	}
+	if err := ioutil.WriteFile(k.remoteBasesFile, []byte(fingerprint.String()), 0644); err != nil {
+		logrus.Warnf("unable to track remote bases: %v", err)
+	}
+	return k.remoteBasesFile
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestParseRemoteBase(t *testing.T) {
+	tests := []struct {
+		description  string
+		target       string
+		expectedRepo string
+		expectedRef  string
+	}{
+		{
+			description:  "short form",
+			target:       "github.com/org/repo/deploy/base?ref=v1.0.0",
+			expectedRepo: "https://github.com/org/repo",
+			expectedRef:  "v1.0.0",
+		},
+		{
+			description:  "url with subdirectory",
+			target:       "https://github.com/org/repo.git//deploy/base?ref=main",
+			expectedRepo: "https://github.com/org/repo.git",
+			expectedRef:  "main",
+		},
+		{
+			description:  "ssh without ref",
+			target:       "git@github.com:org/repo.git//deploy",
+			expectedRepo: "git@github.com:org/repo.git",
+			expectedRef:  "HEAD",
+		},
+		{
+			description:  "version parameter",
+			target:       "ssh://git@example.com/repo?version=v2",
+			expectedRepo: "ssh://git@example.com/repo",
+			expectedRef:  "v2",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			repo, ref := parseRemoteBase(test.target)
+
+			t.CheckDeepEqual(test.expectedRepo, repo)
+			t.CheckDeepEqual(test.expectedRef, ref)
+		})
+	}
+}
+
+func TestWatchRemoteBases(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("git ls-remote https://github.com/org/repo v1", "1111111\trefs/tags/v1").
+			AndRunOut("git ls-remote https://github.com/org/repo v1", "2222222\trefs/tags/v1"))
+		tmpDir := t.NewTempDir().
+			Write("kustomization.yaml", `resources:
+- github.com/org/repo/base?ref=v1
+- pod.yaml`).
+			Write("pod.yaml", "")
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths:          []string{tmpDir.Root()},
+			WatchRemoteBases:        true,
+			RemoteBasesPollInterval: "1ns",
+		})
+		t.RequireNoError(err)
+
+		deps, err := k.Dependencies()
+		t.CheckNoError(err)
+		defer os.Remove(k.remoteBasesFile)
+		t.CheckDeepEqual(3, len(deps))
+		t.CheckTrue(util.StrSliceContains(deps, k.remoteBasesFile))
+
+		fingerprint, err := ioutil.ReadFile(k.remoteBasesFile)
+		t.CheckNoError(err)
+		t.CheckDeepEqual("github.com/org/repo/base?ref=v1 1111111\n", string(fingerprint))
+
+		_, err = k.Dependencies()
+		t.CheckNoError(err)
+
+		fingerprint, err = ioutil.ReadFile(k.remoteBasesFile)
+		t.CheckNoError(err)
+		t.CheckDeepEqual("github.com/org/repo/base?ref=v1 2222222\n", string(fingerprint))
+	})
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+const (
+	separatorBetween = "between"
+	separatorLeading = "leading"
+
+	lineEndingsLF   = "lf"
+	lineEndingsCRLF = "crlf"
+
+	documentSeparator = "---"
+
+	sourceMarkerPrefix = "# source: "
+)
+
+// validateRenderOptions checks the options that control the rendered output.
+func validateRenderOptions(separator, lineEndings string) error {
+	switch separator {
+	case "", separatorBetween, separatorLeading:
+	default:
+		return fmt.Errorf("invalid renderSeparator %q: must be either %q or %q", separator, separatorBetween, separatorLeading)
+	}
+
+	switch lineEndings {
+	case "", lineEndingsLF, lineEndingsCRLF:
+		return nil
+	default:
+		return fmt.Errorf("invalid renderLineEndings %q: must be either %q or %q", lineEndings, lineEndingsLF, lineEndingsCRLF)
+	}
+}
+
+// outputRenderedManifests joins the rendered manifests into a multi-document yaml string.
+func (k *Deployer) outputRenderedManifests(manifests manifest.ManifestList) string {
+	var docs []string
+	for _, m := range manifests {
+		docs = append(docs, string(bytes.TrimSpace(m)))
+	}
+
+	var out strings.Builder
+	previousSource := ""
+	for i, doc := range docs {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		if i > 0 || k.RenderSeparator == separatorLeading {
+			out.WriteString(documentSeparator + "\n")
+		}
+		if k.RenderSourceMarkers {
+			if source := k.manifestSource(manifests[i]); source != "" && source != previousSource {
+				out.WriteString(sourceMarkerPrefix + source + "\n")
+				previousSource = source
+			}
+		}
+		out.WriteString(doc)
+	}
+
+	if k.RenderTrailingSeparator && len(docs) > 0 {
+		out.WriteString("\n" + documentSeparator)
+	}
+	if k.RenderTrailingNewline && len(docs) > 0 {
+		out.WriteString("\n")
+	}
+
+	// Manifests written on Windows can come with CRLF line endings.
+	rendered := strings.ReplaceAll(out.String(), "\r\n", "\n")
+	if k.RenderLineEndings == lineEndingsCRLF {
+		rendered = strings.ReplaceAll(rendered, "\n", "\r\n")
+	}
+	return rendered
+}
+
+// recordSources remembers that the given resources were rendered from a kustomize path.
+func (k *Deployer) recordSources(kustomizePath string, manifests manifest.ManifestList) error {
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+		k.manifestSources[sourceKey(r)] = kustomizePath
+	}
+	return nil
+}
+
+// manifestSource returns the kustomize path a rendered resource comes from, or an empty string if it's unknown.
+func (k *Deployer) manifestSource(m []byte) string {
+	r, err := parseResource(m)
+	if err != nil {
+		return ""
+	}
+	return k.manifestSources[sourceKey(r)]
+}
+
+// sourceKey identifies a resource across the transformations of the rendered manifests, which can change its apiVersion.
+func sourceKey(r resource) string {
+	return r.Kind + "/" + r.Metadata.Namespace + "/" + r.Metadata.Name
+}
+
+// minifyManifests removes the `status` and the fields populated by the API server from the rendered manifests.
+// Manifests that contain none of those fields are left untouched.
+func minifyManifests(manifests manifest.ManifestList) (manifest.ManifestList, error) {
+	var minified manifest.ManifestList
+	for _, m := range manifests {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+
+		if !minifyObject(obj) {
+			minified = append(minified, m)
+			continue
+		}
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		minified = append(minified, buf)
+	}
+	return minified, nil
+}
+
+// minifyObject removes the noise from a single resource and reports whether it was changed.
+func minifyObject(obj map[string]interface{}) bool {
+	changed := false
+	if _, found := obj["status"]; found {
+		delete(obj, "status")
+		changed = true
+	}
+
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		for _, field := range volatileMetadata {
+			if _, found := metadata[field]; found {
+				delete(metadata, field)
+				changed = true
+			}
+		}
+	}
+
+	// Pod templates often come with an empty `creationTimestamp`.
+	if spec, ok := obj["spec"].(map[string]interface{}); ok {
+		if template, ok := spec["template"].(map[string]interface{}); ok {
+			if metadata, ok := template["metadata"].(map[string]interface{}); ok {
+				if _, found := metadata["creationTimestamp"]; found {
+					delete(metadata, "creationTimestamp")
+					changed = true
+				}
+			}
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestOutputRenderedManifests(t *testing.T) {
+	tests := []struct {
+		description string
+		kustomize   latestV1.KustomizeDeploy
+		manifests   manifest.ManifestList
+		expected    string
+	}{
+		{
+			description: "default",
+			manifests:   manifest.ManifestList{[]byte(serviceYAML + "\n"), []byte(deploymentYAML)},
+			expected:    serviceYAML + "\n---\n" + deploymentYAML,
+		},
+		{
+			description: "between",
+			kustomize:   latestV1.KustomizeDeploy{RenderSeparator: "between"},
+			manifests:   manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)},
+			expected:    serviceYAML + "\n---\n" + deploymentYAML,
+		},
+		{
+			description: "leading",
+			kustomize:   latestV1.KustomizeDeploy{RenderSeparator: "leading"},
+			manifests:   manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)},
+			expected:    "---\n" + serviceYAML + "\n---\n" + deploymentYAML,
+		},
+		{
+			description: "between with trailing separator",
+			kustomize:   latestV1.KustomizeDeploy{RenderTrailingSeparator: true},
+			manifests:   manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)},
+			expected:    serviceYAML + "\n---\n" + deploymentYAML + "\n---",
+		},
+		{
+			description: "leading with trailing separator",
+			kustomize:   latestV1.KustomizeDeploy{RenderSeparator: "leading", RenderTrailingSeparator: true},
+			manifests:   manifest.ManifestList{[]byte(serviceYAML)},
+			expected:    "---\n" + serviceYAML + "\n---",
+		},
+		{
+			description: "trailing newline",
+			kustomize:   latestV1.KustomizeDeploy{RenderTrailingNewline: true},
+			manifests:   manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML + "\n\n")},
+			expected:    serviceYAML + "\n---\n" + deploymentYAML + "\n",
+		},
+		{
+			description: "trailing newline after trailing separator",
+			kustomize:   latestV1.KustomizeDeploy{RenderTrailingSeparator: true, RenderTrailingNewline: true},
+			manifests:   manifest.ManifestList{[]byte(serviceYAML)},
+			expected:    serviceYAML + "\n---\n",
+		},
+		{
+			description: "no trailing newline by default",
+			manifests:   manifest.ManifestList{[]byte(serviceYAML + "\n")},
+			expected:    serviceYAML,
+		},
+		{
+			description: "no trailing newline without manifests",
+			kustomize:   latestV1.KustomizeDeploy{RenderTrailingNewline: true},
+			expected:    "",
+		},
+		{
+			description: "crlf line endings",
+			kustomize:   latestV1.KustomizeDeploy{RenderLineEndings: "crlf", RenderTrailingNewline: true},
+			manifests:   manifest.ManifestList{[]byte(serviceYAML), []byte("apiVersion: apps/v1\r\nkind: Deployment\r\nmetadata:\r\n  name: web\r\n")},
+			expected:    strings.ReplaceAll(serviceYAML+"\n---\n"+deploymentYAML+"\n", "\n", "\r\n"),
+		},
+		{
+			description: "crlf normalized to lf by default",
+			manifests:   manifest.ManifestList{[]byte("apiVersion: apps/v1\r\nkind: Deployment\r\nmetadata:\r\n  name: web")},
+			expected:    deploymentYAML,
+		},
+		{
+			description: "lf line endings",
+			kustomize:   latestV1.KustomizeDeploy{RenderLineEndings: "lf"},
+			manifests:   manifest.ManifestList{[]byte("apiVersion: v1\r\nkind: Service\r\nmetadata:\r\n  name: web")},
+			expected:    serviceYAML,
+		},
+		{
+			description: "no manifests",
+			kustomize:   latestV1.KustomizeDeploy{RenderSeparator: "leading", RenderTrailingSeparator: true},
+			expected:    "",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			k := &Deployer{KustomizeDeploy: &test.kustomize}
+
+			t.CheckDeepEqual(test.expected, k.outputRenderedManifests(test.manifests))
+		})
+	}
+}
+
+func TestKustomizeRenderSourceMarkers(t *testing.T) {
+	const configMapYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config`
+
+	tests := []struct {
+		description string
+		markers     bool
+		expected    string
+	}{
+		{
+			description: "markers before the resources of each path",
+			markers:     true,
+			expected: "# source: frontend\n" + serviceYAML + "\n---\n" + deploymentYAML +
+				"\n---\n# source: backend\n" + configMapYAML + "\n",
+		},
+		{
+			description: "no markers by default",
+			expected:    serviceYAML + "\n---\n" + deploymentYAML + "\n---\n" + configMapYAML + "\n",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build frontend", serviceYAML+"\n---\n"+deploymentYAML).
+				AndRunOut("kustomize build backend", configMapYAML))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().
+				Touch("frontend/kustomization.yaml", "backend/kustomization.yaml").
+				Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:      []string{"frontend", "backend"},
+				RenderSourceMarkers: test.markers,
+			})
+			t.RequireNoError(err)
+
+			var out bytes.Buffer
+			err = k.Render(context.Background(), &out, nil, true, "")
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected, out.String())
+		})
+	}
+}
+
+func TestValidateRenderOptions(t *testing.T) {
+	testutil.CheckError(t, false, validateRenderOptions("", ""))
+	testutil.CheckError(t, false, validateRenderOptions("leading", ""))
+	testutil.CheckError(t, true, validateRenderOptions("trailing", ""))
+	testutil.CheckError(t, false, validateRenderOptions("", "lf"))
+	testutil.CheckError(t, false, validateRenderOptions("", "crlf"))
+	testutil.CheckError(t, true, validateRenderOptions("", "CRLF"))
+}
+
+func TestMinifyManifests(t *testing.T) {
+	tests := []struct {
+		description string
+		manifests   manifest.ManifestList
+		expected    manifest.ManifestList
+	}{
+		{
+			description: "strip status and server fields",
+			manifests: manifest.ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  creationTimestamp: null
+  name: app
+  resourceVersion: "42"
+spec:
+  template:
+    metadata:
+      creationTimestamp: null
+      labels:
+        app: app
+status: {}`)},
+			expected: manifest.ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    metadata:
+      labels:
+        app: app
+`)},
+		},
+		{
+			description: "clean manifest is untouched",
+			manifests:   manifest.ManifestList{[]byte(serviceYAML)},
+			expected:    manifest.ManifestList{[]byte(serviceYAML)},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			minified, err := minifyManifests(test.manifests)
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected.String(), minified.String())
+		})
+	}
+}
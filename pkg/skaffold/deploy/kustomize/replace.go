@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+// defaultReplaceAnnotation marks the resources that are sent with `kubectl replace` instead of `kubectl apply`.
+const defaultReplaceAnnotation = "skaffold.dev/replace"
+
+// validateReplaceAnnotation checks the annotation that marks the resources to replace.
+func validateReplaceAnnotation(annotation string) error {
+	if annotation == "" {
+		return nil
+	}
+	if errs := validation.IsQualifiedName(annotation); len(errs) > 0 {
+		return fmt.Errorf("invalid replaceAnnotation %q: %s", annotation, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// splitReplaced separates the resources whose replace annotation is `true` from the resources to apply.
+func splitReplaced(manifests manifest.ManifestList, annotation string) (manifest.ManifestList, manifest.ManifestList, error) {
+	var replaced, applied manifest.ManifestList
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if r.Metadata.Annotations[annotation] == "true" {
+			replaced = append(replaced, m)
+		} else {
+			applied = append(applied, m)
+		}
+	}
+	return replaced, applied, nil
+}
+
+// replace sends the given resources with `kubectl replace`, so that their live state is fully replaced
+// rather than merged. Resources that don't exist yet can't be replaced and are created instead.
+func (k *Deployer) replace(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	existing, created, err := k.splitExisting(ctx, manifests)
+	if err != nil {
+		return err
+	}
+
+	if len(existing) > 0 {
+		if err := k.kubectl.Run(ctx, existing.Reader(), out, "replace", append(append([]string{}, k.kubectl.Flags.Global...), "-f", "-")...); err != nil {
+			return userErr(fmt.Errorf("kubectl replace: %w", err))
+		}
+	}
+	if len(created) > 0 {
+		if err := k.kubectl.Run(ctx, created.Reader(), out, "create", append(append([]string{}, k.kubectl.Flags.Global...), "-f", "-")...); err != nil {
+			return userErr(fmt.Errorf("kubectl create: %w", err))
+		}
+	}
+	return nil
+}
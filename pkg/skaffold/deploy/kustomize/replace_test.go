@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeDeployReplaceAnnotation(t *testing.T) {
+	const (
+		replacedDeploymentYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  annotations:
+    skaffold.dev/replace: "true"
+  name: web`
+		replacedConfigMapYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  annotations:
+    skaffold.dev/replace: "true"
+  name: settings`
+		replaced = replacedDeploymentYAML + "\n---\n" + replacedConfigMapYAML
+	)
+
+	tests := []struct {
+		description string
+		annotation  string
+		prune       bool
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "annotated resources replaced or created",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", replaced+"\n---\n"+serviceYAML).
+				AndRunInput(applyCommand, serviceYAML).
+				AndRunInputOut(getLiveCommand, replaced, liveDeploymentYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace replace -f -", replacedDeploymentYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace create -f -", replacedConfigMapYAML),
+		},
+		{
+			description: "only annotated resources",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", replacedDeploymentYAML).
+				AndRunInputOut(getLiveCommand, replacedDeploymentYAML, liveDeploymentYAML).
+				AndRunInput("kubectl --context kubecontext --namespace testNamespace replace -f -", replacedDeploymentYAML),
+		},
+		{
+			description: "custom annotation",
+			annotation:  "example.com/replace",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", replacedDeploymentYAML).
+				AndRunInput(applyCommand, replacedDeploymentYAML),
+		},
+		{
+			description: "not with prune",
+			prune:       true,
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", replacedDeploymentYAML),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+			labeller := &label.DefaultLabeller{}
+			if test.prune {
+				labeller = label.NewLabeller(true, nil, "run-id")
+			}
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, labeller, &latestV1.KustomizeDeploy{
+				KustomizePaths:    []string{"."},
+				ReplaceAnnotation: test.annotation,
+				Prune:             test.prune,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestValidateReplaceAnnotation(t *testing.T) {
+	testutil.CheckError(t, false, validateReplaceAnnotation(""))
+	testutil.CheckError(t, false, validateReplaceAnnotation("example.com/replace"))
+	testutil.CheckError(t, true, validateReplaceAnnotation("force replace"))
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// scalableKinds are the kinds of workloads whose replica count is overridden.
+var scalableKinds = map[string]bool{
+	"Deployment":            true,
+	"StatefulSet":           true,
+	"ReplicaSet":            true,
+	"ReplicationController": true,
+}
+
+// horizontalPodAutoscaler is the part of a HorizontalPodAutoscaler that identifies the workload it scales.
+type horizontalPodAutoscaler struct {
+	Spec struct {
+		ScaleTargetRef struct {
+			Kind string `yaml:"kind"`
+			Name string `yaml:"name"`
+		} `yaml:"scaleTargetRef"`
+	} `yaml:"spec"`
+}
+
+// overrideReplicas sets the replica count of the scalable workloads.
+// With `skipAutoscaled`, the workloads scaled by a HorizontalPodAutoscaler of the manifests are left untouched.
+func overrideReplicas(manifests manifest.ManifestList, replicas int, skipAutoscaled bool) (manifest.ManifestList, error) {
+	resources := make([]resource, len(manifests))
+	autoscaled := map[string]bool{}
+	for i, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+		resources[i] = r
+
+		if r.Kind == "HorizontalPodAutoscaler" {
+			var hpa horizontalPodAutoscaler
+			if err := yaml.Unmarshal(m, &hpa); err != nil {
+				return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+			}
+			target := hpa.Spec.ScaleTargetRef
+			autoscaled[r.Metadata.Namespace+"/"+target.Kind+"/"+target.Name] = true
+		}
+	}
+
+	var updated manifest.ManifestList
+	for i, m := range manifests {
+		r := resources[i]
+		if !scalableKinds[r.Kind] || (skipAutoscaled && autoscaled[r.Metadata.Namespace+"/"+r.Kind+"/"+r.Metadata.Name]) {
+			updated = append(updated, m)
+			continue
+		}
+
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+		spec, ok := obj["spec"].(map[string]interface{})
+		if !ok {
+			spec = map[string]interface{}{}
+			obj["spec"] = spec
+		}
+		spec["replicas"] = replicas
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, buf)
+	}
+	return updated, nil
+}
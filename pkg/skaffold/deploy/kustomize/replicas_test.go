@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestOverrideReplicas(t *testing.T) {
+	const (
+		apiDeploymentYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 3
+  template: {}`
+		hpaYAML = `apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: api
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: api`
+		statefulSetYAML = `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db`
+	)
+
+	tests := []struct {
+		description    string
+		manifests      manifest.ManifestList
+		replicas       int
+		skipAutoscaled bool
+		expected       manifest.ManifestList
+	}{
+		{
+			description: "workloads scaled",
+			manifests:   manifest.ManifestList{[]byte(apiDeploymentYAML), []byte(statefulSetYAML), []byte(serviceYAML)},
+			replicas:    1,
+			expected: manifest.ManifestList{
+				[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\nspec:\n  replicas: 1\n  template: {}\n"),
+				[]byte("apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: db\nspec:\n  replicas: 1\n"),
+				[]byte(serviceYAML),
+			},
+		},
+		{
+			description: "scaled to zero",
+			manifests:   manifest.ManifestList{[]byte(statefulSetYAML)},
+			expected:    manifest.ManifestList{[]byte("apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: db\nspec:\n  replicas: 0\n")},
+		},
+		{
+			description: "autoscaled workloads scaled by default",
+			manifests:   manifest.ManifestList{[]byte(apiDeploymentYAML), []byte(hpaYAML)},
+			replicas:    1,
+			expected: manifest.ManifestList{
+				[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\nspec:\n  replicas: 1\n  template: {}\n"),
+				[]byte(hpaYAML),
+			},
+		},
+		{
+			description:    "autoscaled workloads skipped",
+			manifests:      manifest.ManifestList{[]byte(apiDeploymentYAML), []byte(hpaYAML), []byte(statefulSetYAML)},
+			replicas:       1,
+			skipAutoscaled: true,
+			expected: manifest.ManifestList{
+				[]byte(apiDeploymentYAML),
+				[]byte(hpaYAML),
+				[]byte("apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: db\nspec:\n  replicas: 1\n"),
+			},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			updated, err := overrideReplicas(test.manifests, test.replicas, test.skipAutoscaled)
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected.String(), updated.String())
+		})
+	}
+}
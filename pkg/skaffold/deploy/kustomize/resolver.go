@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/graph"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+// resolveImages asks the `imageResolver` command which reference to use for each image
+// of the manifests that is not built by Skaffold. Images pinned by digest are left as they are.
+// The answers are cached for the lifetime of the deployer.
+// Images are replaced by repository, so the different tags of a repository must either all be
+// left unchanged or all resolve to the same reference.
+func (k *Deployer) resolveImages(ctx context.Context, manifests manifest.ManifestList, builds []graph.Artifact) ([]graph.Artifact, error) {
+	built := util.NewStringSet()
+	for _, build := range builds {
+		built.Insert(docker.SanitizeImageName(build.ImageName))
+	}
+
+	images, err := manifests.GetImages()
+	if err != nil {
+		return nil, err
+	}
+
+	// The reference that each image of a repository resolves to, by repository.
+	var names []string
+	targets := map[string]map[string]string{}
+	for _, image := range images {
+		if built.Contains(image.ImageName) {
+			continue
+		}
+		parsed, err := docker.ParseReference(image.Tag)
+		if err != nil || parsed.Digest != "" {
+			continue
+		}
+
+		ref, err := k.resolveImage(ctx, image.Tag)
+		if err != nil {
+			return nil, err
+		}
+		if ref == "" {
+			ref = image.Tag
+		}
+
+		if targets[image.ImageName] == nil {
+			names = append(names, image.ImageName)
+			targets[image.ImageName] = map[string]string{}
+		}
+		targets[image.ImageName][image.Tag] = ref
+	}
+
+	var resolved []graph.Artifact
+	for _, name := range names {
+		ref, err := repositoryTarget(name, targets[name])
+		if err != nil {
+			return nil, err
+		}
+		if ref != "" {
+			resolved = append(resolved, graph.Artifact{ImageName: name, Tag: ref})
+		}
+	}
+	return resolved, nil
+}
+
+// repositoryTarget returns the reference that replaces all the images of a repository,
+// or an empty string if they are all left unchanged. It fails if they resolve to different references.
+func repositoryTarget(name string, targets map[string]string) (string, error) {
+	var images []string
+	changed := false
+	for image, ref := range targets {
+		images = append(images, image)
+		changed = changed || ref != image
+	}
+	if !changed {
+		return "", nil
+	}
+
+	sort.Strings(images)
+	ref := targets[images[0]]
+	for _, image := range images[1:] {
+		if targets[image] != ref {
+			return "", userErr(fmt.Errorf("images of %q resolve to different references: %q to %q and %q to %q, but they can only be replaced with the same one",
+				name, images[0], ref, image, targets[image]))
+		}
+	}
+	return ref, nil
+}
+
+// resolveImage runs the `imageResolver` command for a single image. An empty answer
+// means that the image is left unchanged.
+func (k *Deployer) resolveImage(ctx context.Context, image string) (string, error) {
+	if ref, found := k.resolvedImages[image]; found {
+		return ref, nil
+	}
+
+	args := append(append([]string{}, k.ImageResolver[1:]...), image)
+	out, err := util.RunCmdOut(exec.CommandContext(ctx, k.ImageResolver[0], args...))
+	if err != nil {
+		return "", userErr(fmt.Errorf("resolving image %q: %w", image, err))
+	}
+
+	ref := strings.TrimSpace(string(out))
+	if ref != "" {
+		if _, err := docker.ParseReference(ref); err != nil {
+			return "", userErr(fmt.Errorf("resolving image %q: invalid image reference %q: %w", image, ref, err))
+		}
+		logrus.Debugf("image %q resolved to %q", image, ref)
+	}
+
+	k.resolvedImages[image] = ref
+	return ref, nil
+}
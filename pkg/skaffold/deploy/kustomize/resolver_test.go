@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/graph"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeImageResolver(t *testing.T) {
+	const podYAML = `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - image: app
+    name: app
+  - image: redis:6
+    name: cache
+  - image: sidecar
+    name: sidecar`
+
+	tests := []struct {
+		description string
+		commands    util.Command
+		expected    []string
+		shouldErr   bool
+	}{
+		{
+			description: "resolve images not built",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", podYAML).
+				AndRunOut("resolve --registry=prod redis:6", "gcr.io/mirror/redis:6.2.5\n").
+				AndRunOut("resolve --registry=prod sidecar", "").
+				AndRunOut("kustomize build .", podYAML),
+			expected: []string{"image: app:123", "image: gcr.io/mirror/redis:6.2.5", "image: sidecar"},
+		},
+		{
+			description: "resolver fails",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", podYAML).
+				AndRunOutErr("resolve --registry=prod redis:6", "", errors.New("unavailable")),
+			shouldErr: true,
+		},
+		{
+			description: "invalid reference",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", podYAML).
+				AndRunOut("resolve --registry=prod redis:6", "Not Found"),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				ImageResolver:  []string{"resolve", "--registry=prod"},
+			})
+			t.RequireNoError(err)
+
+			builds := []graph.Artifact{{ImageName: "app", Tag: "app:123"}}
+			var out bytes.Buffer
+			err = k.Render(context.Background(), &out, builds, false, "")
+			t.CheckError(test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+			for _, image := range test.expected {
+				t.CheckContains(image, out.String())
+			}
+
+			// Resolved images are cached.
+			err = k.Render(context.Background(), &bytes.Buffer{}, builds, false, "")
+			t.CheckNoError(err)
+		})
+	}
+}
+
+func TestKustomizeImageResolverTagsOfTheSameRepository(t *testing.T) {
+	const podYAML = `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - image: redis:6
+    name: cache
+  - image: redis:7
+    name: queue`
+
+	tests := []struct {
+		description string
+		resolved6   string
+		resolved7   string
+		expected    []string
+		shouldErr   bool
+	}{
+		{
+			description: "all tags unchanged",
+			expected:    []string{"image: redis:6", "image: redis:7"},
+		},
+		{
+			description: "all tags resolve to the same reference",
+			resolved6:   "gcr.io/mirror/redis:7.0.4",
+			resolved7:   "gcr.io/mirror/redis:7.0.4",
+			expected:    []string{"image: gcr.io/mirror/redis:7.0.4"},
+		},
+		{
+			description: "tags resolve to different references",
+			resolved6:   "gcr.io/mirror/redis:6.2.5",
+			resolved7:   "gcr.io/mirror/redis:7.0.4",
+			shouldErr:   true,
+		},
+		{
+			description: "only one tag resolved",
+			resolved6:   "gcr.io/mirror/redis:6.2.5",
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", podYAML).
+				AndRunOut("resolve redis:6", test.resolved6).
+				AndRunOut("resolve redis:7", test.resolved7))
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				ImageResolver:  []string{"resolve"},
+			})
+			t.RequireNoError(err)
+
+			var out bytes.Buffer
+			err = k.Render(context.Background(), &out, nil, false, "")
+
+			t.CheckError(test.shouldErr, err)
+			for _, image := range test.expected {
+				t.CheckContains(image, out.String())
+			}
+		})
+	}
+}
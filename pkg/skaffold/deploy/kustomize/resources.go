@@ -0,0 +1,52 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// resource holds the identifying fields of a rendered Kubernetes resource.
+type resource struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name        string            `yaml:"name"`
+		Namespace   string            `yaml:"namespace"`
+		Labels      map[string]string `yaml:"labels"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+}
+
+// parseResource reads the identifying fields of a single manifest.
+func parseResource(manifest []byte) (resource, error) {
+	var r resource
+	if err := yaml.Unmarshal(manifest, &r); err != nil {
+		return resource{}, fmt.Errorf("reading Kubernetes YAML: %w", err)
+	}
+	return r, nil
+}
+
+// String returns a human readable identifier for the resource, e.g. `Deployment/web`.
+func (r resource) String() string {
+	if r.Metadata.Namespace != "" {
+		return fmt.Sprintf("%s/%s (namespace %s)", r.Kind, r.Metadata.Name, r.Metadata.Namespace)
+	}
+	return fmt.Sprintf("%s/%s", r.Kind, r.Metadata.Name)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+const defaultRolloutTimeout = "5m"
+
+// pausableKinds are the workload kinds that support `kubectl rollout pause`.
+var pausableKinds = map[string]bool{
+	"Deployment": true,
+}
+
+// rolloutStatusKinds are the workload kinds that support `kubectl rollout status`.
+var rolloutStatusKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+}
+
+// pausedRollout identifies a workload whose rollout was paused by Skaffold.
+type pausedRollout struct {
+	namespace string
+	name      string
+}
+
+// pauseRollouts pauses the rollout of every pausable workload found in the manifests.
+// Workloads that can't be paused, because they don't exist yet or are already paused,
+// are skipped: only the rollouts that were actually paused are returned.
+func (k *Deployer) pauseRollouts(ctx context.Context, manifests manifest.ManifestList) ([]pausedRollout, error) {
+	var paused []pausedRollout
+
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+		if !pausableKinds[r.Kind] {
+			continue
+		}
+
+		rollout := pausedRollout{namespace: r.Metadata.Namespace, name: "deployment/" + r.Metadata.Name}
+		if err := k.kubectl.RunInNamespace(ctx, nil, ioutil.Discard, "rollout", rollout.namespace, "pause", rollout.name); err != nil {
+			logrus.Debugf("not pausing rollout of %s: %v", rollout.name, err)
+			continue
+		}
+		paused = append(paused, rollout)
+	}
+
+	return paused, nil
+}
+
+// resumeRollouts resumes the rollouts that were paused by `pauseRollouts`.
+func (k *Deployer) resumeRollouts(ctx context.Context, out io.Writer, paused []pausedRollout) error {
+	var firstErr error
+
+	for _, rollout := range paused {
+		if err := k.kubectl.RunInNamespace(ctx, nil, out, "rollout", rollout.namespace, "resume", rollout.name); err != nil && firstErr == nil {
+			firstErr = userErr(fmt.Errorf("resuming rollout of %s: %w", rollout.name, err))
+		}
+	}
+
+	return firstErr
+}
+
+// validateRolloutStatus checks the per-kind rollout status configuration.
+func validateRolloutStatus(configs []latestV1.KustomizeRolloutStatus) error {
+	seen := util.NewStringSet()
+
+	for _, c := range configs {
+		if !rolloutStatusKinds[c.Kind] {
+			return fmt.Errorf("invalid rolloutStatus kind %q: must be one of Deployment, StatefulSet or DaemonSet", c.Kind)
+		}
+		if seen.Contains(c.Kind) {
+			return fmt.Errorf("rolloutStatus is configured more than once for kind %q", c.Kind)
+		}
+		seen.Insert(c.Kind)
+
+		if c.Timeout != "" {
+			if _, err := time.ParseDuration(c.Timeout); err != nil {
+				return fmt.Errorf("invalid rolloutStatus timeout %q for kind %q: %w", c.Timeout, c.Kind, err)
+			}
+		}
+		if c.PollInterval != "" {
+			if interval, err := time.ParseDuration(c.PollInterval); err != nil || interval <= 0 {
+				return fmt.Errorf("invalid rolloutStatus pollInterval %q for kind %q: must be a positive duration", c.PollInterval, c.Kind)
+			}
+		}
+	}
+
+	return nil
+}
+
+// waitForRollouts waits for the rollout of each deployed workload whose kind is listed in `rolloutStatus`.
+func (k *Deployer) waitForRollouts(ctx context.Context, out io.Writer, manifests manifest.ManifestList) error {
+	configs := map[string]latestV1.KustomizeRolloutStatus{}
+	for _, c := range k.RolloutStatus {
+		configs[c.Kind] = c
+	}
+
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+		c, found := configs[r.Kind]
+		if !found {
+			continue
+		}
+
+		timeout := c.Timeout
+		if timeout == "" {
+			timeout = defaultRolloutTimeout
+		}
+
+		name := strings.ToLower(r.Kind) + "/" + r.Metadata.Name
+		if c.PollInterval == "" {
+			err = k.kubectl.RunInNamespace(ctx, nil, out, "rollout", r.Metadata.Namespace, "status", name, "--timeout="+timeout)
+		} else {
+			err = k.pollRollout(ctx, out, r.Metadata.Namespace, name, timeout, c.PollInterval)
+		}
+		if err != nil {
+			return userErr(fmt.Errorf("waiting for the rollout of %s: %w", r, err))
+		}
+	}
+
+	return nil
+}
+
+// pollRollout checks the rollout status of a workload at regular intervals, until it's rolled out or the timeout expires.
+func (k *Deployer) pollRollout(ctx context.Context, out io.Writer, namespace, name, timeout, pollInterval string) error {
+	// Both durations were validated when the deployer was created.
+	wait, _ := time.ParseDuration(timeout)
+	interval, _ := time.ParseDuration(pollInterval)
+	deadline := time.Now().Add(wait)
+
+	for {
+		status, err := util.RunCmdOut(k.kubectl.CommandWithNamespaceArg(ctx, "rollout", namespace, "status", name, "--watch=false"))
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(status), "successfully rolled out") {
+			fmt.Fprint(out, string(status))
+			return nil
+		}
+		logrus.Debugf("rollout of %s in progress: %s", name, strings.TrimSpace(string(status)))
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
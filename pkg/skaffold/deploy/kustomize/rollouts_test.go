@@ -0,0 +1,239 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizePauseRollouts(t *testing.T) {
+	const apiDeploymentYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: other`
+	rendered := deploymentYAML + "\n---\n" + serviceYAML + "\n---\n" + apiDeploymentYAML
+
+	tests := []struct {
+		description string
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "pause and resume around apply",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout pause deployment/web").
+				AndRun("kubectl --context kubecontext --namespace other rollout pause deployment/api").
+				AndRun(applyCommand).
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout resume deployment/web").
+				AndRun("kubectl --context kubecontext --namespace other rollout resume deployment/api"),
+		},
+		{
+			description: "new deployments are not paused",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout pause deployment/web").
+				AndRunErr("kubectl --context kubecontext --namespace other rollout pause deployment/api", errors.New("not found")).
+				AndRun(applyCommand).
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout resume deployment/web"),
+		},
+		{
+			description: "resume after failed apply",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout pause deployment/web").
+				AndRun("kubectl --context kubecontext --namespace other rollout pause deployment/api").
+				AndRunErr(applyCommand, errors.New("BUG")).
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout resume deployment/web").
+				AndRun("kubectl --context kubecontext --namespace other rollout resume deployment/api"),
+			shouldErr: true,
+		},
+		{
+			description: "failed resume",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout pause deployment/web").
+				AndRun("kubectl --context kubecontext --namespace other rollout pause deployment/api").
+				AndRun(applyCommand).
+				AndRunErr("kubectl --context kubecontext --namespace testNamespace rollout resume deployment/web", errors.New("BUG")).
+				AndRun("kubectl --context kubecontext --namespace other rollout resume deployment/api"),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				PauseRollouts:  true,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestKustomizeRolloutStatus(t *testing.T) {
+	const statefulSetYAML = `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db`
+	rendered := deploymentYAML + "\n---\n" + statefulSetYAML + "\n---\n" + serviceYAML
+
+	tests := []struct {
+		description   string
+		rolloutStatus []latestV1.KustomizeRolloutStatus
+		commands      util.Command
+		shouldErr     bool
+	}{
+		{
+			description: "per kind timeouts",
+			rolloutStatus: []latestV1.KustomizeRolloutStatus{
+				{Kind: "Deployment"},
+				{Kind: "StatefulSet", Timeout: "20m"},
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRun(applyCommand).
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout status deployment/web --timeout=5m").
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout status statefulset/db --timeout=20m"),
+		},
+		{
+			description: "only listed kinds",
+			rolloutStatus: []latestV1.KustomizeRolloutStatus{
+				{Kind: "StatefulSet", Timeout: "20m"},
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRun(applyCommand).
+				AndRun("kubectl --context kubecontext --namespace testNamespace rollout status statefulset/db --timeout=20m"),
+		},
+		{
+			description: "polling",
+			rolloutStatus: []latestV1.KustomizeRolloutStatus{
+				{Kind: "StatefulSet", PollInterval: "1ms"},
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRun(applyCommand).
+				AndRunOut("kubectl --context kubecontext --namespace testNamespace rollout status statefulset/db --watch=false", "Waiting for 1 pods to be ready...").
+				AndRunOut("kubectl --context kubecontext --namespace testNamespace rollout status statefulset/db --watch=false", "partitioned roll out complete: 1 new pods have been updated...\nstatefulset rolling update complete, successfully rolled out"),
+		},
+		{
+			description: "rollout fails",
+			rolloutStatus: []latestV1.KustomizeRolloutStatus{
+				{Kind: "Deployment", Timeout: "1s"},
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRun(applyCommand).
+				AndRunErr("kubectl --context kubecontext --namespace testNamespace rollout status deployment/web --timeout=1s", errors.New("timed out")),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				RolloutStatus:  test.rolloutStatus,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestValidateRolloutStatus(t *testing.T) {
+	tests := []struct {
+		description   string
+		rolloutStatus []latestV1.KustomizeRolloutStatus
+		shouldErr     bool
+	}{
+		{
+			description:   "valid",
+			rolloutStatus: []latestV1.KustomizeRolloutStatus{{Kind: "Deployment", Timeout: "2m", PollInterval: "5s"}},
+		},
+		{
+			description:   "unsupported kind",
+			rolloutStatus: []latestV1.KustomizeRolloutStatus{{Kind: "Job"}},
+			shouldErr:     true,
+		},
+		{
+			description:   "duplicate kind",
+			rolloutStatus: []latestV1.KustomizeRolloutStatus{{Kind: "Deployment"}, {Kind: "Deployment"}},
+			shouldErr:     true,
+		},
+		{
+			description:   "invalid timeout",
+			rolloutStatus: []latestV1.KustomizeRolloutStatus{{Kind: "Deployment", Timeout: "soon"}},
+			shouldErr:     true,
+		},
+		{
+			description:   "invalid poll interval",
+			rolloutStatus: []latestV1.KustomizeRolloutStatus{{Kind: "Deployment", PollInterval: "0s"}},
+			shouldErr:     true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckError(test.shouldErr, validateRolloutStatus(test.rolloutStatus))
+		})
+	}
+}
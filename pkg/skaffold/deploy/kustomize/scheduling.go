@@ -0,0 +1,180 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// validateScheduling checks the tolerations added to the workloads.
+func validateScheduling(scheduling *latestV1.KustomizeScheduling) error {
+	if scheduling == nil {
+		return nil
+	}
+
+	for _, t := range scheduling.Tolerations {
+		switch t.Operator {
+		case "", "Equal":
+			if t.Key == "" {
+				return fmt.Errorf("invalid toleration: operator %q requires a key", "Equal")
+			}
+		case "Exists":
+			if t.Value != "" {
+				return fmt.Errorf("invalid toleration %q: operator %q can't have a value", t.Key, t.Operator)
+			}
+		default:
+			return fmt.Errorf("invalid toleration %q: operator must be either %q or %q, not %q", t.Key, "Exists", "Equal", t.Operator)
+		}
+
+		switch t.Effect {
+		case "", "NoSchedule", "PreferNoSchedule", "NoExecute":
+		default:
+			return fmt.Errorf("invalid toleration %q: unknown effect %q", t.Key, t.Effect)
+		}
+	}
+	return nil
+}
+
+// scheduleWorkloads adds the configured node selector and tolerations to the pod templates of the workloads.
+// Manifests without a pod template are left untouched.
+func scheduleWorkloads(manifests manifest.ManifestList, scheduling *latestV1.KustomizeScheduling) (manifest.ManifestList, error) {
+	var updated manifest.ManifestList
+	for _, m := range manifests {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+
+		spec := podSpec(obj)
+		if spec == nil {
+			updated = append(updated, m)
+			continue
+		}
+		addNodeSelector(spec, scheduling.NodeSelector, scheduling.Overwrite)
+		addTolerations(spec, scheduling.Tolerations, scheduling.Overwrite)
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, buf)
+	}
+	return updated, nil
+}
+
+// podSpec returns the pod spec of a Pod or the pod template spec of a workload.
+func podSpec(obj map[string]interface{}) map[string]interface{} {
+	spec, ok := obj["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	switch obj["kind"] {
+	case "Pod":
+		return spec
+	case "CronJob":
+		jobTemplate, ok := spec["jobTemplate"].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if spec, ok = jobTemplate["spec"].(map[string]interface{}); !ok {
+			return nil
+		}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+	default:
+		return nil
+	}
+
+	template, ok := spec["template"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	templateSpec, ok := template["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return templateSpec
+}
+
+func addNodeSelector(spec map[string]interface{}, nodeSelector map[string]string, overwrite bool) {
+	if len(nodeSelector) == 0 {
+		return
+	}
+
+	existing, ok := spec["nodeSelector"].(map[string]interface{})
+	if !ok {
+		existing = map[string]interface{}{}
+		spec["nodeSelector"] = existing
+	}
+	for key, value := range nodeSelector {
+		if _, found := existing[key]; found && !overwrite {
+			continue
+		}
+		existing[key] = value
+	}
+}
+
+func addTolerations(spec map[string]interface{}, tolerations []latestV1.KustomizeToleration, overwrite bool) {
+	if len(tolerations) == 0 {
+		return
+	}
+
+	existing, _ := spec["tolerations"].([]interface{})
+	for _, t := range tolerations {
+		toleration := tolerationObject(t)
+
+		index := -1
+		for i, e := range existing {
+			if e, ok := e.(map[string]interface{}); ok && e["key"] == toleration["key"] && e["effect"] == toleration["effect"] {
+				index = i
+				break
+			}
+		}
+
+		switch {
+		case index < 0:
+			existing = append(existing, toleration)
+		case overwrite:
+			existing[index] = toleration
+		}
+	}
+	spec["tolerations"] = existing
+}
+
+func tolerationObject(t latestV1.KustomizeToleration) map[string]interface{} {
+	obj := map[string]interface{}{}
+	if t.Key != "" {
+		obj["key"] = t.Key
+	}
+	if t.Operator != "" {
+		obj["operator"] = t.Operator
+	}
+	if t.Value != "" {
+		obj["value"] = t.Value
+	}
+	if t.Effect != "" {
+		obj["effect"] = t.Effect
+	}
+	if t.TolerationSeconds != nil {
+		obj["tolerationSeconds"] = *t.TolerationSeconds
+	}
+	return obj
+}
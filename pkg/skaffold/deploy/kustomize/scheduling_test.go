@@ -0,0 +1,257 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestScheduleWorkloads(t *testing.T) {
+	scheduling := &latestV1.KustomizeScheduling{
+		NodeSelector: map[string]string{"pool": "dev"},
+		Tolerations:  []latestV1.KustomizeToleration{{Key: "dedicated", Operator: "Equal", Value: "dev", Effect: "NoSchedule"}},
+	}
+
+	tests := []struct {
+		description string
+		overwrite   bool
+		manifest    string
+		expected    string
+	}{
+		{
+			description: "pod",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - image: app`,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - image: app
+  nodeSelector:
+    pool: dev
+  tolerations:
+  - effect: NoSchedule
+    key: dedicated
+    operator: Equal
+    value: dev
+`,
+		},
+		{
+			description: "deployment",
+			manifest: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - image: app`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - image: app
+      nodeSelector:
+        pool: dev
+      tolerations:
+      - effect: NoSchedule
+        key: dedicated
+        operator: Equal
+        value: dev
+`,
+		},
+		{
+			description: "cronjob",
+			manifest: `apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: cleanup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - image: app`,
+			expected: `apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: cleanup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - image: app
+          nodeSelector:
+            pool: dev
+          tolerations:
+          - effect: NoSchedule
+            key: dedicated
+            operator: Equal
+            value: dev
+`,
+		},
+		{
+			description: "existing entries are kept",
+			manifest: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    spec:
+      nodeSelector:
+        disk: ssd
+        pool: db
+      tolerations:
+      - effect: NoSchedule
+        key: dedicated
+        operator: Equal
+        value: db`,
+			expected: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    spec:
+      nodeSelector:
+        disk: ssd
+        pool: db
+      tolerations:
+      - effect: NoSchedule
+        key: dedicated
+        operator: Equal
+        value: db
+`,
+		},
+		{
+			description: "existing entries are overwritten",
+			overwrite:   true,
+			manifest: `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  template:
+    spec:
+      nodeSelector:
+        disk: ssd
+        pool: db
+      tolerations:
+      - effect: NoSchedule
+        key: dedicated
+        operator: Equal
+        value: db`,
+			expected: `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  template:
+    spec:
+      nodeSelector:
+        disk: ssd
+        pool: dev
+      tolerations:
+      - effect: NoSchedule
+        key: dedicated
+        operator: Equal
+        value: dev
+`,
+		},
+		{
+			description: "not a workload",
+			manifest:    serviceYAML,
+			expected:    serviceYAML,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			config := *scheduling
+			config.Overwrite = test.overwrite
+
+			updated, err := scheduleWorkloads(manifest.ManifestList{[]byte(test.manifest)}, &config)
+
+			expected := manifest.ManifestList{[]byte(test.expected)}
+			t.CheckNoError(err)
+			t.CheckDeepEqual(expected.String(), updated.String())
+		})
+	}
+}
+
+func TestValidateScheduling(t *testing.T) {
+	tests := []struct {
+		description string
+		toleration  latestV1.KustomizeToleration
+		shouldErr   bool
+	}{
+		{
+			description: "equal",
+			toleration:  latestV1.KustomizeToleration{Key: "dedicated", Value: "dev", Effect: "NoSchedule"},
+		},
+		{
+			description: "exists without key",
+			toleration:  latestV1.KustomizeToleration{Operator: "Exists"},
+		},
+		{
+			description: "equal without key",
+			toleration:  latestV1.KustomizeToleration{Value: "dev"},
+			shouldErr:   true,
+		},
+		{
+			description: "exists with value",
+			toleration:  latestV1.KustomizeToleration{Key: "dedicated", Operator: "Exists", Value: "dev"},
+			shouldErr:   true,
+		},
+		{
+			description: "unknown operator",
+			toleration:  latestV1.KustomizeToleration{Key: "dedicated", Operator: "In"},
+			shouldErr:   true,
+		},
+		{
+			description: "unknown effect",
+			toleration:  latestV1.KustomizeToleration{Key: "dedicated", Effect: "NoRun"},
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			err := validateScheduling(&latestV1.KustomizeScheduling{Tolerations: []latestV1.KustomizeToleration{test.toleration}})
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// secretRefPrefix marks the values of rendered Secrets that must be fetched from a secret manager.
+const secretRefPrefix = "secretRef://"
+
+// SecretResolver fetches the values referenced by `secretRef://<path>` placeholders in rendered Secrets.
+type SecretResolver interface {
+	// ResolveSecret returns the value stored at the given path, without the `secretRef://` prefix.
+	ResolveSecret(ctx context.Context, path string) (string, error)
+}
+
+// WithSecretResolver registers the resolver used for the `secretRef://` placeholders of rendered Secrets.
+// It takes precedence over the `secretResolver` command of the configuration.
+func WithSecretResolver(resolver SecretResolver) DeployerOption {
+	return func(k *Deployer) {
+		k.secretResolver = resolver
+	}
+}
+
+// commandSecretResolver runs a command with the path of the secret as last argument and reads the value from its stdout.
+// The command is run directly, rather than through util.RunCmdOut, so that its output is never logged.
+type commandSecretResolver []string
+
+func (c commandSecretResolver) ResolveSecret(ctx context.Context, path string) (string, error) {
+	args := append(append([]string{}, c[1:]...), path)
+	cmd := exec.CommandContext(ctx, c[0], args...)
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
+
+// resolveSecretRefs replaces the `secretRef://` placeholders of the `data` and `stringData` of Secrets
+// with the values returned by the resolver. Errors only mention the placeholders, never the values.
+func resolveSecretRefs(ctx context.Context, manifests manifest.ManifestList, resolver SecretResolver) (manifest.ManifestList, error) {
+	var updated manifest.ManifestList
+	for _, m := range manifests {
+		if !bytes.Contains(m, []byte(secretRefPrefix)) {
+			updated = append(updated, m)
+			continue
+		}
+
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+		if obj["kind"] != "Secret" {
+			updated = append(updated, m)
+			continue
+		}
+
+		name := ""
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			name, _ = metadata["name"].(string)
+		}
+
+		for _, field := range []string{"data", "stringData"} {
+			values, ok := obj[field].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			for key, value := range values {
+				ref, ok := value.(string)
+				if !ok || !strings.HasPrefix(ref, secretRefPrefix) {
+					continue
+				}
+
+				path := strings.TrimPrefix(ref, secretRefPrefix)
+				if path == "" {
+					return nil, userErr(fmt.Errorf("secret %q: key %q: empty reference %q", name, key, ref))
+				}
+				resolved, err := resolver.ResolveSecret(ctx, path)
+				if err != nil {
+					return nil, userErr(fmt.Errorf("secret %q: key %q: resolving %q: %w", name, key, ref, err))
+				}
+
+				if field == "data" {
+					resolved = base64.StdEncoding.EncodeToString([]byte(resolved))
+				}
+				values[key] = resolved
+			}
+		}
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, buf)
+	}
+	return updated, nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+type mockSecretResolver map[string]string
+
+func (m mockSecretResolver) ResolveSecret(_ context.Context, path string) (string, error) {
+	value, found := m[path]
+	if !found {
+		return "", fmt.Errorf("%q not found", path)
+	}
+	return value, nil
+}
+
+func TestResolveSecretRefs(t *testing.T) {
+	resolver := mockSecretResolver{"db/password": "s3cr3t", "db/user": "admin"}
+
+	tests := []struct {
+		description string
+		manifests   manifest.ManifestList
+		expected    manifest.ManifestList
+		shouldErr   bool
+	}{
+		{
+			description: "data is base64 encoded",
+			manifests: manifest.ManifestList{[]byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  password: secretRef://db/password
+  plain: dmFsdWU=`)},
+			expected: manifest.ManifestList{[]byte(`apiVersion: v1
+data:
+  password: czNjcjN0
+  plain: dmFsdWU=
+kind: Secret
+metadata:
+  name: db
+`)},
+		},
+		{
+			description: "stringData is kept as is",
+			manifests: manifest.ManifestList{[]byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+stringData:
+  user: secretRef://db/user`)},
+			expected: manifest.ManifestList{[]byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+stringData:
+  user: admin
+`)},
+		},
+		{
+			description: "other kinds are left untouched",
+			manifests: manifest.ManifestList{[]byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  password: secretRef://db/password`)},
+			expected: manifest.ManifestList{[]byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  password: secretRef://db/password`)},
+		},
+		{
+			description: "unknown reference",
+			manifests: manifest.ManifestList{[]byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  token: secretRef://db/token`)},
+			shouldErr: true,
+		},
+		{
+			description: "empty reference",
+			manifests: manifest.ManifestList{[]byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  token: secretRef://`)},
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			resolved, err := resolveSecretRefs(context.Background(), test.manifests, resolver)
+
+			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected.String(), resolved.String())
+		})
+	}
+}
+
+func TestKustomizeRenderSecretRefs(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+stringData:
+  password: secretRef://db/password`))
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+			SecretResolver: []string{"unused-command"},
+		}, WithSecretResolver(mockSecretResolver{"db/password": "s3cr3t"}))
+		t.RequireNoError(err)
+
+		var out bytes.Buffer
+		err = k.Render(context.Background(), &out, nil, true, "")
+
+		t.CheckNoError(err)
+		t.CheckContains("password: secretRef://db/password", out.String())
+		testutil.CheckNotContains(t.T, "s3cr3t", out.String())
+	})
+}
+
+func TestKustomizeDeploySecretRefs(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+			AndRunOut("kustomize build .", `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+stringData:
+  password: secretRef://db/password`).
+			AndRunInput("kubectl --context kubecontext --namespace testNamespace apply -f -", `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+stringData:
+  password: s3cr3t`))
+		t.Override(&client.Client, deployutil.MockK8sClient)
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+		t.NewTempDir().Chdir()
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+		}, WithSecretResolver(mockSecretResolver{"db/password": "s3cr3t"}))
+		t.RequireNoError(err)
+
+		var out bytes.Buffer
+		err = k.Deploy(context.Background(), &out, nil)
+
+		t.CheckNoError(err)
+		testutil.CheckNotContains(t.T, "s3cr3t", out.String())
+	})
+}
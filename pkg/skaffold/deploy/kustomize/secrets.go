@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// isGitIgnored checks whether a file is ignored by git.
+// Files that are not in a git repository are considered ignored, since they can't be committed.
+var isGitIgnored = func(path string) bool {
+	cmd := exec.Command("git", "check-ignore", "-q", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+
+	err := util.RunCmd(cmd)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// Exit code 1 means that the file is not ignored.
+		return false
+	}
+	return true
+}
+
+// generatorFilePaths extracts the file paths from generator `files` entries,
+// which can be written either as `path` or `key=path`.
+func generatorFilePaths(files []string) []string {
+	var paths []string
+	for _, file := range files {
+		if i := strings.Index(file, "="); i >= 0 {
+			file = file[i+1:]
+		}
+		paths = append(paths, file)
+	}
+	return paths
+}
+
+// warnTrackedSecrets warns about secret generator files that exist locally
+// but are not ignored by git and could therefore be committed by mistake.
+func warnTrackedSecrets(dir string, files []string) {
+	for _, path := range trackedSecrets(dir, files) {
+		warnings.Printf("secret generator file %q is not ignored by git and could be committed by mistake", path)
+	}
+}
+
+// trackedSecrets returns the secret generator files that exist locally but are not ignored by git.
+func trackedSecrets(dir string, files []string) []string {
+	var tracked []string
+	for _, file := range files {
+		if local, mode := pathExistsLocally(file, dir); !local || mode.IsDir() {
+			continue
+		}
+
+		path := util.AbsolutePaths(dir, []string{file})[0]
+		if !isGitIgnored(path) {
+			tracked = append(tracked, path)
+		}
+	}
+	return tracked
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestWarnOnTrackedSecrets(t *testing.T) {
+	tests := []struct {
+		description string
+		enabled     bool
+		ignored     map[string]bool
+		expected    []string
+	}{
+		{
+			description: "tracked secret files",
+			enabled:     true,
+			expected: []string{
+				`secret generator file "{{dir}}/secret.env" is not ignored by git and could be committed by mistake`,
+				`secret generator file "{{dir}}/secret.txt" is not ignored by git and could be committed by mistake`,
+			},
+		},
+		{
+			description: "ignored secret files",
+			enabled:     true,
+			ignored:     map[string]bool{"secret.txt": true, "secret.env": true},
+		},
+		{
+			description: "partially ignored secret files",
+			enabled:     true,
+			ignored:     map[string]bool{"secret.env": true},
+			expected: []string{
+				`secret generator file "{{dir}}/secret.txt" is not ignored by git and could be committed by mistake`,
+			},
+		},
+		{
+			description: "disabled by default",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir().
+				Write("kustomization.yaml", `secretGenerator:
+- name: creds
+  files: [password=secret.txt, missing.txt]
+  envs: [secret.env]`).
+				Write("secret.txt", "hunter2").
+				Write("secret.env", "PASSWORD=hunter2")
+
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+			t.Override(&isGitIgnored, func(path string) bool { return test.ignored[filepath.Base(path)] })
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:       []string{tmpDir.Root()},
+				WarnOnTrackedSecrets: test.enabled,
+			})
+			t.RequireNoError(err)
+
+			_, err = k.Dependencies()
+			t.CheckNoError(err)
+
+			var expected []string
+			for _, warning := range test.expected {
+				expected = append(expected, strings.ReplaceAll(warning, "{{dir}}", tmpDir.Root()))
+			}
+			t.CheckDeepEqual(expected, fakeWarner.Warnings)
+		})
+	}
+}
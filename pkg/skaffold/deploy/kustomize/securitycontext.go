@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// validateSecurityContext checks the security settings added to the workloads.
+func validateSecurityContext(sc *latestV1.KustomizeSecurityContext) error {
+	if sc == nil {
+		return nil
+	}
+
+	switch sc.SeccompProfile {
+	case "", "RuntimeDefault", "Unconfined":
+	default:
+		return fmt.Errorf("invalid securityContext: seccompProfile must be either %q or %q, not %q", "RuntimeDefault", "Unconfined", sc.SeccompProfile)
+	}
+	if sc.RunAsUser != nil {
+		if *sc.RunAsUser < 0 {
+			return fmt.Errorf("invalid securityContext: runAsUser %d must not be negative", *sc.RunAsUser)
+		}
+		runAsNonRoot := sc.Restricted
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = *sc.RunAsNonRoot
+		}
+		if *sc.RunAsUser == 0 && runAsNonRoot {
+			return fmt.Errorf("invalid securityContext: runAsUser 0 is root, which runAsNonRoot forbids")
+		}
+	}
+	for _, capability := range sc.DropCapabilities {
+		if capability == "" {
+			return fmt.Errorf("invalid securityContext: dropCapabilities can't be empty")
+		}
+	}
+	return nil
+}
+
+// podSecuritySettings returns the settings of the pod security context, with the `restricted` preset applied.
+func podSecuritySettings(sc *latestV1.KustomizeSecurityContext) map[string]interface{} {
+	settings := map[string]interface{}{}
+	if sc.Restricted {
+		settings["runAsNonRoot"] = true
+		settings["seccompProfile"] = map[string]interface{}{"type": "RuntimeDefault"}
+	}
+	if sc.RunAsNonRoot != nil {
+		settings["runAsNonRoot"] = *sc.RunAsNonRoot
+	}
+	if sc.RunAsUser != nil {
+		settings["runAsUser"] = *sc.RunAsUser
+	}
+	if sc.SeccompProfile != "" {
+		settings["seccompProfile"] = map[string]interface{}{"type": sc.SeccompProfile}
+	}
+	return settings
+}
+
+// containerSecuritySettings returns the settings of the container security contexts, with the `restricted` preset applied.
+func containerSecuritySettings(sc *latestV1.KustomizeSecurityContext) (map[string]interface{}, []interface{}) {
+	settings := map[string]interface{}{}
+	var drop []interface{}
+	if sc.Restricted {
+		settings["allowPrivilegeEscalation"] = false
+		drop = []interface{}{"ALL"}
+	}
+	if sc.AllowPrivilegeEscalation != nil {
+		settings["allowPrivilegeEscalation"] = *sc.AllowPrivilegeEscalation
+	}
+	if sc.ReadOnlyRootFilesystem != nil {
+		settings["readOnlyRootFilesystem"] = *sc.ReadOnlyRootFilesystem
+	}
+	if len(sc.DropCapabilities) > 0 {
+		drop = nil
+		for _, capability := range sc.DropCapabilities {
+			drop = append(drop, capability)
+		}
+	}
+	return settings, drop
+}
+
+// enforceSecurityContext adds the security settings to the pod templates of the workloads and to their
+// containers and init containers. Manifests without a pod template are left untouched.
+func enforceSecurityContext(manifests manifest.ManifestList, sc *latestV1.KustomizeSecurityContext) (manifest.ManifestList, error) {
+	podSettings := podSecuritySettings(sc)
+	containerSettings, drop := containerSecuritySettings(sc)
+
+	var updated manifest.ManifestList
+	for _, m := range manifests {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return nil, fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+
+		spec := podSpec(obj)
+		if spec == nil {
+			updated = append(updated, m)
+			continue
+		}
+
+		setSecuritySettings(spec, podSettings, nil, sc.Overwrite)
+		for _, field := range []string{"initContainers", "containers"} {
+			containers, _ := spec[field].([]interface{})
+			for _, c := range containers {
+				if c, ok := c.(map[string]interface{}); ok {
+					setSecuritySettings(c, containerSettings, drop, sc.Overwrite)
+				}
+			}
+		}
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, buf)
+	}
+	return updated, nil
+}
+
+// setSecuritySettings sets the settings, and the dropped capabilities, on the `securityContext` of a pod spec or container.
+func setSecuritySettings(obj map[string]interface{}, settings map[string]interface{}, drop []interface{}, overwrite bool) {
+	if len(settings) == 0 && len(drop) == 0 {
+		return
+	}
+
+	securityContext, ok := obj["securityContext"].(map[string]interface{})
+	if !ok {
+		securityContext = map[string]interface{}{}
+		obj["securityContext"] = securityContext
+	}
+	for key, value := range settings {
+		if _, found := securityContext[key]; found && !overwrite {
+			continue
+		}
+		securityContext[key] = value
+	}
+
+	if len(drop) == 0 {
+		return
+	}
+	capabilities, ok := securityContext["capabilities"].(map[string]interface{})
+	if !ok {
+		capabilities = map[string]interface{}{}
+		securityContext["capabilities"] = capabilities
+	}
+	if _, found := capabilities["drop"]; found && !overwrite {
+		return
+	}
+	capabilities["drop"] = drop
+}
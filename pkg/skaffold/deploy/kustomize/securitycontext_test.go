@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestEnforceSecurityContext(t *testing.T) {
+	user := int64(2000)
+	const webYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        securityContext:
+          capabilities:
+            drop:
+            - NET_RAW
+      initContainers:
+      - name: migrate
+        securityContext:
+          allowPrivilegeEscalation: true
+      securityContext:
+        runAsUser: 1000`
+
+	tests := []struct {
+		description     string
+		securityContext latestV1.KustomizeSecurityContext
+		expected        manifest.ManifestList
+	}{
+		{
+			description:     "restricted settings added when missing",
+			securityContext: latestV1.KustomizeSecurityContext{Restricted: true},
+			expected: manifest.ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        securityContext:
+          allowPrivilegeEscalation: false
+          capabilities:
+            drop:
+            - NET_RAW
+      initContainers:
+      - name: migrate
+        securityContext:
+          allowPrivilegeEscalation: true
+          capabilities:
+            drop:
+            - ALL
+      securityContext:
+        runAsNonRoot: true
+        runAsUser: 1000
+        seccompProfile:
+          type: RuntimeDefault
+`), []byte(serviceYAML)},
+		},
+		{
+			description: "explicit settings overwritten",
+			securityContext: latestV1.KustomizeSecurityContext{
+				Restricted:             true,
+				RunAsUser:              &user,
+				ReadOnlyRootFilesystem: util.BoolPtr(true),
+				Overwrite:              true,
+			},
+			expected: manifest.ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        securityContext:
+          allowPrivilegeEscalation: false
+          capabilities:
+            drop:
+            - ALL
+          readOnlyRootFilesystem: true
+      initContainers:
+      - name: migrate
+        securityContext:
+          allowPrivilegeEscalation: false
+          capabilities:
+            drop:
+            - ALL
+          readOnlyRootFilesystem: true
+      securityContext:
+        runAsNonRoot: true
+        runAsUser: 2000
+        seccompProfile:
+          type: RuntimeDefault
+`), []byte(serviceYAML)},
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			updated, err := enforceSecurityContext(manifest.ManifestList{[]byte(webYAML), []byte(serviceYAML)}, &test.securityContext)
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected.String(), updated.String())
+		})
+	}
+}
+
+func TestValidateSecurityContext(t *testing.T) {
+	root, user := int64(0), int64(1000)
+	testutil.CheckError(t, false, validateSecurityContext(nil))
+	testutil.CheckError(t, false, validateSecurityContext(&latestV1.KustomizeSecurityContext{Restricted: true, RunAsUser: &user}))
+	testutil.CheckError(t, true, validateSecurityContext(&latestV1.KustomizeSecurityContext{SeccompProfile: "Localhost"}))
+	testutil.CheckError(t, true, validateSecurityContext(&latestV1.KustomizeSecurityContext{Restricted: true, RunAsUser: &root}))
+	testutil.CheckError(t, true, validateSecurityContext(&latestV1.KustomizeSecurityContext{DropCapabilities: []string{""}}))
+}
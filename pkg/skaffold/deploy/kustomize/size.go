@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+const (
+	// etcdObjectSizeLimit is the default maximum size of an object stored by the API server.
+	etcdObjectSizeLimit = 1024 * 1024
+
+	// objectSizeWarningRatio is how close to the limit a resource can get before it's reported.
+	objectSizeWarningRatio = 0.9
+)
+
+// checkResourceSizes reports the resources whose size approaches the etcd object size limit.
+// Those are usually ConfigMaps or Secrets created by generators from large files, and their apply fails cryptically.
+// When strict, oversized resources fail the deployment instead of just being warned about.
+func checkResourceSizes(manifests manifest.ManifestList, strict bool) error {
+	var oversized []string
+
+	for _, m := range manifests {
+		if float64(len(m)) < objectSizeWarningRatio*etcdObjectSizeLimit {
+			continue
+		}
+
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+		oversized = append(oversized, fmt.Sprintf("%s is %d bytes", r, len(m)))
+	}
+
+	if len(oversized) == 0 {
+		return nil
+	}
+	if strict {
+		return userErr(fmt.Errorf("resources too close to the %d bytes object size limit:\n - %s", etcdObjectSizeLimit, strings.Join(oversized, "\n - ")))
+	}
+	for _, o := range oversized {
+		warnings.Printf("%s, close to the %d bytes object size limit: the apply might fail", o, etcdObjectSizeLimit)
+	}
+	return nil
+}
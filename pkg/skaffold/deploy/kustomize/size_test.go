@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestCheckResourceSizes(t *testing.T) {
+	oversized := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: large
+data:
+  blob: ` + strings.Repeat("a", etcdObjectSizeLimit)
+
+	tests := []struct {
+		description      string
+		manifests        manifest.ManifestList
+		strict           bool
+		expectedWarnings []string
+		shouldErr        bool
+	}{
+		{
+			description: "small resources",
+			manifests:   manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)},
+		},
+		{
+			description:      "oversized resource",
+			manifests:        manifest.ManifestList{[]byte(serviceYAML), []byte(oversized)},
+			expectedWarnings: []string{"ConfigMap/large is 1048645 bytes, close to the 1048576 bytes object size limit: the apply might fail"},
+		},
+		{
+			description: "oversized resource in strict mode",
+			manifests:   manifest.ManifestList{[]byte(oversized)},
+			strict:      true,
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+
+			err := checkResourceSizes(test.manifests, test.strict)
+
+			t.CheckError(test.shouldErr, err)
+			t.CheckDeepEqual(test.expectedWarnings, fakeWarner.Warnings)
+		})
+	}
+}
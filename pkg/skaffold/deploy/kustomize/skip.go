@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+)
+
+const (
+	defaultSkipAnnotation = "skaffold.dev/deploy"
+	skipValue             = "skip"
+
+	groupAnnotation = "skaffold.dev/group"
+)
+
+// filterSkipped removes the resources whose skip annotation is set to `skip`,
+// so that informational resources can be kept in a kustomization without being deployed.
+func (k *Deployer) filterSkipped(manifests manifest.ManifestList) (manifest.ManifestList, error) {
+	annotation := k.SkipAnnotation
+	if annotation == "" {
+		annotation = defaultSkipAnnotation
+	}
+
+	var filtered manifest.ManifestList
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Metadata.Annotations[annotation] == skipValue {
+			logrus.Infof("not deploying %s: annotated with %s: %s", r, annotation, skipValue)
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+
+	return filtered, nil
+}
+
+// filterGroup keeps the resources whose group annotation is set to the given group,
+// so that groups of resources can be deployed one after the other, e.g. `infra` then `apps`.
+func filterGroup(manifests manifest.ManifestList, group string) (manifest.ManifestList, error) {
+	var filtered manifest.ManifestList
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Metadata.Annotations[groupAnnotation] != group {
+			logrus.Debugf("not deploying %s: not in group %q", r, group)
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+
+	return filtered, nil
+}
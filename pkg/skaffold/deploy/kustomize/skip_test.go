@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeSkipAnnotation(t *testing.T) {
+	tests := []struct {
+		description   string
+		annotation    string
+		skippedYAML   string
+		expectedApply string
+	}{
+		{
+			description: "default annotation",
+			skippedYAML: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  annotations:
+    skaffold.dev/deploy: skip
+  name: docs
+  namespace: docs`,
+			expectedApply: deploymentYAML,
+		},
+		{
+			description: "custom annotation",
+			annotation:  "example.com/apply",
+			skippedYAML: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  annotations:
+    example.com/apply: skip
+  name: docs
+  namespace: docs`,
+			expectedApply: deploymentYAML,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", test.skippedYAML+"\n---\n"+deploymentYAML).
+				AndRunInput(applyCommand, test.expectedApply))
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				SkipAnnotation: test.annotation,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckNoError(err)
+			t.CheckFalse(util.StrSliceContains(*k.namespaces, "docs"))
+		})
+	}
+}
+
+func TestKustomizeDeployGroup(t *testing.T) {
+	infraYAML := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  annotations:
+    skaffold.dev/group: infra
+  name: infra`
+	appsYAML := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  annotations:
+    skaffold.dev/group: apps
+  name: web`
+	rendered := infraYAML + "\n---\n" + appsYAML + "\n---\n" + serviceYAML
+
+	tests := []struct {
+		description string
+		group       string
+		commands    util.Command
+	}{
+		{
+			description: "infra group",
+			group:       "infra",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRunInput(applyCommand, infraYAML),
+		},
+		{
+			description: "apps group",
+			group:       "apps",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRunInput(applyCommand, appsYAML),
+		},
+		{
+			description: "unknown group",
+			group:       "monitoring",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered),
+		},
+		{
+			description: "all the resources without group",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", rendered).
+				AndRunInput(applyCommand, rendered),
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				DeployGroup:    test.group,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckNoError(err)
+		})
+	}
+}
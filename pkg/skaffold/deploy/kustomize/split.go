@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+var (
+	unsafeFileNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+	// splitFileNamePattern matches the names returned by splitFileName.
+	splitFileNamePattern = regexp.MustCompile(`^[0-9]{3,}-[a-z0-9._-]*\.yaml$`)
+)
+
+// renderIndex lists the files written to `renderSplitDir`.
+type renderIndex struct {
+	Files []renderIndexFile `yaml:"files"`
+}
+
+type renderIndexFile struct {
+	File      string                `yaml:"file"`
+	Resources []renderIndexResource `yaml:"resources"`
+}
+
+type renderIndexResource struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+	Namespace  string `yaml:"namespace,omitempty"`
+}
+
+// validateRenderSplit checks the options that split the rendered output into multiple files.
+func validateRenderSplit(dir, indexFile string) error {
+	if indexFile == "" {
+		return nil
+	}
+	if dir == "" {
+		return fmt.Errorf("renderIndexFile requires renderSplitDir")
+	}
+	if filepath.Base(indexFile) != indexFile || indexFile == "." || indexFile == ".." {
+		return fmt.Errorf("invalid renderIndexFile %q: must be a file name", indexFile)
+	}
+	return nil
+}
+
+// writeSplitManifests writes each rendered resource to its own file in the given directory and,
+// if an index file is configured, an index of those files and the resources they hold.
+func writeSplitManifests(dir, indexFile string, manifests manifest.ManifestList) error {
+	if err := removeResourceFiles(dir); err != nil {
+		return err
+	}
+
+	files, resources, err := writeResourceFiles(dir, manifests)
+	if err != nil {
+		return err
+	}
+
+	var index renderIndex
+	for i, file := range files {
+		r := resources[i]
+		index.Files = append(index.Files, renderIndexFile{
+			File: file,
+			Resources: []renderIndexResource{{
+				APIVersion: r.APIVersion,
+				Kind:       r.Kind,
+				Name:       r.Metadata.Name,
+				Namespace:  r.Metadata.Namespace,
+			}},
+		})
+	}
+
+	if indexFile == "" {
+		return nil
+	}
+
+	buf, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, indexFile), buf, 0644)
+}
+
+// writeResourceFiles writes each resource to its own file in the given directory.
+// It returns the names of the files and the resources they hold.
+func writeResourceFiles(dir string, manifests manifest.ManifestList) ([]string, []resource, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("creating %q: %w", dir, err)
+	}
+
+	var files []string
+	var resources []resource
+	for i, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		file := splitFileName(i, r)
+		if err := ioutil.WriteFile(filepath.Join(dir, file), append(bytes.TrimSpace(m), '\n'), 0644); err != nil {
+			return nil, nil, fmt.Errorf("writing %q: %w", file, err)
+		}
+		files = append(files, file)
+		resources = append(resources, r)
+	}
+	return files, resources, nil
+}
+
+// removeResourceFiles removes the files written by a previous render to the given directory,
+// so that resources that are no longer rendered don't leave stale files behind.
+// Other files are left untouched.
+func removeResourceFiles(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading %q: %w", dir, err)
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !splitFileNamePattern.MatchString(f.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+			return fmt.Errorf("removing %q: %w", f.Name(), err)
+		}
+	}
+	return nil
+}
+
+// splitFileName returns the name of the file holding a resource, e.g. `001-deployment-web.yaml`.
+// The prefix keeps the files in the rendered order and unique.
+func splitFileName(i int, r resource) string {
+	parts := []string{fmt.Sprintf("%03d", i), r.Kind}
+	if r.Metadata.Namespace != "" {
+		parts = append(parts, r.Metadata.Namespace)
+	}
+	parts = append(parts, r.Metadata.Name)
+
+	name := unsafeFileNameChars.ReplaceAllString(strings.ToLower(strings.Join(parts, "-")), "_")
+	return name + ".yaml"
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestWriteSplitManifests(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		dir := filepath.Join(t.NewTempDir().Root(), "rendered")
+		configMapYAML := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: team-a`
+
+		err := writeSplitManifests(dir, "index.yaml", manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML), []byte(configMapYAML)})
+		t.RequireNoError(err)
+
+		index, err := ioutil.ReadFile(filepath.Join(dir, "index.yaml"))
+		t.RequireNoError(err)
+		t.CheckDeepEqual(`files:
+- file: 000-service-web.yaml
+  resources:
+  - apiVersion: v1
+    kind: Service
+    name: web
+- file: 001-deployment-web.yaml
+  resources:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: web
+- file: 002-configmap-team-a-settings.yaml
+  resources:
+  - apiVersion: v1
+    kind: ConfigMap
+    name: settings
+    namespace: team-a
+`, string(index))
+
+		// Every file listed in the index holds the resources it lists.
+		var parsed renderIndex
+		t.RequireNoError(yaml.Unmarshal(index, &parsed))
+		for _, f := range parsed.Files {
+			content, err := ioutil.ReadFile(filepath.Join(dir, f.File))
+			t.RequireNoError(err)
+
+			r, err := parseResource(content)
+			t.RequireNoError(err)
+			t.CheckDeepEqual(f.Resources, []renderIndexResource{{APIVersion: r.APIVersion, Kind: r.Kind, Name: r.Metadata.Name, Namespace: r.Metadata.Namespace}})
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		t.RequireNoError(err)
+		t.CheckDeepEqual(len(parsed.Files)+1, len(files))
+	})
+}
+
+func TestWriteSplitManifestsRemovesStaleFiles(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir().Write("rendered/README.md", "not rendered")
+		dir := tmpDir.Path("rendered")
+
+		err := writeSplitManifests(dir, "index.yaml", manifest.ManifestList{[]byte(serviceYAML), []byte(deploymentYAML)})
+		t.RequireNoError(err)
+
+		err = writeSplitManifests(dir, "index.yaml", manifest.ManifestList{[]byte(deploymentYAML)})
+		t.RequireNoError(err)
+
+		files, err := ioutil.ReadDir(dir)
+		t.RequireNoError(err)
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+		t.CheckDeepEqual([]string{"000-deployment-web.yaml", "README.md", "index.yaml"}, names)
+	})
+}
+
+func TestKustomizeRenderSplitWithOutputFile(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		tmpDir := t.NewTempDir()
+		t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+		k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"."},
+			RenderSplitDir: tmpDir.Path("rendered"),
+		})
+		t.RequireNoError(err)
+
+		err = k.Render(context.Background(), ioutil.Discard, nil, true, tmpDir.Path("output.yaml"))
+
+		t.CheckErrorContains("renderSplitDir can't be combined with an output file", err)
+	})
+}
+
+func TestValidateRenderSplit(t *testing.T) {
+	tests := []struct {
+		description string
+		dir         string
+		indexFile   string
+		shouldErr   bool
+	}{
+		{description: "no index", dir: "rendered"},
+		{description: "index", dir: "rendered", indexFile: "index.yaml"},
+		{description: "index without split dir", indexFile: "index.yaml", shouldErr: true},
+		{description: "index in a sub directory", dir: "rendered", indexFile: "sub/index.yaml", shouldErr: true},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			err := validateRenderSplit(test.dir, test.indexFile)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
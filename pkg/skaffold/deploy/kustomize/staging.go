@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stageFiles copies the given files into the kustomize path, so that they are co-located
+// with the kustomization. The returned function removes the copies.
+func stageFiles(kustomizePath string, files []string) (func(), error) {
+	var staged []string
+	cleanup := func() {
+		for _, path := range staged {
+			if err := os.Remove(path); err != nil {
+				logrus.Warnf("unable to remove staged file %q: %v", path, err)
+			}
+		}
+	}
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("staging file %q: %w", file, err)
+		}
+		if info.IsDir() {
+			cleanup()
+			return nil, fmt.Errorf("staging file %q: it is a directory", file)
+		}
+
+		dest := filepath.Join(kustomizePath, filepath.Base(file))
+		if samePath(file, dest) {
+			continue
+		}
+		if _, err := os.Stat(dest); err == nil {
+			cleanup()
+			return nil, fmt.Errorf("staging file %q: %q already exists", file, dest)
+		}
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("staging file %q: %w", file, err)
+		}
+		if err := ioutil.WriteFile(dest, content, info.Mode()); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("staging file %q: %w", file, err)
+		}
+		staged = append(staged, dest)
+	}
+
+	return cleanup, nil
+}
+
+// samePath checks whether two paths point to the same location.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+// stagingCommand checks that the staged file is present while kustomize runs.
+type stagingCommand struct {
+	t      *testutil.T
+	staged string
+}
+
+func (c *stagingCommand) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Args[1] == "build" {
+		c.t.CheckFileExistAndContent(c.staged, []byte("plugin: config"))
+	}
+	return []byte(serviceYAML), nil
+}
+
+func (c *stagingCommand) RunCmd(cmd *exec.Cmd) error {
+	return nil
+}
+
+func TestKustomizeStagedFiles(t *testing.T) {
+	tests := []struct {
+		description string
+		stagedFiles []string
+		existing    string
+		shouldErr   bool
+	}{
+		{
+			description: "staged and cleaned up",
+			stagedFiles: []string{"plugins/config.yaml"},
+		},
+		{
+			description: "missing file",
+			stagedFiles: []string{"plugins/missing.yaml"},
+			shouldErr:   true,
+		},
+		{
+			description: "would overwrite a file",
+			stagedFiles: []string{"plugins/config.yaml"},
+			existing:    "app/config.yaml",
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir().
+				Write("plugins/config.yaml", "plugin: config").
+				Write("app/kustomization.yaml", "resources: [service.yaml]").
+				Chdir()
+			if test.existing != "" {
+				tmpDir.Write(test.existing, "existing")
+			}
+			t.Override(&util.DefaultExecCommand, &stagingCommand{t: t, staged: tmpDir.Path("app/config.yaml")})
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"app"},
+				StagedFiles:    test.stagedFiles,
+			})
+			t.RequireNoError(err)
+
+			err = k.Render(context.Background(), &bytes.Buffer{}, nil, true, "")
+
+			t.CheckError(test.shouldErr, err)
+			if test.existing != "" {
+				t.CheckFileExistAndContent(tmpDir.Path(test.existing), []byte("existing"))
+			} else {
+				t.CheckFalse(util.IsFile(tmpDir.Path("app/config.yaml")))
+			}
+		})
+	}
+}
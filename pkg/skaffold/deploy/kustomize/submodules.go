@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+)
+
+// checkSubmodule reports a missing kustomization that belongs to a git submodule that isn't initialized.
+// Kustomize paths and bases in such a submodule are otherwise mistaken for remote ones, and their files aren't watched.
+func checkSubmodule(path string, fail bool) error {
+	submodule, found := uninitializedSubmodule(path)
+	if !found {
+		return nil
+	}
+
+	err := fmt.Errorf("%s is in git submodule %s, which is not initialized: run `git submodule update --init`", path, submodule)
+	if fail {
+		return err
+	}
+	warnings.Printf("%v", err)
+	return nil
+}
+
+// uninitializedSubmodule returns the path, as declared in its `.gitmodules` file, of the git submodule
+// that holds the given path, when that submodule is not checked out.
+func uninitializedSubmodule(path string) (string, bool) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+
+	for root := filepath.Dir(absPath); ; root = filepath.Dir(root) {
+		for _, submodule := range submodulePaths(filepath.Join(root, ".gitmodules")) {
+			absSubmodule := filepath.Join(root, submodule)
+			if absPath != absSubmodule && !strings.HasPrefix(absPath, absSubmodule+string(filepath.Separator)) {
+				continue
+			}
+			// Checked out submodules have a `.git` file pointing to their repository.
+			if _, err := os.Stat(filepath.Join(absSubmodule, ".git")); err != nil {
+				return submodule, true
+			}
+			return "", false
+		}
+
+		// Don't look past the root of the repository.
+		if util.IsDir(filepath.Join(root, ".git")) || filepath.Dir(root) == root {
+			return "", false
+		}
+	}
+}
+
+// submodulePaths reads the paths of the submodules declared in a `.gitmodules` file.
+func submodulePaths(file string) []string {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "path" {
+			paths = append(paths, filepath.FromSlash(strings.TrimSpace(parts[1])))
+		}
+	}
+	return paths
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/warnings"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeDependenciesUninitializedSubmodule(t *testing.T) {
+	const gitmodules = `[submodule "platform"]
+	path = vendor/platform
+	url = https://github.com/example/platform.git
+`
+
+	tests := []struct {
+		description      string
+		kustomizePath    string
+		initialized      bool
+		fail             bool
+		expectedWarnings []string
+		shouldErr        bool
+	}{
+		{
+			description:      "base in uninitialized submodule",
+			kustomizePath:    "app",
+			expectedWarnings: []string{"vendor/platform/base is in git submodule vendor/platform, which is not initialized: run `git submodule update --init`"},
+		},
+		{
+			description:      "kustomize path in uninitialized submodule",
+			kustomizePath:    "vendor/platform/overlays/dev",
+			expectedWarnings: []string{"vendor/platform/overlays/dev is in git submodule vendor/platform, which is not initialized: run `git submodule update --init`"},
+		},
+		{
+			description:   "fail on uninitialized submodule",
+			kustomizePath: "app",
+			fail:          true,
+			shouldErr:     true,
+		},
+		{
+			description:   "initialized submodule",
+			kustomizePath: "app",
+			initialized:   true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir().
+				Mkdir(".git").
+				Mkdir("vendor/platform").
+				Write(".gitmodules", gitmodules).
+				Write("app/kustomization.yaml", "resources:\n- ../vendor/platform/base\n")
+			if test.initialized {
+				tmpDir.Write("vendor/platform/.git", "gitdir: ../../.git/modules/platform\n")
+			}
+			tmpDir.Chdir()
+			fakeWarner := &warnings.Collect{}
+			t.Override(&warnings.Printf, fakeWarner.Warnf)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+
+			k, err := NewDeployer(&kustomizeConfig{}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:                []string{test.kustomizePath},
+				FailOnUninitializedSubmodules: test.fail,
+			})
+			t.RequireNoError(err)
+
+			_, err = k.Dependencies()
+
+			t.CheckError(test.shouldErr, err)
+			t.CheckDeepEqual(test.expectedWarnings, fakeWarner.Warnings)
+		})
+	}
+}
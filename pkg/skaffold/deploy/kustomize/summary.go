@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// writeEditSummary writes, for each kustomize path, a summary of how the
+// kustomization modifies its bases. Lines are written as YAML comments so that
+// the summary doesn't break the rendered manifests that follow.
+func (k *Deployer) writeEditSummary(out io.Writer) error {
+	kustomizePaths, err := k.kustomizePaths()
+	if err != nil {
+		return err
+	}
+
+	for _, kustomizePath := range kustomizePaths {
+		lines, err := editSummary(kustomizePath)
+		if err != nil {
+			return userErr(err)
+		}
+
+		fmt.Fprintf(out, "# kustomization %s\n", kustomizePath)
+		for _, line := range lines {
+			fmt.Fprintf(out, "#  - %s\n", line)
+		}
+	}
+	return nil
+}
+
+// editSummary lists the transformations declared by the kustomization found in dir.
+func editSummary(dir string) ([]string, error) {
+	path, err := FindKustomizationConfig(dir)
+	if err != nil {
+		return []string{"remote kustomization, no summary available"}, nil
+	}
+
+	content, err := readKustomization(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	add := func(name string, values []string) {
+		if len(values) > 0 {
+			lines = append(lines, fmt.Sprintf("%s: %s", name, strings.Join(values, ", ")))
+		}
+	}
+
+	bases := append([]string{}, content.Bases...)
+	for _, resource := range content.Resources {
+		if local, mode := pathExistsLocally(resource, dir); local && mode.IsDir() {
+			bases = append(bases, resource)
+		}
+	}
+	add("bases", append(bases, content.Components...))
+
+	var patches []string
+	for _, patch := range content.Patches {
+		patches = append(patches, patchSource(patch.Path))
+	}
+	for _, patch := range content.PatchesStrategicMerge {
+		patches = append(patches, patchSource(patch.Path))
+	}
+	for _, patch := range content.PatchesJSON6902 {
+		patches = append(patches, patchSource(patch.Path))
+	}
+	add("patches", patches)
+
+	var images []string
+	for _, image := range content.Images {
+		images = append(images, fmt.Sprintf("%s -> %s", image.Name, replacedImage(image)))
+	}
+	add("images", images)
+
+	add("labels", sortedPairs(content.CommonLabels))
+	add("annotations", sortedPairs(content.CommonAnnotations))
+
+	if content.Namespace != "" {
+		lines = append(lines, "namespace: "+content.Namespace)
+	}
+	if content.NamePrefix != "" || content.NameSuffix != "" {
+		lines = append(lines, fmt.Sprintf("names: %s<name>%s", content.NamePrefix, content.NameSuffix))
+	}
+
+	if len(lines) == 0 {
+		lines = []string{"no transformations"}
+	}
+	return lines, nil
+}
+
+func patchSource(path string) string {
+	if path == "" {
+		return "(inline)"
+	}
+	return path
+}
+
+// replacedImage returns the image reference that kustomize substitutes for an `images` entry.
+func replacedImage(image kustomizeImage) string {
+	name := image.Name
+	if image.NewName != "" {
+		name = image.NewName
+	}
+
+	switch {
+	case image.Digest != "":
+		return name + "@" + image.Digest
+	case image.NewTag != "":
+		return name + ":" + image.NewTag
+	default:
+		return name
+	}
+}
+
+func sortedPairs(m map[string]string) []string {
+	var pairs []string
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
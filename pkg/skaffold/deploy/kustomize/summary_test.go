@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeRenderSummary(t *testing.T) {
+	testutil.Run(t, "", func(t *testutil.T) {
+		t.NewTempDir().
+			Write("base/kustomization.yaml", `resources: [pod.yaml]`).
+			Write("overlay/kustomization.yaml", `resources: [../base]
+namePrefix: dev-
+patchesStrategicMerge: [patch.yaml]
+patches:
+- patch: |-
+    - op: add
+      path: /metadata/annotations
+      value: {}
+images:
+- name: app
+  newName: gcr.io/project/app
+  newTag: v2
+commonLabels:
+  env: dev
+  team: web`).
+			Chdir()
+
+		t.Override(&util.DefaultExecCommand, testutil.
+			CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion112).
+			AndRunOut("kustomize build overlay", `apiVersion: v1
+kind: Pod
+metadata:
+  name: dev-pod`))
+
+		k, err := NewDeployer(&kustomizeConfig{
+			workingDir: ".",
+			RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+		}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+			KustomizePaths: []string{"overlay"},
+			RenderSummary:  true,
+		})
+		t.RequireNoError(err)
+
+		var b bytes.Buffer
+		err = k.Render(context.Background(), &b, nil, true, "")
+
+		t.CheckNoError(err)
+		t.CheckDeepEqual(`# kustomization overlay
+#  - bases: ../base
+#  - patches: (inline), patch.yaml
+#  - images: app -> gcr.io/project/app:v2
+#  - labels: env=dev, team=web
+#  - names: dev-<name>
+apiVersion: v1
+kind: Pod
+metadata:
+  name: dev-pod
+`, b.String())
+	})
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+const (
+	terminatingNamespacesFail = "fail"
+	terminatingNamespacesWait = "wait"
+
+	defaultTerminatingNamespacesTimeout = "5m"
+)
+
+// validateTerminatingNamespaces checks how namespaces that are being deleted are handled.
+func validateTerminatingNamespaces(mode, timeout string) error {
+	switch mode {
+	case "", terminatingNamespacesFail, terminatingNamespacesWait:
+	default:
+		return fmt.Errorf("invalid terminatingNamespaces %q, must be %q or %q", mode, terminatingNamespacesFail, terminatingNamespacesWait)
+	}
+
+	if timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid terminatingNamespacesTimeout %q, must be a positive duration", timeout)
+		}
+	}
+	return nil
+}
+
+// checkTerminatingNamespaces looks for target namespaces in the `Terminating` phase. Depending on
+// the configuration, it fails with an actionable error or waits for those namespaces to be deleted.
+func (k *Deployer) checkTerminatingNamespaces(ctx context.Context, out io.Writer, namespaces []string) error {
+	targets := util.NewStringSet()
+	if k.kubectl.Namespace != "" {
+		targets.Insert(k.kubectl.Namespace)
+	}
+	targets.Insert(namespaces...)
+
+	var terminating []string
+	for _, ns := range targets.ToList() {
+		phase, err := k.kubectl.RunOut(ctx, "get", "namespace", ns, "--ignore-not-found", "-o", "jsonpath={.status.phase}")
+		if err != nil {
+			return fmt.Errorf("checking the phase of namespace %q: %w", ns, err)
+		}
+		if strings.TrimSpace(string(phase)) == "Terminating" {
+			terminating = append(terminating, ns)
+		}
+	}
+	sort.Strings(terminating)
+
+	if len(terminating) == 0 {
+		return nil
+	}
+
+	if k.TerminatingNamespaces == terminatingNamespacesFail {
+		return userErr(fmt.Errorf("namespaces %s are being deleted and can't receive new resources: "+
+			"wait for the deletion to finish, or set `terminatingNamespaces: wait`", strings.Join(terminating, ", ")))
+	}
+
+	timeout := k.TerminatingNamespacesTimeout
+	if timeout == "" {
+		timeout = defaultTerminatingNamespacesTimeout
+	}
+	for _, ns := range terminating {
+		fmt.Fprintf(out, "Waiting for namespace %s to be deleted\n", ns)
+		if err := k.kubectl.Run(ctx, nil, out, "wait", "--for=delete", "--timeout="+timeout, "namespace/"+ns); err != nil {
+			return userErr(fmt.Errorf("waiting for namespace %q to be deleted: %w", ns, err))
+		}
+	}
+	return nil
+}
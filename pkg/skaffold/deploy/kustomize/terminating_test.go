@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeTerminatingNamespaces(t *testing.T) {
+	const getPhase = "kubectl --context kubecontext --namespace testNamespace get namespace testNamespace --ignore-not-found -o jsonpath={.status.phase}"
+
+	tests := []struct {
+		description string
+		mode        string
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "active namespace",
+			mode:        "fail",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRunOut(getPhase, "Active").
+				AndRun(applyCommand),
+		},
+		{
+			description: "terminating namespace fails",
+			mode:        "fail",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRunOut(getPhase, "Terminating"),
+			shouldErr: true,
+		},
+		{
+			description: "terminating namespace waited for",
+			mode:        "wait",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRunOut(getPhase, "Terminating").
+				AndRun("kubectl --context kubecontext --namespace testNamespace wait --for=delete --timeout=5m namespace/testNamespace").
+				AndRun(applyCommand),
+		},
+		{
+			description: "not checked by default",
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRun(applyCommand),
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:        []string{"."},
+				TerminatingNamespaces: test.mode,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestValidateTerminatingNamespaces(t *testing.T) {
+	testutil.CheckError(t, false, validateTerminatingNamespaces("", ""))
+	testutil.CheckError(t, false, validateTerminatingNamespaces("wait", "90s"))
+	testutil.CheckError(t, true, validateTerminatingNamespaces("ignore", ""))
+	testutil.CheckError(t, true, validateTerminatingNamespaces("wait", "soon"))
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// timeNow returns the current time. For testing.
+var timeNow = time.Now
+
+// phaseTimings records how long each phase of the last deploy took.
+type phaseTimings struct {
+	Build     time.Duration // running `kustomize build`
+	Transform time.Duration // transforming, filtering and sorting the built manifests
+	Apply     time.Duration // applying the manifests
+}
+
+// since returns the time elapsed since start.
+func since(start time.Time) time.Duration {
+	return timeNow().Sub(start)
+}
+
+// reportPhaseTimings writes how long each phase of the last deploy took.
+func (k *Deployer) reportPhaseTimings(out io.Writer) {
+	logrus.Debugf("kustomize deploy timings: %+v", k.timings)
+	fmt.Fprintf(out, "Deploy timings: build %v, transform %v, apply %v\n", k.timings.Build, k.timings.Transform, k.timings.Apply)
+}
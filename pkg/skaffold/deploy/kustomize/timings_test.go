@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeDeployPhaseTimings(t *testing.T) {
+	tests := []struct {
+		description string
+		report      bool
+		expected    string
+	}{
+		{
+			description: "reported",
+			report:      true,
+			expected:    "Deploy timings: build 1s, transform 2s, apply 1s\n",
+		},
+		{
+			description: "recorded but not reported",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			// Each reading of the clock moves it forward by a second.
+			clock := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			t.Override(&timeNow, func() time.Time {
+				clock = clock.Add(time.Second)
+				return clock
+			})
+			t.Override(&util.DefaultExecCommand, testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", serviceYAML).
+				AndRun(applyCommand))
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths:     []string{"."},
+				ReportPhaseTimings: test.report,
+			})
+			t.RequireNoError(err)
+
+			var out bytes.Buffer
+			err = k.Deploy(context.Background(), &out, nil)
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(phaseTimings{Build: time.Second, Transform: 2 * time.Second, Apply: time.Second}, k.timings)
+			t.CheckDeepEqual(test.expected, out.String())
+		})
+	}
+}
@@ -26,28 +26,68 @@ import (
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
 )
 
+// defaultMaxDependencyDepth is the maximum number of nested bases followed when collecting dependencies.
+const defaultMaxDependencyDepth = 100
+
+// dependencyOptions configures the optional checks run while collecting dependencies.
+type dependencyOptions struct {
+	// warnTrackedSecrets warns about secret generator files that are not ignored by git.
+	warnTrackedSecrets bool
+
+	// maxDepth is the maximum number of nested bases to follow. Zero means defaultMaxDependencyDepth.
+	maxDepth int
+
+	// remoteBase, when set, is called with each base or resource that points to a git repository.
+	remoteBase func(target string)
+
+	// warnInvalidEncodings warns about generator files that are not valid UTF-8.
+	warnInvalidEncodings bool
+
+	// warnDuplicateEnvKeys warns about generator env files that set a key more than once.
+	warnDuplicateEnvKeys bool
+
+	// failOnUninitializedSubmodules fails, instead of warning, on directories of git submodules that are not initialized.
+	failOnUninitializedSubmodules bool
+
+	// patch, when set, is called with the name and the content of each inline patch and local patch file.
+	patch func(name string, content []byte)
+
+	// plugin, when set, is called with each entry of the generators, transformers and validators.
+	plugin func(kustomizationPath, dir, entry string)
+}
+
 // DependenciesForKustomization finds common kustomize artifacts relative to the
 // provided working dir, and collects them into a list of files to be passed
 // to the file watcher.
 func DependenciesForKustomization(dir string) ([]string, error) {
+	return dependenciesForKustomization(dir, dependencyOptions{}, 0)
+}
+
+func dependenciesForKustomization(dir string, opts dependencyOptions, depth int) ([]string, error) {
 	var deps []string
 
+	maxDepth := opts.maxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultMaxDependencyDepth
+	}
+	if depth > maxDepth {
+		return nil, fmt.Errorf("kustomization %s is nested more than %d levels deep, check for overly long base chains", dir, maxDepth)
+	}
+
 	path, err := FindKustomizationConfig(dir)
 	if err != nil {
+		if err := checkSubmodule(dir, opts.failOnUninitializedSubmodules); err != nil {
+			return nil, err
+		}
 		// No kustomization config found so assume it's remote and stop traversing
 		return deps, nil
 	}
 
-	buf, err := ioutil.ReadFile(path)
+	content, err := readKustomization(path)
 	if err != nil {
 		return nil, err
 	}
 
-	content := kustomization{}
-	if err := yaml.Unmarshal(buf, &content); err != nil {
-		return nil, err
-	}
-
 	deps = append(deps, path)
 
 	candidates := append(content.Bases, content.Resources...)
@@ -59,11 +99,18 @@ func DependenciesForKustomization(dir string) ([]string, error) {
 		// handle invalid/missing files.
 		local, mode := pathExistsLocally(candidate, dir)
 		if !local {
+			if isRemoteBase(candidate) {
+				if opts.remoteBase != nil {
+					opts.remoteBase(candidate)
+				}
+			} else if err := checkSubmodule(filepath.Join(dir, candidate), opts.failOnUninitializedSubmodules); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		if mode.IsDir() {
-			candidateDeps, err := DependenciesForKustomization(filepath.Join(dir, candidate))
+			candidateDeps, err := dependenciesForKustomization(filepath.Join(dir, candidate), opts, depth+1)
 			if err != nil {
 				return nil, err
 			}
@@ -74,7 +121,21 @@ func DependenciesForKustomization(dir string) ([]string, error) {
 	}
 
 	for _, patch := range content.PatchesStrategicMerge {
-		if patch.Path != "" {
+		if opts.patch != nil {
+			visitPatch(opts.patch, path, dir, patch.Path, patch.Patch)
+		}
+		if patch.Path == "" {
+			continue
+		}
+
+		// Patches can be grouped in a directory, whose files are all watched.
+		if local, mode := pathExistsLocally(patch.Path, dir); local && mode.IsDir() {
+			files, err := strategicMergePatchFiles(filepath.Join(dir, patch.Path))
+			if err != nil {
+				return nil, err
+			}
+			deps = append(deps, files...)
+		} else {
 			deps = append(deps, filepath.Join(dir, patch.Path))
 		}
 	}
@@ -82,6 +143,9 @@ func DependenciesForKustomization(dir string) ([]string, error) {
 	deps = append(deps, util.AbsolutePaths(dir, content.CRDs)...)
 
 	for _, patch := range content.Patches {
+		if opts.patch != nil {
+			visitPatch(opts.patch, path, dir, patch.Path, patch.Patch)
+		}
 		if patch.Path != "" {
 			deps = append(deps, filepath.Join(dir, patch.Path))
 		}
@@ -93,6 +157,14 @@ func DependenciesForKustomization(dir string) ([]string, error) {
 		}
 	}
 
+	if opts.plugin != nil {
+		for _, entries := range [][]string{content.Generators, content.Transformers, content.Validators} {
+			for _, entry := range entries {
+				opts.plugin(path, dir, entry)
+			}
+		}
+	}
+
 	for _, generator := range content.ConfigMapGenerator {
 		deps = append(deps, util.AbsolutePaths(dir, generator.Files)...)
 		envs := generator.Envs
@@ -100,6 +172,13 @@ func DependenciesForKustomization(dir string) ([]string, error) {
 			envs = append(envs, generator.Env)
 		}
 		deps = append(deps, util.AbsolutePaths(dir, envs)...)
+
+		if opts.warnInvalidEncodings {
+			warnInvalidEncodings(dir, append(generatorFilePaths(generator.Files), envs...))
+		}
+		if opts.warnDuplicateEnvKeys {
+			warnDuplicateEnvKeys(dir, envs)
+		}
 	}
 
 	for _, generator := range content.SecretGenerator {
@@ -109,15 +188,118 @@ func DependenciesForKustomization(dir string) ([]string, error) {
 			envs = append(envs, generator.Env)
 		}
 		deps = append(deps, util.AbsolutePaths(dir, envs)...)
+
+		if opts.warnTrackedSecrets {
+			warnTrackedSecrets(dir, append(generatorFilePaths(generator.Files), envs...))
+		}
+		if opts.warnInvalidEncodings {
+			warnInvalidEncodings(dir, append(generatorFilePaths(generator.Files), envs...))
+		}
+		if opts.warnDuplicateEnvKeys {
+			warnDuplicateEnvKeys(dir, envs)
+		}
 	}
 
 	return deps, nil
 }
 
+// CanonicalKustomization reads the kustomization config found in the provided dir
+// and re-emits it in a canonical form: keys are sorted, empty fields are dropped and
+// the deprecated `env` field of generators is folded into `envs`. All the other fields,
+// including `apiVersion`, `kind` and the fields Skaffold doesn't know about, are kept.
+func CanonicalKustomization(dir string) ([]byte, error) {
+	path, err := FindKustomizationConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content := map[string]interface{}{}
+	if err := yaml.Unmarshal(buf, &content); err != nil {
+		return nil, err
+	}
+
+	for _, field := range []string{"configMapGenerator", "secretGenerator"} {
+		generators, _ := content[field].([]interface{})
+		for _, g := range generators {
+			generator, ok := g.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if env, ok := generator["env"].(string); ok && env != "" {
+				envs, _ := generator["envs"].([]interface{})
+				generator["envs"] = append(envs, env)
+				delete(generator, "env")
+			}
+		}
+	}
+
+	return yaml.Marshal(dropEmptyFields(content))
+}
+
+// dropEmptyFields recursively removes the fields of maps whose value is null, an empty string,
+// an empty list or an empty map.
+func dropEmptyFields(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			field = dropEmptyFields(field)
+			if isEmptyField(field) {
+				delete(v, key)
+			} else {
+				v[key] = field
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = dropEmptyFields(item)
+		}
+	}
+	return value
+}
+
+// isEmptyField checks whether a parsed YAML value is null, an empty string, an empty list or an empty map.
+func isEmptyField(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// readKustomization parses the kustomization config at the provided path.
+func readKustomization(path string) (kustomization, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return kustomization{}, err
+	}
+
+	content := kustomization{}
+	if err := yaml.Unmarshal(buf, &content); err != nil {
+		return kustomization{}, err
+	}
+	return content, nil
+}
+
 // FindKustomizationConfig finds the kustomization config relative to the provided dir.
 // A Kustomization config must be at the root of the directory. Kustomize will
 // error if more than one of these files exists so order doesn't matter.
 func FindKustomizationConfig(dir string) (string, error) {
+	if path, found := findKustomizationFile(dir); found {
+		return path, nil
+	}
+
 	for _, candidate := range KustomizeFilePaths {
 		if local, _ := pathExistsLocally(candidate, dir); local {
 			return filepath.Join(dir, candidate), nil
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// varReference matches the `$(VAR)` references left in the output when a kustomize var is not resolved.
+var varReference = regexp.MustCompile(`^\$\(([A-Za-z_][A-Za-z0-9_.\-]*)\)`)
+
+// checkUnresolvedVars fails if the rendered manifests still hold `$(VAR)` references.
+// References to environment variables declared by the resource's containers are expanded
+// by Kubernetes, so they are not reported. Neither are the references of containers that
+// load their environment with `envFrom`, since the variables it declares are unknown, nor
+// the `$$(VAR)` escapes, which Kubernetes never expands.
+func checkUnresolvedVars(manifests manifest.ManifestList) error {
+	var unresolved []string
+
+	for _, m := range manifests {
+		r, err := parseResource(m)
+		if err != nil {
+			return err
+		}
+
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal(m, &obj); err != nil {
+			return fmt.Errorf("reading Kubernetes YAML: %w", err)
+		}
+
+		envVars := util.NewStringSet()
+		collectEnvVars(obj, envVars)
+
+		var found []string
+		findVarReferences("", obj, envVars, &found)
+		sort.Strings(found)
+		for _, f := range found {
+			unresolved = append(unresolved, fmt.Sprintf("%s: %s", r, f))
+		}
+	}
+
+	if len(unresolved) > 0 {
+		return userErr(fmt.Errorf("unresolved kustomize vars in the rendered manifests:\n - %s", strings.Join(unresolved, "\n - ")))
+	}
+	return nil
+}
+
+// collectEnvVars collects the names of the environment variables declared anywhere in the resource.
+func collectEnvVars(value interface{}, envVars util.StringSet) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if env, ok := child.([]interface{}); ok && key == "env" {
+				for _, item := range env {
+					if envVar, ok := item.(map[string]interface{}); ok {
+						if name, ok := envVar["name"].(string); ok {
+							envVars.Insert(name)
+						}
+					}
+				}
+			}
+			collectEnvVars(child, envVars)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectEnvVars(child, envVars)
+		}
+	}
+}
+
+// findVarReferences records the `$(VAR)` references found in string values, along with their path.
+func findVarReferences(path string, value interface{}, envVars util.StringSet, found *[]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, found := v["envFrom"]; found {
+			return
+		}
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			findVarReferences(childPath, child, envVars, found)
+		}
+	case []interface{}:
+		for i, child := range v {
+			findVarReferences(fmt.Sprintf("%s[%d]", path, i), child, envVars, found)
+		}
+	case string:
+		for _, match := range varReferences(v) {
+			if !envVars.Contains(match[1]) {
+				*found = append(*found, fmt.Sprintf("%s at %s", match[0], path))
+			}
+		}
+	}
+}
+
+// varReferences returns the `$(VAR)` references of a string, along with the name of their variable.
+// The `$$` escapes are skipped.
+func varReferences(s string) [][]string {
+	var matches [][]string
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] != '$' {
+			continue
+		}
+		if s[i+1] == '$' {
+			i++
+			continue
+		}
+		if match := varReference.FindStringSubmatch(s[i:]); match != nil {
+			matches = append(matches, match)
+			i += len(match[0]) - 1
+		}
+	}
+	return matches
+}
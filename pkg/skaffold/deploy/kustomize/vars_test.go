@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/manifest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestCheckUnresolvedVars(t *testing.T) {
+	tests := []struct {
+		description string
+		manifest    string
+		expectedErr string
+	}{
+		{
+			description: "unresolved var",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  url: http://$(SERVICE_NAME):8080`,
+			expectedErr: "ConfigMap/config: $(SERVICE_NAME) at data.url",
+		},
+		{
+			description: "unresolved var in a list",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: app
+    args:
+    - --host=$(SERVICE_NAME)`,
+			expectedErr: "Pod/pod: $(SERVICE_NAME) at spec.containers[0].args[0]",
+		},
+		{
+			description: "container env var",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: app
+    args:
+    - --pod=$(POD_NAME)
+    env:
+    - name: POD_NAME
+      valueFrom:
+        fieldRef:
+          fieldPath: metadata.name`,
+		},
+		{
+			description: "escaped var",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: app
+    args:
+    - --literal=$$(SERVICE_NAME)
+    - --host=$$$(SERVICE_NAME)`,
+			expectedErr: "Pod/pod: $(SERVICE_NAME) at spec.containers[0].args[1]",
+		},
+		{
+			description: "only escaped vars",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: app
+    command: [sh, -c, echo $$(date)]
+    args:
+    - --literal=$$(SERVICE_NAME)`,
+		},
+		{
+			description: "container env from a config map",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: app
+    args:
+    - --host=$(SERVICE_HOST)
+    envFrom:
+    - configMapRef:
+        name: settings`,
+		},
+		{
+			description: "envFrom only applies to its container",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: app
+    envFrom:
+    - configMapRef:
+        name: settings
+  - name: sidecar
+    args:
+    - --host=$(SERVICE_HOST)`,
+			expectedErr: "Pod/pod: $(SERVICE_HOST) at spec.containers[1].args[0]",
+		},
+		{
+			description: "no var",
+			manifest:    serviceYAML,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			err := checkUnresolvedVars(manifest.ManifestList{[]byte(test.manifest)})
+
+			if test.expectedErr == "" {
+				t.CheckNoError(err)
+			} else {
+				t.CheckErrorContains(test.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+)
+
+const defaultWaitTimeout = "5m"
+
+// validateWaits checks the `kubectl wait` conditions run after the resources are applied.
+func validateWaits(waits []latestV1.KustomizeWait) error {
+	for _, w := range waits {
+		if w.Resource == "" {
+			return fmt.Errorf("invalid waitFor: resource is required")
+		}
+		if strings.Contains(w.Resource, "/") && w.Selector != "" {
+			return fmt.Errorf("invalid waitFor %q: a named resource can't have a selector", w.Resource)
+		}
+		if w.For != "delete" && !strings.HasPrefix(w.For, "condition=") && !strings.HasPrefix(w.For, "jsonpath=") {
+			return fmt.Errorf("invalid waitFor %q: for must be `delete`, `condition=<name>` or `jsonpath=<expression>`, not %q", w.Resource, w.For)
+		}
+		if w.Timeout != "" {
+			if timeout, err := time.ParseDuration(w.Timeout); err != nil || timeout <= 0 {
+				return fmt.Errorf("invalid waitFor %q: timeout %q must be a positive duration", w.Resource, w.Timeout)
+			}
+		}
+	}
+	return nil
+}
+
+// waitForConditions runs `kubectl wait` for each configured condition, in order.
+func (k *Deployer) waitForConditions(ctx context.Context, out io.Writer) error {
+	for _, w := range k.WaitFor {
+		timeout := w.Timeout
+		if timeout == "" {
+			timeout = defaultWaitTimeout
+		}
+
+		args := []string{w.Resource}
+		switch {
+		case w.Selector != "":
+			args = append(args, "--selector="+w.Selector)
+		case !strings.Contains(w.Resource, "/"):
+			args = append(args, "--all")
+		}
+		args = append(args, "--for="+w.For, "--timeout="+timeout)
+
+		if err := k.kubectl.RunInNamespace(ctx, nil, out, "wait", w.Namespace, args...); err != nil {
+			return userErr(fmt.Errorf("waiting for %s %s: %w", w.Resource, w.For, err))
+		}
+	}
+	return nil
+}
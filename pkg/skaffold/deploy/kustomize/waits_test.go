@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/label"
+	deployutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/client"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner/runcontext"
+	latestV1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizeWaitFor(t *testing.T) {
+	tests := []struct {
+		description string
+		waits       []latestV1.KustomizeWait
+		commands    util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "conditions waited for in order",
+			waits: []latestV1.KustomizeWait{
+				{Resource: "certificates.cert-manager.io", Selector: "app=web", For: "condition=Ready", Timeout: "2m"},
+				{Resource: "deployment/web", For: "condition=Available", Namespace: "prod"},
+				{Resource: "jobs", For: "condition=Complete"},
+			},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRun(applyCommand).
+				AndRun("kubectl --context kubecontext --namespace testNamespace wait certificates.cert-manager.io --selector=app=web --for=condition=Ready --timeout=2m").
+				AndRun("kubectl --context kubecontext --namespace prod wait deployment/web --for=condition=Available --timeout=5m").
+				AndRun("kubectl --context kubecontext --namespace testNamespace wait jobs --all --for=condition=Complete --timeout=5m"),
+		},
+		{
+			description: "condition not met",
+			waits:       []latestV1.KustomizeWait{{Resource: "deployment/web", For: "condition=Available"}},
+			commands: testutil.
+				CmdRunOut("kubectl version --client -ojson", kubectl.KubectlVersion118).
+				AndRunOut("kustomize build .", deploymentYAML).
+				AndRun(applyCommand).
+				AndRunErr("kubectl --context kubecontext --namespace testNamespace wait deployment/web --for=condition=Available --timeout=5m", errors.New("timed out")),
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&util.DefaultExecCommand, test.commands)
+			t.Override(&client.Client, deployutil.MockK8sClient)
+			t.Override(&KustomizeBinaryCheck, func() bool { return true })
+			t.NewTempDir().Chdir()
+
+			k, err := NewDeployer(&kustomizeConfig{
+				workingDir: ".",
+				RunContext: runcontext.RunContext{Opts: config.SkaffoldOptions{Namespace: kubectl.TestNamespace}},
+			}, &label.DefaultLabeller{}, &latestV1.KustomizeDeploy{
+				KustomizePaths: []string{"."},
+				WaitFor:        test.waits,
+			})
+			t.RequireNoError(err)
+
+			err = k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestValidateWaits(t *testing.T) {
+	tests := []struct {
+		description string
+		wait        latestV1.KustomizeWait
+		shouldErr   bool
+	}{
+		{description: "condition", wait: latestV1.KustomizeWait{Resource: "deployment", Selector: "app=web", For: "condition=Available"}},
+		{description: "jsonpath", wait: latestV1.KustomizeWait{Resource: "foo/bar", For: "jsonpath={.status.phase}=Running"}},
+		{description: "delete", wait: latestV1.KustomizeWait{Resource: "pods", For: "delete", Timeout: "30s"}},
+		{description: "no resource", wait: latestV1.KustomizeWait{For: "delete"}, shouldErr: true},
+		{description: "unknown condition", wait: latestV1.KustomizeWait{Resource: "pods", For: "Ready"}, shouldErr: true},
+		{description: "selector on a named resource", wait: latestV1.KustomizeWait{Resource: "deployment/web", Selector: "app=web", For: "delete"}, shouldErr: true},
+		{description: "invalid timeout", wait: latestV1.KustomizeWait{Resource: "pods", For: "delete", Timeout: "-1s"}, shouldErr: true},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckError(test.shouldErr, validateWaits([]latestV1.KustomizeWait{test.wait}))
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
 	sErrors "github.com/GoogleContainerTools/skaffold/pkg/skaffold/errors"
+	"github.com/GoogleContainerTools/skaffold/proto/enums"
 	proto "github.com/GoogleContainerTools/skaffold/proto/v2"
 )
 
@@ -50,6 +51,17 @@ func DeploySucceeded(id int) {
 	})
 }
 
+// DeployResourceApplied notifies of the outcome of applying a single resource,
+// e.g. `created`, `configured`, `unchanged` or `failed`.
+func DeployResourceApplied(resource, status string) {
+	handler.handleSkaffoldLogEvent(&proto.SkaffoldLogEvent{
+		TaskId:    fmt.Sprintf("%s-%d", constants.Deploy, handler.iteration),
+		SubtaskId: resource,
+		Level:     enums.LogLevel_INFO,
+		Message:   fmt.Sprintf("%s %s", resource, status),
+	})
+}
+
 func (ev *eventHandler) handleDeploySubtaskEvent(e *proto.DeploySubtaskEvent) {
 	ev.handle(&proto.Event{
 		EventType: &proto.Event_DeploySubtaskEvent{
@@ -71,3 +71,21 @@ func TestHandleDeploySubtaskEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestDeployResourceApplied(t *testing.T) {
+	defer func() { handler = newHandler() }()
+	handler = newHandler()
+	handler.state = emptyState(mockCfg([]latestV1.Pipeline{{}}, "test"))
+
+	DeployResourceApplied("deployment.apps/web", "configured")
+
+	wait(t, func() bool {
+		handler.logLock.Lock()
+		defer handler.logLock.Unlock()
+		if len(handler.eventLog) != 1 {
+			return false
+		}
+		logEvent := handler.eventLog[0].GetSkaffoldLogEvent()
+		return logEvent.GetSubtaskId() == "deployment.apps/web" && logEvent.GetMessage() == "deployment.apps/web configured"
+	})
+}
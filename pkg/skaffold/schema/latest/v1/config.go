@@ -614,7 +614,8 @@ type HelmDeployFlags struct {
 
 // KustomizeDeploy *beta* uses the `kustomize` CLI to "patch" a deployment for a target environment.
 type KustomizeDeploy struct {
-	// KustomizePaths is the path to Kustomization files.
+	// KustomizePaths is the path to Kustomization files. Paths can also be `file://` URLs, or `oci://` references
+	// to kustomizations stored as OCI artifacts, which must be pinned to a tag or a digest.
 	// Defaults to `["."]`.
 	KustomizePaths []string `yaml:"paths,omitempty" skaffold:"filepath"`
 
@@ -627,10 +628,625 @@ type KustomizeDeploy struct {
 	// DefaultNamespace is the default namespace passed to kubectl on deployment if no other override is given.
 	DefaultNamespace *string `yaml:"defaultNamespace,omitempty"`
 
+	// WaitForCRDs when set to `true`, applies CustomResourceDefinitions before any other resource
+	// and waits for each of them to be established before applying the custom resources.
+	WaitForCRDs bool `yaml:"waitForCRDs,omitempty"`
+
+	// CRDWaitTimeout is the maximum time to wait for a CustomResourceDefinition to be established (e.g. `30s`).
+	// Defaults to `60s`.
+	CRDWaitTimeout string `yaml:"crdWaitTimeout,omitempty"`
+
+	// ApplyRetries is the number of times `kubectl apply` is retried when it fails with a transient
+	// API server error, such as an etcd leader election. Validation and admission errors are never retried.
+	// Defaults to `0`.
+	ApplyRetries int `yaml:"applyRetries,omitempty"`
+
+	// WarnOnTrackedSecrets when set to `true`, warns about files referenced by a `secretGenerator`
+	// that are not ignored by git, to avoid committing secrets by mistake.
+	WarnOnTrackedSecrets bool `yaml:"warnOnTrackedSecrets,omitempty"`
+
+	// RenderSummary when set to `true`, `skaffold render` prints a summary of how each kustomization
+	// modifies its bases (patches, images, labels...) ahead of the rendered manifests.
+	RenderSummary bool `yaml:"renderSummary,omitempty"`
+
+	// AllowedAPIGroups when not empty, only resources from these API groups are deployed.
+	// The core API group is written `core`.
+	AllowedAPIGroups []string `yaml:"allowedAPIGroups,omitempty"`
+
+	// DeniedAPIGroups lists the API groups whose resources must not be deployed, e.g. `rbac.authorization.k8s.io`.
+	// The core API group is written `core`.
+	DeniedAPIGroups []string `yaml:"deniedAPIGroups,omitempty"`
+
+	// SkipDeniedAPIGroups when set to `true`, resources from disallowed API groups are skipped with a warning
+	// instead of failing the deploy.
+	SkipDeniedAPIGroups bool `yaml:"skipDeniedAPIGroups,omitempty"`
+
+	// PauseRollouts when set to `true`, pauses the rollout of the deployed Deployments before applying
+	// the manifests and resumes them afterwards, so that all the changes are rolled out at once.
+	PauseRollouts bool `yaml:"pauseRollouts,omitempty"`
+
+	// KustomizeLogLevel controls what happens to the messages that kustomize prints on stderr while building.
+	// Valid values are `none` (discarded), `debug` (sent to Skaffold's debug logs) and `info` (printed to the output).
+	// Defaults to `none`.
+	KustomizeLogLevel string `yaml:"kustomizeLogLevel,omitempty"`
+
+	// ReplaceArtifacts restricts the image replacement to the artifacts with these image names.
+	// Images of the other artifacts keep the tag defined in the manifests.
+	// Defaults to all the built artifacts.
+	ReplaceArtifacts []string `yaml:"replaceArtifacts,omitempty"`
+
+	// RenderSeparator controls where the `---` document separator is written in the rendered manifests:
+	// `between` consecutive documents, or `leading`, before every document including the first one.
+	// Defaults to `between`.
+	RenderSeparator string `yaml:"renderSeparator,omitempty"`
+
+	// RenderTrailingSeparator when set to `true`, writes a `---` document separator after the last rendered manifest.
+	RenderTrailingSeparator bool `yaml:"renderTrailingSeparator,omitempty"`
+
+	// RenderTrailingNewline when set to `true`, ends the rendered manifests with a newline.
+	// By default, the output ends with the last line of the last manifest.
+	RenderTrailingNewline bool `yaml:"renderTrailingNewline,omitempty"`
+
+	// RenderLineEndings sets the line endings of the rendered manifests, either `lf` or `crlf`,
+	// whatever the line endings of the kustomization files.
+	// Defaults to `lf`.
+	RenderLineEndings string `yaml:"renderLineEndings,omitempty"`
+
+	// RenderSourceMarkers when set to `true`, writes a `# source: <path>` comment before the resources rendered
+	// from each kustomize path, to tell which path a resource comes from when several paths are rendered.
+	RenderSourceMarkers bool `yaml:"renderSourceMarkers,omitempty"`
+
+	// ApplyPlugin is the name of a kubectl plugin subcommand, e.g. `apply-set`, used instead of `kubectl apply`.
+	// The plugin must read the manifests from stdin with `-f -`, like `kubectl apply` does.
+	ApplyPlugin string `yaml:"applyPlugin,omitempty"`
+
+	// MaxDependencyDepth is the maximum number of nested bases followed when collecting
+	// the files to watch. Deeper kustomizations fail with an error.
+	// Defaults to `100`.
+	MaxDependencyDepth int `yaml:"maxDependencyDepth,omitempty"`
+
+	// WatchRemoteBases when set to `true`, polls the git repositories of remote bases and resources
+	// so that `skaffold dev` redeploys when the commit they point to changes.
+	WatchRemoteBases bool `yaml:"watchRemoteBases,omitempty"`
+
+	// RemoteBasesPollInterval is how often remote bases are checked for updates (e.g. `30s`).
+	// Defaults to `1m`.
+	RemoteBasesPollInterval string `yaml:"remoteBasesPollInterval,omitempty"`
+
+	// ReportRemoteBaseRefs when set to `true`, prints the ref, such as a tag or a commit, that each remote base
+	// or resource is pinned to, to record where the rendered resources come from.
+	ReportRemoteBaseRefs bool `yaml:"reportRemoteBaseRefs,omitempty"`
+
+	// WarnFloatingRemoteBases when set to `true`, warns about remote bases and resources that are not pinned:
+	// those without a `ref` or `version`, or following `HEAD`, `main` or `master`.
+	WarnFloatingRemoteBases bool `yaml:"warnFloatingRemoteBases,omitempty"`
+
+	// AdoptHelmResources when set to `true`, removes the Helm ownership label and annotations
+	// (`app.kubernetes.io/managed-by: Helm`, `meta.helm.sh/release-name` and `meta.helm.sh/release-namespace`)
+	// from the rendered resources and from the matching live resources before applying, so that
+	// resources previously installed with Helm can be taken over by Skaffold.
+	AdoptHelmResources bool `yaml:"adoptHelmResources,omitempty"`
+
+	// ValidateAPIVersions when set to `true`, makes `skaffold render` query the API versions served by the
+	// target cluster and warn about resources using a deprecated or unavailable `apiVersion`.
+	// Requires access to the cluster, so it is skipped with `--offline`.
+	ValidateAPIVersions bool `yaml:"validateAPIVersions,omitempty"`
+
+	// StagedFiles are files referenced by `buildArgs`, such as plugin configurations, that must be
+	// co-located with the kustomization. They are copied into each kustomize path before running
+	// `kustomize build` and removed afterwards.
+	StagedFiles []string `yaml:"stagedFiles,omitempty" skaffold:"filepath"`
+
+	// ResourceEvents when set to `true`, sends a deploy event for each applied resource, with its status
+	// as reported by `kubectl apply`: `created`, `configured`, `unchanged` or `failed`.
+	ResourceEvents bool `yaml:"resourceEvents,omitempty"`
+
+	// ServerSidePreview when set to `true`, applies the manifests with server-side apply and,
+	// beforehand, prints how the live resources would change, as predicted by a server-side dry-run.
+	ServerSidePreview bool `yaml:"serverSidePreview,omitempty"`
+
+	// ArtifactPathTemplate derives an additional kustomize path from the image name of each artifact
+	// built by the pipeline, e.g. `overlays/{{.ImageName}}`. Each derived path must be an existing directory.
+	ArtifactPathTemplate string `yaml:"artifactPathTemplate,omitempty"`
+
+	// ApplyConcurrency is the maximum number of namespaces applied concurrently. CustomResourceDefinitions
+	// and Namespaces are always applied first. Defaults to `1`, applying all the resources at once.
+	ApplyConcurrency int `yaml:"applyConcurrency,omitempty"`
+
+	// MinifyRendered when set to `true`, removes the `status` and the fields populated by the API server,
+	// such as `creationTimestamp: null`, from the rendered manifests.
+	MinifyRendered bool `yaml:"minifyRendered,omitempty"`
+
+	// ApplyByKind when set to `true`, runs one `kubectl apply` per resource kind, in the order the kinds
+	// first appear in the rendered manifests. This helps operators that expect a complete set of resources.
+	ApplyByKind bool `yaml:"applyByKind,omitempty"`
+
+	// ApplyByDependencies when set to `true`, applies each resource on its own as soon as the resources it
+	// depends on are applied, running up to `applyConcurrency` applies at the same time. A resource depends
+	// on the CustomResourceDefinition of its kind, on its Namespace and on the resources listed in its
+	// `skaffold.dev/depends-on` annotation.
+	ApplyByDependencies bool `yaml:"applyByDependencies,omitempty"`
+
+	// FailOnUnresolvedVars when set to `true`, fails the deployment if the rendered manifests still hold
+	// `$(VAR)` references, left behind by kustomize `vars` that could not be resolved.
+	// References to environment variables declared by the containers are allowed, and so are the references of
+	// containers with `envFrom` and the `$$(VAR)` escapes.
+	FailOnUnresolvedVars bool `yaml:"failOnUnresolvedVars,omitempty"`
+
+	// RolloutStatus waits for the rollout of the deployed workloads of the listed kinds, with `kubectl rollout status`.
+	RolloutStatus []KustomizeRolloutStatus `yaml:"rolloutStatus,omitempty"`
+
+	// WaitFor lists the conditions, checked with `kubectl wait`, that the deployed resources must meet
+	// after they are applied, for example the readiness of custom resources. They are checked in order.
+	WaitFor []KustomizeWait `yaml:"waitFor,omitempty"`
+
+	// ReadinessCommand is a command that must succeed, after the resources are applied and waited for, before the
+	// deployment is considered successful, for example to run application health checks. It's called with each
+	// deployed resource as an argument, written `<namespace>/<kind>.<group>/<name>`, and its output is streamed.
+	ReadinessCommand []string `yaml:"readinessCommand,omitempty"`
+
+	// ReadinessTimeout is how long the `readinessCommand` can run. Defaults to `5m`.
+	ReadinessTimeout string `yaml:"readinessTimeout,omitempty"`
+
+	// Depfile when set, is a file where the dependencies of the kustomizations are written each time
+	// they are computed, so that external build systems can track them.
+	Depfile string `yaml:"depfile,omitempty"`
+
+	// DepfileFormat is the format of the `depfile`: `list`, with one path per line,
+	// or `make`, a Makefile rule whose target is the depfile. Defaults to `list`.
+	DepfileFormat string `yaml:"depfileFormat,omitempty"`
+
+	// DepfileAbsolutePaths when set to `true`, writes absolute paths to the `depfile`.
+	// Defaults to paths relative to the working directory.
+	DepfileAbsolutePaths bool `yaml:"depfileAbsolutePaths,omitempty"`
+
+	// CleanupCascade is the cascading deletion policy used by `skaffold delete` and on cleanup:
+	// `background`, `foreground` or `orphan`. Requires kubectl 1.20 or later.
+	// Defaults to kubectl's default, `background`.
+	CleanupCascade string `yaml:"cleanupCascade,omitempty"`
+
+	// Environments maps environment names to kustomize overlay paths, e.g. `dev: overlays/dev`.
+	// When set, only the overlay of the selected `environment` is built.
+	Environments map[string]string `yaml:"environments,omitempty" skaffold:"filepath"`
+
+	// Environment selects the entry of `environments` to build. It supports environment variables,
+	// e.g. `{{.DEPLOY_ENV}}`, so that the overlay can be chosen at runtime.
+	Environment string `yaml:"environment,omitempty"`
+
+	// ImageResolver is a command that chooses the reference of the images not built by Skaffold,
+	// e.g. to use the version promoted by a registry service. It's called with the image as last argument,
+	// and must print the reference to use. An empty output leaves the image unchanged. Images are replaced by
+	// repository, so the deployment fails when tags of the same repository resolve to different references.
+	ImageResolver []string `yaml:"imageResolver,omitempty"`
+
+	// PluginHome is the directory where kustomize looks for plugins, passed as `KUSTOMIZE_PLUGIN_HOME`.
+	// Its layout is validated before building. Plugins must still be enabled with `--enable-alpha-plugins`.
+	PluginHome string `yaml:"pluginHome,omitempty" skaffold:"filepath"`
+
+	// PluginAllowlist lists the kustomize plugins that the `generators`, `transformers` and `validators` of the
+	// kustomizations can use, written `<apiVersion>/<kind>`, e.g. `someteam.example.com/v1/SecretsFromVault`.
+	// The build fails when another plugin is referenced, or when a kustomization uses remote bases, whose plugins
+	// can't be checked. Builtin plugins are always allowed.
+	// Defaults to allowing all the plugins.
+	PluginAllowlist []string `yaml:"pluginAllowlist,omitempty"`
+
+	// FailOnOversizedResources when set to `true`, fails the deployment if a rendered resource
+	// is close to the 1MiB object size limit of the API server, instead of only warning about it.
+	FailOnOversizedResources bool `yaml:"failOnOversizedResources,omitempty"`
+
+	// ContentHash when set to `true`, annotates each rendered resource with a hash of its content,
+	// so that tools can detect changes independently of the fields managed by the API server.
+	ContentHash bool `yaml:"contentHash,omitempty"`
+
+	// ContentHashAnnotation is the annotation that holds the content hash.
+	// Defaults to `skaffold.dev/content-hash`.
+	ContentHashAnnotation string `yaml:"contentHashAnnotation,omitempty"`
+
+	// RenderDiffAgainst is the path to previously rendered manifests. When set, `skaffold render`
+	// prints how each resource changed compared to that file, instead of the rendered manifests,
+	// so it can't be combined with `--output`.
+	RenderDiffAgainst string `yaml:"renderDiffAgainst,omitempty"`
+
+	// SkipAnnotation is the annotation that excludes a rendered resource from the deployment
+	// when it's set to `skip`. Defaults to `skaffold.dev/deploy`.
+	SkipAnnotation string `yaml:"skipAnnotation,omitempty"`
+
+	// RequireQualifiedImages when set to `true`, fails the deployment if an image of the rendered manifests,
+	// once replaced by the built artifacts, has no registry, or neither a tag nor a digest.
+	RequireQualifiedImages bool `yaml:"requireQualifiedImages,omitempty"`
+
+	// AllowedShortImages lists the images accepted by `requireQualifiedImages` without a registry, tag or digest,
+	// e.g. `busybox`. An image can be listed with or without its tag.
+	AllowedShortImages []string `yaml:"allowedShortImages,omitempty"`
+
+	// FailOnOverlappingPaths when set to `true`, fails if a kustomize path is nested inside another one,
+	// which usually renders the same resources twice, instead of only warning about it.
+	FailOnOverlappingPaths bool `yaml:"failOnOverlappingPaths,omitempty"`
+
+	// RemoveFields lists the dot separated paths of the fields to remove from the rendered manifests,
+	// such as `spec.template.metadata.annotations.key`. Dots in field names are escaped with a backslash.
+	RemoveFields []string `yaml:"removeFields,omitempty"`
+
+	// Prune when set to `true`, deletes the resources of the current run that are no longer rendered,
+	// by passing `--prune` and the run id selector to `kubectl apply`. All the manifests are then applied on every deploy.
+	Prune bool `yaml:"prune,omitempty"`
+
+	// PruneAllowlist lists the `group/version/Kind` types that can be pruned, such as `apps/v1/Deployment`
+	// or `core/v1/ConfigMap`. Defaults to the types kubectl prunes by default.
+	PruneAllowlist []string `yaml:"pruneAllowlist,omitempty"`
+
+	// ClusterCheckTimeout is how long the check that the cluster is reachable, done before rendering
+	// the manifests on deploy, waits for the API server. Defaults to `10s`.
+	ClusterCheckTimeout string `yaml:"clusterCheckTimeout,omitempty"`
+
+	// Scheduling adds node selectors and tolerations to the pod templates of all the rendered workloads,
+	// for example to run them on a dedicated node pool.
+	Scheduling *KustomizeScheduling `yaml:"scheduling,omitempty"`
+
+	// SkipExistingCRDWait when set to `true`, only waits for the CustomResourceDefinitions that don't exist
+	// on the cluster yet to be established. Requires `waitForCRDs`.
+	SkipExistingCRDWait bool `yaml:"skipExistingCRDWait,omitempty"`
+
+	// RenderSplitDir when set, makes `skaffold render` write each rendered resource to its own file in this directory,
+	// instead of a single output, so it can't be combined with `--output`. The files written by previous renders
+	// are removed first.
+	RenderSplitDir string `yaml:"renderSplitDir,omitempty"`
+
+	// RenderIndexFile when set, also writes an index with this name to `renderSplitDir`, listing each file
+	// and the resources it holds.
+	RenderIndexFile string `yaml:"renderIndexFile,omitempty"`
+
+	// BuildCacheDir when set, caches the output of kustomize builds in this directory, so that it's shared across
+	// Skaffold runs. A build is reused as long as its command, the version of its binary, the `KUSTOMIZE_*` and
+	// `XDG_CONFIG_HOME` environment variables and the content of the local files it depends on are unchanged.
+	// Kustomizations with remote bases are never cached.
+	BuildCacheDir string `yaml:"buildCacheDir,omitempty"`
+
+	// BuildCacheMaxSize is the maximum size of the build cache, such as `500Mi`. The least recently used
+	// builds are evicted first. Defaults to `100Mi`.
+	BuildCacheMaxSize string `yaml:"buildCacheMaxSize,omitempty"`
+
+	// ValidateGeneratorEncoding when set to `true`, warns about `configMapGenerator` and `secretGenerator` files
+	// that are not valid UTF-8 before running kustomize. Binary files are not reported.
+	ValidateGeneratorEncoding bool `yaml:"validateGeneratorEncoding,omitempty"`
+
+	// ApplyCommandPrefix is a command prepended to the kubectl commands that apply, delete or preview manifests,
+	// such as a wrapper that injects credentials. It must run the kubectl command and pass its stdin through.
+	// For example: `["aws-vault", "exec", "dev", "--"]`.
+	ApplyCommandPrefix []string `yaml:"applyCommandPrefix,omitempty"`
+
+	// OnlyNewResources when set to `true`, only renders and deploys the resources that don't exist on the cluster yet,
+	// leaving the existing ones untouched. Requires access to the cluster, so it is skipped with `--offline`.
+	OnlyNewResources bool `yaml:"onlyNewResources,omitempty"`
+
+	// BinaryLookupRetries is how many times a kustomize build is retried when its binary can't be found,
+	// which happens intermittently with some network filesystems. Defaults to `2`.
+	BinaryLookupRetries *int `yaml:"binaryLookupRetries,omitempty"`
+
+	// RenderComponentDir when set, makes `skaffold render` write the rendered resources to this directory as a
+	// kustomize component, with a generated `kustomization.yaml` of kind `Component`, instead of a single output,
+	// so it can't be combined with `--output`. The resource files written by previous renders are removed first.
+	RenderComponentDir string `yaml:"renderComponentDir,omitempty"`
+
+	// WebhookTimeoutRetries is the number of times `kubectl apply` is retried when an admission webhook
+	// times out. These retries are counted separately from `applyRetries`. Webhook denials are never retried.
+	// Defaults to `0`.
+	WebhookTimeoutRetries int `yaml:"webhookTimeoutRetries,omitempty"`
+
+	// LabelAllowlist restricts the Skaffold labels added to the rendered manifests to the given keys,
+	// for example only `app.kubernetes.io/managed-by`. The `skaffold.dev/run-id` label is always kept, since the status check,
+	// port forwarding and `prune` rely on it.
+	// Defaults to all the labels.
+	LabelAllowlist []string `yaml:"labelAllowlist,omitempty"`
+
+	// ServerSideApplyLargeCRDs when set to `false`, disables applying server-side the CustomResourceDefinitions
+	// that are too large to be stored in the annotation used by client-side apply. The other resources are
+	// always applied client-side.
+	// Defaults to `true`.
+	ServerSideApplyLargeCRDs *bool `yaml:"serverSideApplyLargeCRDs,omitempty"`
+
+	// SecretResolver is a command that fetches the values of rendered Secrets from a secret manager.
+	// Values of `data` or `stringData` written as `secretRef://<path>` are replaced by the output of the command,
+	// called with the path as last argument, right before the Secrets are deployed.
+	// The values are never logged, and `skaffold render` keeps the references.
+	SecretResolver []string `yaml:"secretResolver,omitempty"`
+
+	// OCICacheDir when set, keeps the kustomizations pulled from `oci://` paths in this directory.
+	// Only the artifacts pinned by digest are reused, those pinned to a tag are pulled on every build.
+	// `oci://` paths are pulled with `oras`, which must be installed.
+	OCICacheDir string `yaml:"ociCacheDir,omitempty"`
+
+	// ReportPhaseTimings when set to `true`, reports how long each phase of a deploy took:
+	// the kustomize builds, the transformations of the manifests and the apply.
+	ReportPhaseTimings bool `yaml:"reportPhaseTimings,omitempty"`
+
+	// CheckPatchTargets when set to `true`, warns about the patch targets of the kustomizations that match
+	// none of the rendered resources, since kustomize silently skips those patches.
+	CheckPatchTargets bool `yaml:"checkPatchTargets,omitempty"`
+
+	// DeployGroup when set, only deploys the resources whose `skaffold.dev/group` annotation is set to this group,
+	// so that groups of resources can be rolled out in stages, e.g. `infra` then `apps`.
+	// Resources without the annotation are not deployed.
+	DeployGroup string `yaml:"deployGroup,omitempty"`
+
+	// GitCredentials configures the credentials git uses when kustomize fetches private remote bases.
+	// Only paths and helper names are configured here, the secrets themselves are never logged.
+	GitCredentials *KustomizeGitCredentials `yaml:"gitCredentials,omitempty"`
+
+	// Fingerprint when set to `true`, annotates the top-level workloads, such as Deployments, with a fingerprint
+	// of all the rendered manifests, so that the resources in the cluster can be traced back to a deploy.
+	Fingerprint bool `yaml:"fingerprint,omitempty"`
+
+	// FingerprintAnnotation is the annotation that holds the fingerprint.
+	// Defaults to `skaffold.dev/fingerprint`.
+	FingerprintAnnotation string `yaml:"fingerprintAnnotation,omitempty"`
+
+	// RecreateImmutable when set to `true`, deletes and creates again the ConfigMaps, Secrets and Jobs
+	// that fail to apply because one of their immutable fields changed. Other kinds are never recreated.
+	RecreateImmutable bool `yaml:"recreateImmutable,omitempty"`
+
+	// APIVersionOverrides rewrites the apiVersion of the rendered resources before they are applied,
+	// for example `extensions/v1beta1: apps/v1` for clusters that no longer serve a deprecated apiVersion.
+	// Keys are the rendered apiVersions and values the apiVersions they are replaced with.
+	APIVersionOverrides map[string]string `yaml:"apiVersionOverrides,omitempty"`
+
+	// FailOnEmptyRender when set to `true`, fails the deployment when the kustomize paths render no resources at all.
+	// Kustomizations that are expected to be empty are marked with the `skaffold.dev/allow-empty: "true"` annotation
+	// in their `metadata`.
+	FailOnEmptyRender bool `yaml:"failOnEmptyRender,omitempty"`
+
+	// FieldManager is the name `kubectl apply` records as the manager of the applied fields, to attribute
+	// the changes made by Skaffold in the `managedFields` of the resources and in the API server audit logs.
+	// kubectl has no option to set the user agent of its requests, so the field manager is the closest attribution.
+	// Other kubectl calls, such as `get` and `delete`, are not attributed.
+	FieldManager string `yaml:"fieldManager,omitempty"`
+
+	// Replicas overrides the replica count of all the rendered Deployments, StatefulSets, ReplicaSets and
+	// ReplicationControllers, for example `1` to save resources on a local cluster.
+	Replicas *int `yaml:"replicas,omitempty"`
+
+	// SkipAutoscaledReplicas when set to `true`, keeps the replica count of the workloads scaled by one of
+	// the rendered HorizontalPodAutoscalers. Requires `replicas`.
+	SkipAutoscaledReplicas bool `yaml:"skipAutoscaledReplicas,omitempty"`
+
+	// Hermetic when set to `true`, forbids kustomize builds from using the network. Builds fail as soon as
+	// a kustomization references a remote base or resource, and kustomize runs with git limited to local
+	// repositories and HTTP requests sent to an unreachable proxy.
+	Hermetic bool `yaml:"hermetic,omitempty"`
+
+	// WarnDuplicateEnvKeys when set to `true`, warns about `configMapGenerator` and `secretGenerator` env files
+	// that set the same key more than once, since only the last value ends up in the generated resource.
+	WarnDuplicateEnvKeys bool `yaml:"warnDuplicateEnvKeys,omitempty"`
+
+	// LongLabelValues shortens the values of the Skaffold labels that are longer than the 63 characters
+	// Kubernetes accepts, such as a long custom run id. It can be `truncate`, to cut the value, or `hash`,
+	// to replace its end with a hash of the whole value. Defaults to keeping the values unchanged.
+	LongLabelValues string `yaml:"longLabelValues,omitempty"`
+
+	// AllowPartialBuildOutput when set to `true`, deploys the resources printed by a kustomize build that exits
+	// with an error, as long as each of them is complete, and reports the error as a warning. Defaults to failing
+	// on any build error.
+	AllowPartialBuildOutput bool `yaml:"allowPartialBuildOutput,omitempty"`
+
+	// MinResources is the minimum number of resources a deployment is expected to apply. The deployment
+	// fails when fewer resources are applied, for example because the render was truncated. With `onlyNewResources`,
+	// the resources that already exist are counted too.
+	MinResources int `yaml:"minResources,omitempty"`
+
+	// MinResourcesPerKind is the minimum number of resources of each kind a deployment is expected to apply,
+	// for example `Deployment: 3`. The deployment fails when fewer resources of a kind are applied.
+	MinResourcesPerKind map[string]int `yaml:"minResourcesPerKind,omitempty"`
+
+	// MaxResources is the maximum number of resources a render can produce, as a guard against generators or
+	// overlays that multiply the resources by mistake. The render fails when more resources are produced.
+	// Defaults to `0`, for no limit.
+	MaxResources int `yaml:"maxResources,omitempty"`
+
+	// CommonAnnotations are added to the metadata of all the rendered resources, for example to record the
+	// owning `team` or the `cost-center`. Resources that already have one of these annotations keep their value.
+	CommonAnnotations map[string]string `yaml:"commonAnnotations,omitempty"`
+
+	// OverwriteCommonAnnotations when set to `true`, replaces the values of the `commonAnnotations` that the
+	// rendered resources already have.
+	OverwriteCommonAnnotations bool `yaml:"overwriteCommonAnnotations,omitempty"`
+
+	// MultiDocumentPatches checks the patches of the kustomizations, inline or in files, that hold more than
+	// one YAML document, since kustomize expects a single patch document. It can be `warn`, to print a warning,
+	// or `error`, to fail the render. Defaults to not checking the patches.
+	MultiDocumentPatches string `yaml:"multiDocumentPatches,omitempty"`
+
+	// TerminatingNamespaces checks, before the resources are applied, whether the namespaces they are deployed to
+	// are being deleted. It can be `fail`, to stop the deployment with an error, or `wait`, to wait for the
+	// namespaces to be gone before applying. Defaults to not checking the namespaces.
+	TerminatingNamespaces string `yaml:"terminatingNamespaces,omitempty"`
+
+	// TerminatingNamespacesTimeout is how long to wait for terminating namespaces to be deleted,
+	// when `terminatingNamespaces` is `wait`. Defaults to `5m`.
+	TerminatingNamespacesTimeout string `yaml:"terminatingNamespacesTimeout,omitempty"`
+
+	// CommandOverrides replaces the command or the arguments of containers of named workloads, for example
+	// with `sleep infinity` to inspect a broken image. They are meant for debugging and only change the
+	// workloads they target.
+	CommandOverrides []KustomizeCommandOverride `yaml:"commandOverrides,omitempty"`
+
+	// FailOnUninitializedSubmodules when set to `true`, fails when a kustomize path or base belongs to a git
+	// submodule that is not initialized. By default, a warning asks to initialize the submodule, since the
+	// files of such paths can't be watched.
+	FailOnUninitializedSubmodules bool `yaml:"failOnUninitializedSubmodules,omitempty"`
+
+	// ReplaceAnnotation is the annotation that marks the resources to deploy with `kubectl replace` instead of
+	// `kubectl apply`, when its value is `"true"`. Those resources are fully replaced rather than merged with
+	// their live state, and created when they don't exist yet. Defaults to `skaffold.dev/replace`.
+	ReplaceAnnotation string `yaml:"replaceAnnotation,omitempty"`
+
+	// HostAliases are added to the `hostAliases` of the pod templates of all the workloads, for example to
+	// resolve local development hostnames. Hostnames are merged into the entries that a pod template already
+	// has for the same IP.
+	HostAliases []KustomizeHostAlias `yaml:"hostAliases,omitempty"`
+
+	// PropagationPolicies sets, per kind, how the dependents of a resource are handled when `kubectl apply`
+	// deletes it to recreate it, as forced deploys do: `background`, `foreground` or `orphan`. Orphaning
+	// keeps, for example, the pods of a workload whose controller manages them itself. Resources of these
+	// kinds are applied after the others, with `--cascade`.
+	PropagationPolicies map[string]string `yaml:"propagationPolicies,omitempty"`
+
+	// PolicyPath is the directory of the conftest policies that the rendered manifests must pass before they are
+	// deployed. Policy violations fail the deployment. The checks are skipped, with a warning, when the
+	// directory or the binary is missing.
+	PolicyPath string `yaml:"policyPath,omitempty"`
+
+	// PolicyBinary is the conftest compatible binary that checks the policies, run as
+	// `<binary> test --no-color --policy <policyPath> -`. Defaults to `conftest`.
+	PolicyBinary string `yaml:"policyBinary,omitempty"`
+
+	// DuplicateKeys checks the documents output by kustomize for mapping keys that are set more than once, as some
+	// generators produce. Depending on the YAML parser, such documents are either rejected or silently keep only
+	// one of the values. It can be `warn`, to print a warning, or `error`, to fail the render. Defaults to not
+	// checking the documents.
+	DuplicateKeys string `yaml:"duplicateKeys,omitempty"`
+
+	// SecurityContext is added to the pod templates of all the workloads and to their containers, for example
+	// to deploy to clusters that enforce the `restricted` Pod Security Standard.
+	SecurityContext *KustomizeSecurityContext `yaml:"securityContext,omitempty"`
+
 	// LifecycleHooks describes a set of lifecycle hooks that are executed before and after every deploy.
 	LifecycleHooks DeployHooks `yaml:"-"`
 }
 
+// KustomizeRolloutStatus configures how Skaffold waits for the rollout of the workloads of a given kind.
+type KustomizeRolloutStatus struct {
+	// Kind is the kind of workload: `Deployment`, `StatefulSet` or `DaemonSet`.
+	Kind string `yaml:"kind" yamltags:"required"`
+
+	// Timeout is how long to wait for each workload to roll out, e.g. `5m`. Defaults to `5m`.
+	Timeout string `yaml:"timeout,omitempty"`
+
+	// PollInterval when set, checks the rollout status at this interval instead of watching the workload.
+	PollInterval string `yaml:"pollInterval,omitempty"`
+}
+
+// KustomizeWait describes a condition that the deployed resources must meet, checked with `kubectl wait`.
+type KustomizeWait struct {
+	// Resource is the type of the resources to wait for, e.g. `deployment` or `certificates.cert-manager.io`,
+	// or a single resource, e.g. `deployment/web`. Without a selector, all the resources of the type are waited for.
+	Resource string `yaml:"resource" yamltags:"required"`
+
+	// Selector is a label selector that restricts the resources to wait for, e.g. `app=web`.
+	Selector string `yaml:"selector,omitempty"`
+
+	// For is the condition to wait for: `condition=<name>`, e.g. `condition=Available`, `jsonpath=<expression>`
+	// or `delete`.
+	For string `yaml:"for" yamltags:"required"`
+
+	// Namespace is the namespace of the resources. Defaults to the namespace of the deployment.
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// Timeout is how long to wait for the condition, e.g. `2m`. Defaults to `5m`.
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// KustomizeCommandOverride describes the command and arguments that replace those of a workload's container.
+type KustomizeCommandOverride struct {
+	// Name is the name of the workload. Workloads of any kind with a pod template match.
+	Name string `yaml:"name" yamltags:"required"`
+
+	// Kind is the kind of the workload, such as `Deployment`. Defaults to any kind.
+	Kind string `yaml:"kind,omitempty"`
+
+	// Container is the name of the container to override. It can be omitted when the workload has a single container.
+	Container string `yaml:"container,omitempty"`
+
+	// Command replaces the entrypoint of the container. Defaults to keeping the current command.
+	Command []string `yaml:"command,omitempty"`
+
+	// Args replaces the arguments of the container. Defaults to keeping the current arguments.
+	Args []string `yaml:"args,omitempty"`
+}
+
+// KustomizeHostAlias is an entry of the hosts file of a pod.
+type KustomizeHostAlias struct {
+	// IP is the IP address the hostnames resolve to.
+	IP string `yaml:"ip" yamltags:"required"`
+
+	// Hostnames are the hostnames that resolve to the IP address.
+	Hostnames []string `yaml:"hostnames" yamltags:"required"`
+}
+
+// KustomizeSecurityContext describes the security settings added to the pod templates and their containers.
+type KustomizeSecurityContext struct {
+	// Restricted when set to `true`, enforces the settings required by the `restricted` Pod Security Standard:
+	// `runAsNonRoot`, the `RuntimeDefault` seccomp profile, no privilege escalation and all capabilities dropped.
+	// The other fields take precedence over these settings.
+	Restricted bool `yaml:"restricted,omitempty"`
+
+	// RunAsNonRoot requires the containers to run as a non-root user. Set on the pod security context.
+	RunAsNonRoot *bool `yaml:"runAsNonRoot,omitempty"`
+
+	// RunAsUser is the user ID the containers run as. Set on the pod security context.
+	RunAsUser *int64 `yaml:"runAsUser,omitempty"`
+
+	// SeccompProfile is the type of the seccomp profile, such as `RuntimeDefault`. Set on the pod security context.
+	SeccompProfile string `yaml:"seccompProfile,omitempty"`
+
+	// AllowPrivilegeEscalation controls whether a process can gain more privileges than its parent.
+	// Set on each container.
+	AllowPrivilegeEscalation *bool `yaml:"allowPrivilegeEscalation,omitempty"`
+
+	// ReadOnlyRootFilesystem mounts the root filesystem of the containers as read-only. Set on each container.
+	ReadOnlyRootFilesystem *bool `yaml:"readOnlyRootFilesystem,omitempty"`
+
+	// DropCapabilities are the capabilities dropped from each container, such as `ALL`.
+	DropCapabilities []string `yaml:"dropCapabilities,omitempty"`
+
+	// Overwrite when set to `true`, replaces the settings that the workloads already have.
+	// By default, those are kept unchanged.
+	Overwrite bool `yaml:"overwrite,omitempty"`
+}
+
+// KustomizeScheduling describes the node selectors and tolerations added to the pod templates of all the workloads.
+type KustomizeScheduling struct {
+	// NodeSelector is added to the node selector of every pod template.
+	NodeSelector map[string]string `yaml:"nodeSelector,omitempty"`
+
+	// Tolerations are added to the tolerations of every pod template.
+	Tolerations []KustomizeToleration `yaml:"tolerations,omitempty"`
+
+	// Overwrite when set to `true`, replaces the node selector entries with the same key and the tolerations
+	// with the same key and effect that a workload already has. By default, those are kept unchanged.
+	Overwrite bool `yaml:"overwrite,omitempty"`
+}
+
+// KustomizeToleration is a Kubernetes toleration.
+type KustomizeToleration struct {
+	// Key is the taint key that the toleration applies to. Empty means all the taint keys.
+	Key string `yaml:"key,omitempty"`
+
+	// Operator is either `Exists` or `Equal`. Defaults to `Equal`.
+	Operator string `yaml:"operator,omitempty"`
+
+	// Value is the taint value the toleration matches to.
+	Value string `yaml:"value,omitempty"`
+
+	// Effect is the taint effect to match: `NoSchedule`, `PreferNoSchedule` or `NoExecute`. Empty means all the effects.
+	Effect string `yaml:"effect,omitempty"`
+
+	// TolerationSeconds is how long a pod tolerates a `NoExecute` taint before being evicted.
+	TolerationSeconds *int64 `yaml:"tolerationSeconds,omitempty"`
+}
+
+// KustomizeGitCredentials describes how git authenticates when kustomize fetches private remote bases.
+type KustomizeGitCredentials struct {
+	// SSHKey is the path to the private key used to fetch the remote bases over ssh.
+	SSHKey string `yaml:"sshKey,omitempty" skaffold:"filepath"`
+
+	// SSHConfig is the path to an ssh config file, e.g. to use a different key per host.
+	SSHConfig string `yaml:"sshConfig,omitempty" skaffold:"filepath"`
+
+	// CredentialHelper is the git credential helper used to fetch the remote bases over https,
+	// either the name of a helper, such as `store` or `osxkeychain`, or the absolute path to an executable.
+	CredentialHelper string `yaml:"credentialHelper,omitempty"`
+}
+
 // KptDeploy *alpha* uses the `kpt` CLI to manage and deploy manifests.
 type KptDeploy struct {
 	// Dir is the path to the config directory (Required).
@@ -20,12 +20,16 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
-// MakeFilePathsAbsolute recursively sets all fields marked with the tag `filepath` to absolute paths
+var urlScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+
+// MakeFilePathsAbsolute recursively sets all fields marked with the tag `filepath` to absolute paths.
+// Values with a URL scheme are left unchanged.
 func MakeFilePathsAbsolute(s interface{}, base string) error {
 	errs := makeFilePathsAbsolute(s, base)
 	if len(errs) == 0 {
@@ -58,7 +62,7 @@ func makeFilePathsAbsolute(config interface{}, base string) []error {
 				switch v.Interface().(type) {
 				case string:
 					path := v.String()
-					if path == "" || filepath.IsAbs(path) {
+					if path == "" || filepath.IsAbs(path) || hasURLScheme(path) {
 						continue
 					}
 					v.SetString(filepath.Join(base, path))
@@ -67,7 +71,7 @@ func makeFilePathsAbsolute(config interface{}, base string) []error {
 					for j := 0; j < v.Len(); j++ {
 						elem := v.Index(j)
 						path := elem.String()
-						if path == "" || filepath.IsAbs(path) {
+						if path == "" || filepath.IsAbs(path) || hasURLScheme(path) {
 							continue
 						}
 						elem.SetString(filepath.Join(base, path))
@@ -76,7 +80,7 @@ func makeFilePathsAbsolute(config interface{}, base string) []error {
 				case map[string]string:
 					for _, key := range v.MapKeys() {
 						path := v.MapIndex(key).String()
-						if path == "" || filepath.IsAbs(path) {
+						if path == "" || filepath.IsAbs(path) || hasURLScheme(path) {
 							continue
 						}
 						v.SetMapIndex(key, reflect.ValueOf(filepath.Join(base, path)))
@@ -116,6 +120,12 @@ func makeFilePathsAbsolute(config interface{}, base string) []error {
 	}
 }
 
+// hasURLScheme checks whether a path is a URL, such as `oci://registry/app:v1` or `file://overlays/dev`,
+// which is left for the consumer of the field to interpret.
+func hasURLScheme(path string) bool {
+	return urlScheme.MatchString(path)
+}
+
 func filepathTagExists(f reflect.StructField) bool {
 	t, ok := f.Tag.Lookup("skaffold")
 	if !ok {
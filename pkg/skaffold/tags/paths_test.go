@@ -76,6 +76,28 @@ func TestSetAbsFilePaths(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "paths with a url scheme",
+			config: &latestV1.SkaffoldConfig{
+				Pipeline: latestV1.Pipeline{
+					Deploy: latestV1.DeployConfig{
+						DeployType: latestV1.DeployType{
+							KustomizeDeploy: &latestV1.KustomizeDeploy{KustomizePaths: []string{"overlays/dev", "oci://registry.example.com/app:v1", "file://overlays/prod"}},
+						},
+					},
+				},
+			},
+			base: "/a/b",
+			expected: &latestV1.SkaffoldConfig{
+				Pipeline: latestV1.Pipeline{
+					Deploy: latestV1.DeployConfig{
+						DeployType: latestV1.DeployType{
+							KustomizeDeploy: &latestV1.KustomizeDeploy{KustomizePaths: []string{"/a/b/overlays/dev", "oci://registry.example.com/app:v1", "file://overlays/prod"}},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {